@@ -0,0 +1,136 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// accountEntry is a cached WHOIS account lookup
+type accountEntry struct {
+	account string // "" when the nick isn't logged in
+	expires time.Time
+}
+
+// AccountVerifier resolves nicks to their NickServ accounts with WHOIS so
+// admin commands can be tied to an account rather than a nick anyone could take
+type AccountVerifier struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	whois   func(nick string)        // sends a WHOIS for nick
+	cache   map[string]accountEntry  // maps lowercased nick -> last lookup
+	found   map[string]string        // accounts from 330 replies for WHOIS still in progress
+	pending map[string][]chan string // lookups waiting for the end of a WHOIS
+}
+
+// NewAccountVerifier creates a verifier that sends WHOIS requests with whois
+// and caches results for ttl
+func NewAccountVerifier(whois func(nick string), ttl time.Duration) *AccountVerifier {
+	return &AccountVerifier{
+		ttl:     ttl,
+		whois:   whois,
+		cache:   make(map[string]accountEntry),
+		found:   make(map[string]string),
+		pending: make(map[string][]chan string),
+	}
+}
+
+// Account returns the account nick is logged in as, or "" if it isn't logged
+// in or the server didn't answer within timeout
+func (v *AccountVerifier) Account(nick string, timeout time.Duration) string {
+	key := strings.ToLower(nick)
+
+	v.mu.Lock()
+	if entry, ok := v.cache[key]; ok && time.Now().Before(entry.expires) {
+		v.mu.Unlock()
+		return entry.account
+	}
+	ch := make(chan string, 1)
+	v.pending[key] = append(v.pending[key], ch)
+	first := len(v.pending[key]) == 1
+	v.mu.Unlock()
+
+	// Concurrent lookups for the same nick share one WHOIS
+	if first {
+		v.whois(nick)
+	}
+
+	select {
+	case account := <-ch:
+		return account
+	case <-time.After(timeout):
+		// Stop waiting so the next lookup sends a fresh WHOIS
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		waiting := v.pending[key]
+		for i, c := range waiting {
+			if c == ch {
+				waiting = append(waiting[:i], waiting[i+1:]...)
+				break
+			}
+		}
+		if len(waiting) == 0 {
+			delete(v.pending, key)
+		} else {
+			v.pending[key] = waiting
+		}
+		return ""
+	}
+}
+
+// HandleAccountReply records a 330 RPL_WHOISACCOUNT reply
+func (v *AccountVerifier) HandleAccountReply(args []string) {
+	nick, account, ok := parseWhoisAccount(args)
+	if !ok {
+		return
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.found[strings.ToLower(nick)] = account
+}
+
+// HandleEndOfWhois completes lookups on a 318 RPL_ENDOFWHOIS reply. A WHOIS
+// without a 330 reply means the nick isn't logged in.
+func (v *AccountVerifier) HandleEndOfWhois(args []string) {
+	// 318 arguments: <me> <nick> :End of /WHOIS list
+	if len(args) < 2 {
+		return
+	}
+	key := strings.ToLower(args[1])
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	account := v.found[key]
+	delete(v.found, key)
+	v.cache[key] = accountEntry{account: account, expires: time.Now().Add(v.ttl)}
+
+	for _, ch := range v.pending[key] {
+		ch <- account
+	}
+	delete(v.pending, key)
+}
+
+// Remember caches an account learned another way, such as an IRCv3 account tag
+func (v *AccountVerifier) Remember(nick, account string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	v.cache[strings.ToLower(nick)] = accountEntry{account: account, expires: time.Now().Add(v.ttl)}
+}
+
+// Forget drops the cached account for nick, e.g. after a nick change or quit
+func (v *AccountVerifier) Forget(nick string) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	delete(v.cache, strings.ToLower(nick))
+}
+
+// parseWhoisAccount extracts the nick and account from the arguments of a 330
+// reply: <me> <nick> <account> :is logged in as
+func parseWhoisAccount(args []string) (nick, account string, ok bool) {
+	if len(args) < 3 || args[1] == "" || args[2] == "" {
+		return "", "", false
+	}
+	return args[1], args[2], true
+}
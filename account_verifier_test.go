@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParseWhoisAccount(t *testing.T) {
+	nick, account, ok := parseWhoisAccount([]string{"agent", "Alice", "alice_account", "is logged in as"})
+	if !ok || nick != "Alice" || account != "alice_account" {
+		t.Errorf("Expected Alice/alice_account, got %q/%q (ok=%v)", nick, account, ok)
+	}
+
+	if _, _, ok := parseWhoisAccount([]string{"agent", "Alice"}); ok {
+		t.Errorf("Expected short 330 reply to be rejected")
+	}
+}
+
+func TestAccountVerifierLookup(t *testing.T) {
+	var whoisCount atomic.Int32
+	var verifier *AccountVerifier
+	verifier = NewAccountVerifier(func(nick string) {
+		whoisCount.Add(1)
+		// Simulate the server answering the WHOIS
+		go func() {
+			if nick == "alice" {
+				verifier.HandleAccountReply([]string{"agent", nick, "alice_account", "is logged in as"})
+			}
+			verifier.HandleEndOfWhois([]string{"agent", nick, "End of /WHOIS list"})
+		}()
+	}, time.Minute)
+
+	if account := verifier.Account("alice", time.Second); account != "alice_account" {
+		t.Errorf("Expected alice_account, got %q", account)
+	}
+
+	// Cached: no second WHOIS, and nick case doesn't matter
+	if account := verifier.Account("ALICE", time.Second); account != "alice_account" {
+		t.Errorf("Expected cached alice_account, got %q", account)
+	}
+	if n := whoisCount.Load(); n != 1 {
+		t.Errorf("Expected 1 WHOIS, got %d", n)
+	}
+
+	// A nick that isn't logged in gets no 330 reply
+	if account := verifier.Account("mallory", time.Second); account != "" {
+		t.Errorf("Expected no account for mallory, got %q", account)
+	}
+
+	// Forgetting the nick forces a fresh WHOIS
+	verifier.Forget("alice")
+	verifier.Account("alice", time.Second)
+	if n := whoisCount.Load(); n != 3 {
+		t.Errorf("Expected 3 WHOIS after Forget, got %d", n)
+	}
+}
+
+func TestAccountVerifierTimeout(t *testing.T) {
+	var whoisCount atomic.Int32
+	verifier := NewAccountVerifier(func(string) { whoisCount.Add(1) }, time.Minute)
+
+	if account := verifier.Account("alice", 10*time.Millisecond); account != "" {
+		t.Errorf("Expected no account on timeout, got %q", account)
+	}
+
+	// The unanswered lookup must not block later WHOIS requests
+	verifier.Account("alice", 10*time.Millisecond)
+	if n := whoisCount.Load(); n != 2 {
+		t.Errorf("Expected a new WHOIS after a timeout, got %d", n)
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+import "strings"
+
+// ctcpAction wraps text as a CTCP ACTION, the form /me takes on the wire
+func ctcpAction(text string) string {
+	return "\x01ACTION " + text + "\x01"
+}
+
+// parseAction returns the text of a CTCP ACTION message such as
+// "\x01ACTION waves\x01", and whether message is one. A missing closing
+// \x01, which some clients leave off, is tolerated.
+func parseAction(message string) (string, bool) {
+	rest, ok := strings.CutPrefix(message, "\x01ACTION")
+	if !ok {
+		return "", false
+	}
+	rest = strings.TrimSuffix(rest, "\x01")
+	if rest != "" && rest[0] != ' ' {
+		// Some other CTCP command starting with ACTION
+		return "", false
+	}
+	return strings.TrimSpace(rest), true
+}
+
+// describeMessage renders a message for a prompt: actions as "does: ..." and
+// everything else as "said: ..."
+func describeMessage(message string) string {
+	if action, ok := parseAction(message); ok {
+		return "does: " + action
+	}
+	return "said: " + message
+}
+
+// ownAction returns the text of a reply written as "/me ...", which is sent
+// as an ACTION, and whether message is one
+func ownAction(message string) (string, bool) {
+	action, ok := strings.CutPrefix(message, "/me ")
+	if !ok || strings.TrimSpace(action) == "" {
+		return "", false
+	}
+	return action, true
+}
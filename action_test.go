@@ -0,0 +1,55 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseAction(t *testing.T) {
+	tests := []struct {
+		message  string
+		expected string
+		ok       bool
+	}{
+		{"\x01ACTION waves\x01", "waves", true},
+		{"\x01ACTION waves at agent", "waves at agent", true},
+		{"\x01ACTION\x01", "", true},
+		{ctcpAction("shrugs"), "shrugs", true},
+		{"waves", "", false},
+		{"\x01ACTIONS waves\x01", "", false},
+		{"\x01VERSION\x01", "", false},
+	}
+
+	for _, tt := range tests {
+		got, ok := parseAction(tt.message)
+		if got != tt.expected || ok != tt.ok {
+			t.Errorf("parseAction(%q): expected (%q, %v), got (%q, %v)", tt.message, tt.expected, tt.ok, got, ok)
+		}
+	}
+}
+
+func TestBuildPromptDescribesActions(t *testing.T) {
+	recent := []ChannelMessage{{Sender: "bob", Message: ctcpAction("yawns")}, {Sender: "bob", Message: "hi"}}
+	prompt := buildPrompt("alice", "#agent", ctcpAction("waves at agent"), recent)
+
+	if !strings.Contains(prompt, "User alice in channel #agent does: waves at agent") {
+		t.Errorf("Expected the action described, got %q", prompt)
+	}
+	if !strings.Contains(prompt, "* bob yawns\n<bob> hi\n") {
+		t.Errorf("Expected actions in the context shown as emotes, got %q", prompt)
+	}
+	if strings.Contains(prompt, "\x01") {
+		t.Errorf("Expected no CTCP wrapper in the prompt, got %q", prompt)
+	}
+}
+
+func TestOwnAction(t *testing.T) {
+	if action, ok := ownAction("/me waves back"); !ok || action != "waves back" {
+		t.Errorf("Expected an action, got (%q, %v)", action, ok)
+	}
+	for _, message := range []string{"hello", "/me ", "/meh"} {
+		if _, ok := ownAction(message); ok {
+			t.Errorf("Expected %q not to be an action", message)
+		}
+	}
+}
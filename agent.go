@@ -4,43 +4,96 @@ import (
 	"context"
 	"log"
 	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
+	"github.com/r33drichards/irc-agent/internal/configfile"
+	"github.com/r33drichards/irc-agent/shortener"
 	"google.golang.org/adk/cmd/launcher/adk"
 	"google.golang.org/adk/cmd/launcher/full"
 	"google.golang.org/adk/server/restapi/services"
 )
 
 func main() {
-	ctx := context.Background()
+	// Cancel the root context on SIGINT/SIGTERM so components can shut down cleanly
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	// Get URL shortener host from environment (defaults to Railway production URL)
-	shortenerHost := os.Getenv("SHORTENER_HOST")
-	if shortenerHost == "" {
-		shortenerHost = "https://irc-agent-production-09eb.up.railway.app"
+	// Settings from CONFIG_FILE fill in whatever the environment leaves unset
+	if err := configfile.Apply(); err != nil {
+		log.Fatalf("Invalid configuration: %v", err)
+	}
+	// A dry run against stdin needs no IRC settings
+	stdin := stdinMode(os.Args)
+	if stdin {
+		stdinDefaults()
+	}
+	// Report every configuration problem up front, before connecting to IRC
+	if err := validateConfig(); err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	// Store code and results in S3 or, with ARTIFACT_STORE=filesystem, on local disk
+	shortenerHost := shortener.Host()
+	artifactStore, err := newArtifactStoreFromEnv(shortenerHost)
+	if err != nil {
+		log.Fatalf("Invalid artifact store configuration: %v", err)
+	}
+	var shortenerOpts []shortener.ShortenerOption
+	if fileStore, ok := artifactStore.(*FileArtifactStore); ok {
+		shortenerOpts = append(shortenerOpts, shortener.WithArtifactDir(fileStore.Dir))
+		log.Printf("Storing artifacts in %s", fileStore.Dir)
 	}
 
-	// Create URL Shortener first
-	urlShortener := NewURLShortener(shortenerHost)
+	// Create URL Shortener first, with storage and settings from the environment
+	urlShortener, closeShortener, err := shortener.FromEnv(shortenerHost, shortenerOpts...)
+	if err != nil {
+		log.Fatalf("Invalid URL shortener configuration: %v", err)
+	}
 
 	// Create IRC Agent with URL Shortener
-	ircAgent, err := NewIRCAgent(ctx, urlShortener)
+	ircAgents, err := NewIRCAgents(ctx, urlShortener, artifactStore)
 	if err != nil {
 		log.Fatalf("Failed to create IRC agent: %v", err)
 	}
 
-	// Start URL Shortener on port 3000
+	// Start URL Shortener (port from SHORTENER_PORT, default 3000)
+	port := shortener.Port()
 	go func() {
-		log.Println("Starting URL Shortener on port 3000...")
-		if err := urlShortener.Serve("3000"); err != nil {
+		log.Printf("Starting URL Shortener on port %s...", port)
+		if err := urlShortener.Serve(port); err != nil {
 			log.Fatalf("URL Shortener failed: %v", err)
 		}
 	}()
 
-	// Check if we should run in web mode or IRC mode
-	if len(os.Args) > 1 && os.Args[1] == "web" {
+	// Drain the shortener and release storage once a shutdown signal arrives
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+
+		if err := urlShortener.Shutdown(shutdownCtx); err != nil {
+			log.Printf("URL Shortener shutdown error: %v", err)
+		}
+		if err := closeShortener(); err != nil {
+			log.Printf("Storage close error: %v", err)
+		}
+	}()
+
+	// Check if we should read stdin, run in web mode or IRC mode
+	if stdin {
+		// Answer lines from stdin and print replies, without connecting to IRC
+		sender := envOrDefault("STDIN_NICK", "user")
+		if err := ircAgents[0].RunStdin(ctx, os.Stdin, NewWriterSink(os.Stdout), sender); err != nil {
+			log.Fatalf("Stdin mode failed: %v", err)
+		}
+	} else if len(os.Args) > 1 && os.Args[1] == "web" {
 		// Run with ADK web interface
 		config := &adk.Config{
-			AgentLoader: services.NewSingleAgentLoader(ircAgent.agent),
+			AgentLoader: services.NewSingleAgentLoader(ircAgents[0].agent),
 		}
 
 		l := full.NewLauncher()
@@ -51,10 +104,24 @@ func main() {
 	} else {
 		// Run in IRC mode
 		log.Println("Starting IRC Agent...")
-		log.Printf("Channel: %s", ircAgent.channel)
 
-		if err := ircAgent.Start(ctx); err != nil {
-			log.Fatalf("IRC agent failed: %v", err)
+		// Each network runs its own connection; one failing stops the process
+		var wg sync.WaitGroup
+		for _, ircAgent := range ircAgents {
+			if ircAgent.network != "" {
+				log.Printf("Channels on %s: %s", ircAgent.network, strings.Join(ircAgent.channels, ", "))
+			} else {
+				log.Printf("Channels: %s", strings.Join(ircAgent.channels, ", "))
+			}
+
+			wg.Add(1)
+			go func(ircAgent *IRCAgent) {
+				defer wg.Done()
+				if err := ircAgent.Start(ctx); err != nil {
+					log.Fatalf("IRC agent failed: %v", err)
+				}
+			}(ircAgent)
 		}
+		wg.Wait()
 	}
 }
@@ -0,0 +1,513 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/r33drichards/irc-agent/internal/envconf"
+)
+
+// ArtifactStore stores executed code and full results and returns a URL
+// where they can be fetched
+type ArtifactStore interface {
+	// Upload stores content and returns its URL. An empty contentType is
+	// detected from the content.
+	Upload(ctx context.Context, content, contentType string) (string, error)
+}
+
+// ArtifactReader is implemented by stores that can read an artifact back
+type ArtifactReader interface {
+	// Read returns the content stored under ref, which is either a key or a
+	// URL returned by Upload
+	Read(ctx context.Context, ref string) (string, error)
+}
+
+// ArtifactObject describes a stored artifact returned by ArtifactLister
+type ArtifactObject struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+	URL          string // where the artifact can be downloaded
+}
+
+// ArtifactLister is implemented by stores that can list their artifacts
+type ArtifactLister interface {
+	// List returns up to limit artifacts whose keys start with prefix, after
+	// the page identified by cursor, and the cursor of the next page or ""
+	List(ctx context.Context, prefix, cursor string, limit int) ([]ArtifactObject, string, error)
+}
+
+// Default bucket and region for S3ArtifactStore
+const (
+	defaultS3Bucket = "robust-cicada"
+	defaultS3Region = "us-west-2"
+)
+
+// defaultKeyPrefix is the S3 key prefix used when none is configured
+const defaultKeyPrefix = "code-results/"
+
+// defaultArtifactDir is where FileArtifactStore writes when ARTIFACT_DIR is not set
+const defaultArtifactDir = "artifacts"
+
+// detectContentType picks a content type for uploaded output so browsers
+// render JSON and HTML properly instead of showing them as plain text
+func detectContentType(content string) string {
+	trimmed := strings.TrimSpace(content)
+	if (strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[")) && json.Valid([]byte(trimmed)) {
+		return "application/json"
+	}
+	return http.DetectContentType([]byte(content))
+}
+
+// resultKey builds the S3 key for content from the prefix, upload time and a
+// content hash, with a file extension matching the content type
+func resultKey(prefix, content, contentType string, now time.Time) string {
+	hash := sha256.Sum256([]byte(content))
+	hashStr := hex.EncodeToString(hash[:])[:16]
+
+	ext := ".txt"
+	mediaType, _, _ := mime.ParseMediaType(contentType)
+	switch mediaType {
+	case "application/json":
+		ext = ".json"
+	case "text/html":
+		ext = ".html"
+	case "image/png":
+		ext = ".png"
+	case "image/jpeg":
+		ext = ".jpg"
+	case "image/gif":
+		ext = ".gif"
+	case "image/webp":
+		ext = ".webp"
+	case "application/pdf":
+		ext = ".pdf"
+	case "application/x-gzip":
+		ext = ".gz"
+	case "application/zip":
+		ext = ".zip"
+	default:
+		if mediaType != "" && !strings.HasPrefix(mediaType, "text/") {
+			ext = ".bin"
+		}
+	}
+
+	return fmt.Sprintf("%s%d-%s%s", prefix, now.Unix(), hashStr, ext)
+}
+
+// Retry settings for S3 requests
+const (
+	defaultS3MaxAttempts = 4
+	s3RetryMaxBackoff    = 5 * time.Second
+)
+
+// S3ArtifactStore uploads artifacts to S3 and returns presigned URLs
+type S3ArtifactStore struct {
+	Bucket    string        // defaults to defaultS3Bucket
+	Region    string        // defaults to defaultS3Region
+	KeyPrefix string        // defaults to defaultKeyPrefix
+	Tagging   string        // object tags in URL query form, e.g. "retention=ephemeral"
+	Expires   time.Duration // sets the Expires header when positive
+	// MaxAttempts is how many times a request is tried, with exponential
+	// backoff between retryable failures. Defaults to defaultS3MaxAttempts.
+	MaxAttempts int
+	// Endpoint points the client at an S3-compatible store such as MinIO or
+	// R2, e.g. "http://minio:9000". Empty uses AWS.
+	Endpoint string
+	// ForcePathStyle addresses objects as <endpoint>/<bucket>/<key> instead of
+	// <bucket>.<endpoint>/<key>, which most S3-compatible stores need
+	ForcePathStyle bool
+	// Profile loads credentials and settings from this shared config profile.
+	// Empty leaves the choice to the default credential chain.
+	Profile string
+	// AccessKey and SecretKey are static credentials used instead of the
+	// default credential chain when both are set
+	AccessKey string
+	SecretKey string
+
+	httpClient aws.HTTPClient // replaces the SDK's HTTP client in tests
+	maxBackoff time.Duration  // replaces s3RetryMaxBackoff in tests
+}
+
+// bucket returns the configured bucket or the default
+func (s *S3ArtifactStore) bucket() string {
+	if s.Bucket == "" {
+		return defaultS3Bucket
+	}
+	return s.Bucket
+}
+
+func (s *S3ArtifactStore) region() string {
+	if s.Region == "" {
+		return defaultS3Region
+	}
+	return s.Region
+}
+
+func (s *S3ArtifactStore) keyPrefix() string {
+	if s.KeyPrefix == "" {
+		return defaultKeyPrefix
+	}
+	return s.KeyPrefix
+}
+
+// netHosts returns the hosts the store's objects are reached at, as the
+// host[:port] list Deno's --allow-net takes. On AWS that is the regional
+// endpoint and the bucket's virtual host; with a custom endpoint it is the
+// endpoint, prefixed with the bucket unless addressing is path style.
+func (s *S3ArtifactStore) netHosts() []string {
+	if s.Endpoint != "" {
+		u, err := url.Parse(s.Endpoint)
+		if err != nil || u.Host == "" {
+			return nil
+		}
+		if s.ForcePathStyle {
+			return []string{u.Host}
+		}
+		return []string{u.Host, s.bucket() + "." + u.Host}
+	}
+
+	host := "s3." + s.region() + ".amazonaws.com"
+	return []string{host, s.bucket() + "." + host}
+}
+
+// client creates an S3 client for the configured region
+func (s *S3ArtifactStore) client(ctx context.Context) (*s3.Client, error) {
+	cfg, err := s.awsConfig(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s3.NewFromConfig(cfg, s.clientOptions), nil
+}
+
+// awsConfig loads the AWS configuration for the store: region, retries and
+// credentials from the profile, static keys or the default chain
+func (s *S3ArtifactStore) awsConfig(ctx context.Context) (aws.Config, error) {
+	region := s.region()
+
+	maxAttempts := s.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultS3MaxAttempts
+	}
+	maxBackoff := s.maxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = s3RetryMaxBackoff
+	}
+
+	// Load AWS configuration. The standard retryer retries throttling,
+	// 5xx responses and connection errors with jittered exponential backoff.
+	opts := []func(*config.LoadOptions) error{
+		config.WithRegion(region),
+		config.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = maxAttempts
+				o.MaxBackoff = maxBackoff
+				o.Backoff = retry.NewExponentialJitterBackoff(maxBackoff)
+			})
+		}),
+	}
+	if s.Profile != "" {
+		opts = append(opts, config.WithSharedConfigProfile(s.Profile))
+	}
+	if s.AccessKey != "" && s.SecretKey != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(s.AccessKey, s.SecretKey, ""),
+		))
+	}
+	if s.httpClient != nil {
+		opts = append(opts, config.WithHTTPClient(s.httpClient))
+	}
+	cfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return aws.Config{}, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return cfg, nil
+}
+
+// clientOptions applies the endpoint settings to the S3 client
+func (s *S3ArtifactStore) clientOptions(o *s3.Options) {
+	if s.Endpoint != "" {
+		o.BaseEndpoint = aws.String(s.Endpoint)
+	}
+	o.UsePathStyle = s.ForcePathStyle
+}
+
+// Upload stores content in the bucket and returns a presigned URL valid for 24 hours
+func (s *S3ArtifactStore) Upload(ctx context.Context, content, contentType string) (string, error) {
+	bucketName := s.bucket()
+	s3Client, err := s.client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	keyPrefix := s.keyPrefix()
+	if contentType == "" {
+		contentType = detectContentType(content)
+	}
+
+	// Generate a unique key based on timestamp and content hash
+	key := resultKey(keyPrefix, content, contentType, time.Now())
+
+	// Tag results so a bucket lifecycle rule can expire them
+	input := &s3.PutObjectInput{
+		Bucket:      aws.String(bucketName),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader([]byte(content)),
+		ContentType: aws.String(contentType),
+	}
+	if s.Tagging != "" {
+		input.Tagging = aws.String(s.Tagging)
+	}
+
+	// Optionally set the Expires header. This only marks the object as stale for
+	// caches; deletion still relies on the lifecycle rule matching the tag.
+	if s.Expires > 0 {
+		input.Expires = aws.Time(time.Now().Add(s.Expires))
+	}
+
+	// Upload content to S3
+	_, err = s3Client.PutObject(ctx, input)
+	if err != nil {
+		return "", fmt.Errorf("failed to upload to S3: %w", err)
+	}
+
+	return presignGet(ctx, s3Client, bucketName, key)
+}
+
+// presignGet returns a GET URL for key valid for 24 hours. Presigning only
+// signs locally, so it needs no retries.
+func presignGet(ctx context.Context, s3Client *s3.Client, bucketName, key string) (string, error) {
+	presignResult, err := s3.NewPresignClient(s3Client).PresignGetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(24*time.Hour))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	}
+	return presignResult.URL, nil
+}
+
+// presignedPutTTL is how long a presigned upload URL stays valid
+const presignedPutTTL = 15 * time.Minute
+
+// presignPutURL returns a URL that accepts a PUT of the object at key, so
+// executed code can upload without AWS credentials. The body is sent as is;
+// no content type or tagging is signed, so any may be used.
+func (s *S3ArtifactStore) presignPutURL(ctx context.Context, key string) (string, error) {
+	s3Client, err := s.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	presignResult, err := s3.NewPresignClient(s3Client).PresignPutObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.bucket()),
+		Key:    aws.String(key),
+	}, s3.WithPresignExpires(presignedPutTTL))
+	if err != nil {
+		return "", fmt.Errorf("failed to generate presigned upload URL: %w", err)
+	}
+	return presignResult.URL, nil
+}
+
+// presignGetURL returns a GET URL for the object at key valid for 24 hours
+func (s *S3ArtifactStore) presignGetURL(ctx context.Context, key string) (string, error) {
+	s3Client, err := s.client(ctx)
+	if err != nil {
+		return "", err
+	}
+	return presignGet(ctx, s3Client, s.bucket(), key)
+}
+
+// List returns the objects under the key prefix whose keys continue with
+// prefix, each with a presigned URL valid for 24 hours
+func (s *S3ArtifactStore) List(ctx context.Context, prefix, cursor string, limit int) ([]ArtifactObject, string, error) {
+	bucketName := s.bucket()
+	s3Client, err := s.client(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+
+	keyPrefix := s.keyPrefix()
+	input := &s3.ListObjectsV2Input{
+		Bucket:  aws.String(bucketName),
+		Prefix:  aws.String(keyPrefix + prefix),
+		MaxKeys: aws.Int32(int32(limit)),
+	}
+	if cursor != "" {
+		input.ContinuationToken = aws.String(cursor)
+	}
+	output, err := s3Client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to list S3 objects: %w", err)
+	}
+
+	objects := make([]ArtifactObject, 0, len(output.Contents))
+	for _, obj := range output.Contents {
+		key := aws.ToString(obj.Key)
+		signedURL, err := presignGet(ctx, s3Client, bucketName, key)
+		if err != nil {
+			return nil, "", err
+		}
+		objects = append(objects, ArtifactObject{
+			Key:          key,
+			Size:         aws.ToInt64(obj.Size),
+			LastModified: aws.ToTime(obj.LastModified),
+			URL:          signedURL,
+		})
+	}
+
+	next := ""
+	if aws.ToBool(output.IsTruncated) {
+		next = aws.ToString(output.NextContinuationToken)
+	}
+	return objects, next, nil
+}
+
+// s3KeyFromRef returns the object key for a key or an S3 URL in either
+// virtual-hosted or path style
+func s3KeyFromRef(bucket, ref string) string {
+	if u, err := url.Parse(ref); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		ref = u.Path
+	}
+	ref = strings.TrimPrefix(ref, "/")
+	return strings.TrimPrefix(ref, bucket+"/")
+}
+
+// Read downloads the object for ref from the bucket
+func (s *S3ArtifactStore) Read(ctx context.Context, ref string) (string, error) {
+	bucketName := s.bucket()
+	s3Client, err := s.client(ctx)
+	if err != nil {
+		return "", err
+	}
+
+	key := s3KeyFromRef(bucketName, ref)
+	if key == "" {
+		return "", fmt.Errorf("no object key in %q", ref)
+	}
+
+	output, err := s3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to download %s from S3: %w", key, err)
+	}
+	defer output.Body.Close()
+
+	data, err := io.ReadAll(output.Body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s from S3: %w", key, err)
+	}
+	return string(data), nil
+}
+
+// FileArtifactStore writes artifacts to a local directory. The files are served
+// by the URL shortener's /artifacts/ route (see shortener.WithArtifactDir).
+type FileArtifactStore struct {
+	Dir     string // directory artifacts are written to
+	BaseURL string // public base URL of the shortener, e.g. "http://localhost:3000"
+}
+
+// Upload writes content to Dir and returns its /artifacts/ URL
+func (s *FileArtifactStore) Upload(ctx context.Context, content, contentType string) (string, error) {
+	if contentType == "" {
+		contentType = detectContentType(content)
+	}
+
+	if err := os.MkdirAll(s.Dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create artifact directory: %w", err)
+	}
+
+	name := resultKey("", content, contentType, time.Now())
+	if err := os.WriteFile(filepath.Join(s.Dir, name), []byte(content), 0644); err != nil {
+		return "", fmt.Errorf("failed to write artifact: %w", err)
+	}
+
+	return fmt.Sprintf("%s/artifacts/%s", s.BaseURL, name), nil
+}
+
+// Read returns the artifact for ref, a file name or an /artifacts/ URL
+func (s *FileArtifactStore) Read(ctx context.Context, ref string) (string, error) {
+	if u, err := url.Parse(ref); err == nil && (u.Scheme == "http" || u.Scheme == "https") {
+		ref = u.Path
+	}
+	// Only plain file names inside Dir can be read
+	name := path.Base(ref)
+	if name == "." || name == ".." || name == "/" {
+		return "", fmt.Errorf("invalid artifact name %q", ref)
+	}
+
+	data, err := os.ReadFile(filepath.Join(s.Dir, name))
+	if err != nil {
+		return "", fmt.Errorf("failed to read artifact: %w", err)
+	}
+	return string(data), nil
+}
+
+// S3 settings newArtifactStoreFromEnv reads. validateConfig checks them by
+// building the store the same way.
+var (
+	s3ForcePathStyleSetting = envconf.Bool{Name: "S3_FORCE_PATH_STYLE"}
+	s3ResultExpiresSetting  = envconf.Duration{Name: "S3_RESULT_EXPIRES"}  // zero sets no Expires header
+	s3MaxAttemptsSetting    = envconf.Int{Name: "S3_MAX_ATTEMPTS", Min: 1} // unset keeps the SDK default
+)
+
+// newArtifactStoreFromEnv selects the artifact store from ARTIFACT_STORE
+// ("s3", the default, or "filesystem"). baseURL is the shortener's public URL,
+// used to build links to files written by the filesystem store.
+func newArtifactStoreFromEnv(baseURL string) (ArtifactStore, error) {
+	switch kind := os.Getenv("ARTIFACT_STORE"); kind {
+	case "", "s3":
+		store := &S3ArtifactStore{
+			Bucket:    os.Getenv("S3_BUCKET"),
+			Region:    os.Getenv("S3_REGION"),
+			KeyPrefix: envOrDefault("S3_KEY_PREFIX", defaultKeyPrefix),
+			Tagging:   envOrDefault("S3_RESULT_TAGGING", "retention=ephemeral"),
+			Endpoint:  os.Getenv("S3_ENDPOINT"),
+			Profile:   os.Getenv("AWS_PROFILE"),
+			AccessKey: os.Getenv("S3_ACCESS_KEY"),
+			SecretKey: os.Getenv("S3_SECRET_KEY"),
+		}
+		var problems []error
+		if (store.AccessKey == "") != (store.SecretKey == "") {
+			problems = append(problems, fmt.Errorf("S3_ACCESS_KEY and S3_SECRET_KEY must be set together"))
+		}
+		var err error
+		store.ForcePathStyle, err = s3ForcePathStyleSetting.Get()
+		problems = append(problems, err)
+		store.Expires, err = s3ResultExpiresSetting.Get()
+		problems = append(problems, err)
+		store.MaxAttempts, err = s3MaxAttemptsSetting.Get()
+		problems = append(problems, err)
+		if err := errors.Join(problems...); err != nil {
+			return nil, err
+		}
+		return store, nil
+	case "filesystem":
+		return &FileArtifactStore{
+			Dir:     envOrDefault("ARTIFACT_DIR", defaultArtifactDir),
+			BaseURL: baseURL,
+		}, nil
+	default:
+		return nil, fmt.Errorf("ARTIFACT_STORE must be \"s3\" or \"filesystem\", got %q", kind)
+	}
+}
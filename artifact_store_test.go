@@ -0,0 +1,441 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+func TestResultKeyWithCustomPrefix(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+
+	key := resultKey("team-a/results/", "hello", "text/plain; charset=utf-8", now)
+	if !strings.HasPrefix(key, "team-a/results/1700000000-") {
+		t.Errorf("Expected key under custom prefix, got %s", key)
+	}
+	if !strings.HasSuffix(key, ".txt") {
+		t.Errorf("Expected .txt extension for plain text, got %s", key)
+	}
+
+	// Same content and time always map to the same key
+	if again := resultKey("team-a/results/", "hello", "text/plain", now); again != key {
+		t.Errorf("Expected stable key, got %s and %s", key, again)
+	}
+
+	if key := resultKey("p/", `{"a":1}`, "application/json", now); !strings.HasSuffix(key, ".json") {
+		t.Errorf("Expected .json extension for JSON, got %s", key)
+	}
+}
+
+func TestResultKeyExtensions(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		contentType string
+		ext         string
+	}{
+		{"text/plain; charset=utf-8", ".txt"},
+		{"image/png", ".png"},
+		{"application/x-gzip", ".gz"},
+		{"application/octet-stream", ".bin"},
+	}
+
+	for _, tt := range tests {
+		if key := resultKey("", "x", tt.contentType, now); !strings.HasSuffix(key, tt.ext) {
+			t.Errorf("%s: Expected %s extension, got %s", tt.contentType, tt.ext, key)
+		}
+	}
+}
+
+func TestDetectContentType(t *testing.T) {
+	tests := []struct {
+		content  string
+		expected string
+	}{
+		{`{"result": 42}`, "application/json"},
+		{"[1, 2, 3]\n", "application/json"},
+		{"<html><body>hi</body></html>", "text/html; charset=utf-8"},
+		{"Sum of 1 to 10: 55", "text/plain; charset=utf-8"},
+		{"{not json", "text/plain; charset=utf-8"},
+	}
+
+	for _, tt := range tests {
+		if got := detectContentType(tt.content); got != tt.expected {
+			t.Errorf("detectContentType(%q) = %q, expected %q", tt.content, got, tt.expected)
+		}
+	}
+}
+
+func TestFileArtifactStoreServedByShortener(t *testing.T) {
+	dir := t.TempDir()
+	shortener := shortener.NewURLShortener("http://example.com:3000", shortener.NewInMemoryStorage(), shortener.WithArtifactDir(dir))
+	server := httptest.NewServer(shortener.Handler())
+	defer server.Close()
+
+	store := &FileArtifactStore{Dir: dir, BaseURL: server.URL}
+	url, err := store.Upload(context.Background(), `{"result": 42}`, "")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if !strings.HasPrefix(url, server.URL+"/artifacts/") || !strings.HasSuffix(url, ".json") {
+		t.Errorf("Expected a .json URL under /artifacts/, got %s", url)
+	}
+
+	// The file lands in the configured directory
+	if _, err := os.Stat(filepath.Join(dir, filepath.Base(url))); err != nil {
+		t.Errorf("Expected artifact file in %s: %v", dir, err)
+	}
+
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s failed: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if string(body) != `{"result": 42}` {
+		t.Errorf("Expected stored content, got %q", body)
+	}
+}
+
+func TestArtifactRouteRejectsTraversal(t *testing.T) {
+	dir := t.TempDir()
+	shortener := shortener.NewURLShortener("http://example.com:3000", shortener.NewInMemoryStorage(), shortener.WithArtifactDir(dir))
+
+	for _, path := range []string{"/artifacts/", "/artifacts/../secret.txt", "/artifacts/.hidden", "/artifacts/missing.txt"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		shortener.Handler().ServeHTTP(rec, req)
+
+		if rec.Code == http.StatusOK {
+			t.Errorf("Expected %s to be rejected, got status 200", path)
+		}
+	}
+}
+
+func TestNewArtifactStoreFromEnv(t *testing.T) {
+	t.Setenv("ARTIFACT_STORE", "filesystem")
+	t.Setenv("ARTIFACT_DIR", "/tmp/results")
+
+	store, err := newArtifactStoreFromEnv("http://example.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	fileStore, ok := store.(*FileArtifactStore)
+	if !ok {
+		t.Fatalf("Expected *FileArtifactStore, got %T", store)
+	}
+	if fileStore.Dir != "/tmp/results" || fileStore.BaseURL != "http://example.com" {
+		t.Errorf("Unexpected file store config: %+v", fileStore)
+	}
+
+	t.Setenv("ARTIFACT_STORE", "")
+	if store, _ := newArtifactStoreFromEnv("http://example.com"); store == nil {
+		t.Errorf("Expected S3 store by default")
+	} else if _, ok := store.(*S3ArtifactStore); !ok {
+		t.Errorf("Expected *S3ArtifactStore by default, got %T", store)
+	}
+
+	t.Setenv("ARTIFACT_STORE", "ftp")
+	if _, err := newArtifactStoreFromEnv("http://example.com"); err == nil {
+		t.Errorf("Expected error for unknown ARTIFACT_STORE")
+	}
+}
+
+func TestS3StaticCredentials(t *testing.T) {
+	useStaticAWSCredentials(t)
+
+	store := &S3ArtifactStore{AccessKey: "AKIASTATIC", SecretKey: "static-secret"}
+	cfg, err := store.awsConfig(context.Background())
+	if err != nil {
+		t.Fatalf("awsConfig failed: %v", err)
+	}
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve credentials: %v", err)
+	}
+	if creds.AccessKeyID != "AKIASTATIC" || creds.SecretAccessKey != "static-secret" {
+		t.Errorf("Expected the static keys instead of the environment's, got %s", creds.AccessKeyID)
+	}
+}
+
+func TestS3Profile(t *testing.T) {
+	useStaticAWSCredentials(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+	credsFile := filepath.Join(t.TempDir(), "credentials")
+	if err := os.WriteFile(credsFile, []byte("[uploader]\naws_access_key_id = AKIAPROFILE\naws_secret_access_key = profile-secret\n"), 0600); err != nil {
+		t.Fatalf("Failed to write credentials file: %v", err)
+	}
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credsFile)
+
+	cfg, err := (&S3ArtifactStore{Profile: "uploader"}).awsConfig(context.Background())
+	if err != nil {
+		t.Fatalf("awsConfig failed: %v", err)
+	}
+	creds, err := cfg.Credentials.Retrieve(context.Background())
+	if err != nil {
+		t.Fatalf("Failed to retrieve credentials: %v", err)
+	}
+	if creds.AccessKeyID != "AKIAPROFILE" {
+		t.Errorf("Expected the profile's keys, got %s", creds.AccessKeyID)
+	}
+}
+
+func TestS3CredentialsFromEnv(t *testing.T) {
+	t.Setenv("ARTIFACT_STORE", "s3")
+	t.Setenv("AWS_PROFILE", "uploader")
+	t.Setenv("S3_ACCESS_KEY", "AKIASTATIC")
+	t.Setenv("S3_SECRET_KEY", "static-secret")
+
+	store, err := newArtifactStoreFromEnv("")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s3Store := store.(*S3ArtifactStore)
+	if s3Store.Profile != "uploader" || s3Store.AccessKey != "AKIASTATIC" || s3Store.SecretKey != "static-secret" {
+		t.Errorf("Expected the profile and keys from the environment, got %+v", s3Store)
+	}
+
+	t.Setenv("S3_SECRET_KEY", "")
+	if _, err := newArtifactStoreFromEnv(""); err == nil {
+		t.Errorf("Expected an error for an access key without a secret key")
+	}
+}
+
+// flakyS3 answers S3 requests, failing the first failures of them with 503 SlowDown
+func flakyS3(failures int, requests *int) *http.Client {
+	return &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		*requests++
+		if req.Body != nil {
+			io.Copy(io.Discard, req.Body)
+		}
+		if *requests <= failures {
+			return &http.Response{
+				StatusCode: http.StatusServiceUnavailable,
+				Header:     http.Header{"Content-Type": {"application/xml"}},
+				Body:       io.NopCloser(strings.NewReader(`<Error><Code>SlowDown</Code><Message>Please reduce your request rate.</Message></Error>`)),
+				Request:    req,
+			}, nil
+		}
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Header:     http.Header{"Etag": {`"abc"`}},
+			Body:       io.NopCloser(strings.NewReader("")),
+			Request:    req,
+		}, nil
+	})}
+}
+
+// useStaticAWSCredentials keeps the SDK from looking for real credentials
+func useStaticAWSCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test")
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(t.TempDir(), "config"))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", filepath.Join(t.TempDir(), "credentials"))
+	t.Setenv("AWS_CA_BUNDLE", "")
+}
+
+func TestS3UploadRetriesTransientFailure(t *testing.T) {
+	useStaticAWSCredentials(t)
+
+	requests := 0
+	store := &S3ArtifactStore{httpClient: flakyS3(1, &requests), maxBackoff: time.Millisecond}
+
+	url, err := store.Upload(context.Background(), "hello", "")
+	if err != nil {
+		t.Fatalf("Expected upload to succeed after a retry, got %v", err)
+	}
+	if requests != 2 {
+		t.Errorf("Expected 2 PutObject attempts, got %d", requests)
+	}
+	if !strings.Contains(url, defaultKeyPrefix) || !strings.Contains(url, "X-Amz-Signature") {
+		t.Errorf("Expected a presigned URL for the result, got %s", url)
+	}
+}
+
+func TestS3UploadStopsAfterMaxAttempts(t *testing.T) {
+	useStaticAWSCredentials(t)
+
+	requests := 0
+	store := &S3ArtifactStore{httpClient: flakyS3(10, &requests), MaxAttempts: 3, maxBackoff: time.Millisecond}
+
+	if _, err := store.Upload(context.Background(), "hello", ""); err == nil {
+		t.Fatal("Expected upload to fail")
+	}
+	if requests != 3 {
+		t.Errorf("Expected 3 attempts, got %d", requests)
+	}
+}
+
+func TestS3PresignPutURL(t *testing.T) {
+	useStaticAWSCredentials(t)
+
+	store := &S3ArtifactStore{Bucket: "results"}
+	key := defaultKeyPrefix + "uploads/1700000000-chart.png"
+	uploadURL, err := store.presignPutURL(context.Background(), key)
+	if err != nil {
+		t.Fatalf("presignPutURL failed: %v", err)
+	}
+	u, err := url.Parse(uploadURL)
+	if err != nil {
+		t.Fatalf("Expected a valid URL, got %s", uploadURL)
+	}
+	if u.Host != "results.s3.us-west-2.amazonaws.com" || u.Path != "/"+key {
+		t.Errorf("Expected a URL for %s in the results bucket, got %s", key, uploadURL)
+	}
+	query := u.Query()
+	if query.Get("X-Amz-Signature") == "" {
+		t.Errorf("Expected a signed URL, got %s", uploadURL)
+	}
+	if got := query.Get("X-Amz-Expires"); got != "900" {
+		t.Errorf("Expected the upload URL to expire after 900 seconds, got %s", got)
+	}
+	// Only the host is signed, so the uploaded body can have any content type
+	if got := query.Get("X-Amz-SignedHeaders"); got != "host" {
+		t.Errorf("Expected only the host header to be signed, got %s", got)
+	}
+}
+
+func TestS3List(t *testing.T) {
+	useStaticAWSCredentials(t)
+
+	var query url.Values
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		query = req.URL.Query()
+		body := `<ListBucketResult>
+			<IsTruncated>true</IsTruncated>
+			<NextContinuationToken>next-page</NextContinuationToken>
+			<Contents><Key>code-results/uploads/1-a.png</Key><Size>42</Size><LastModified>2024-05-01T12:00:00.000Z</LastModified></Contents>
+		</ListBucketResult>`
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader(body)), Header: http.Header{"Content-Type": {"application/xml"}}, Request: req}, nil
+	})}
+	store := &S3ArtifactStore{Bucket: "results", httpClient: client}
+
+	objects, next, err := store.List(context.Background(), "uploads/", "", 10)
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if query.Get("prefix") != defaultKeyPrefix+"uploads/" || query.Get("max-keys") != "10" {
+		t.Errorf("Expected the key prefix and limit in the request, got %v", query)
+	}
+	if next != "next-page" {
+		t.Errorf("Expected next cursor next-page, got %q", next)
+	}
+	if len(objects) != 1 || objects[0].Key != "code-results/uploads/1-a.png" || objects[0].Size != 42 {
+		t.Fatalf("Expected the listed object, got %+v", objects)
+	}
+	if !strings.Contains(objects[0].URL, "X-Amz-Signature") {
+		t.Errorf("Expected a presigned URL, got %s", objects[0].URL)
+	}
+}
+
+func TestS3CustomEndpoint(t *testing.T) {
+	useStaticAWSCredentials(t)
+
+	var hosts, paths []string
+	client := &http.Client{Transport: roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		hosts = append(hosts, req.URL.Host)
+		paths = append(paths, req.URL.Path)
+		return &http.Response{StatusCode: http.StatusOK, Body: io.NopCloser(strings.NewReader("")), Header: http.Header{}, Request: req}, nil
+	})}
+	store := &S3ArtifactStore{
+		Bucket:         "results",
+		Endpoint:       "http://minio.local:9000",
+		ForcePathStyle: true,
+		httpClient:     client,
+	}
+
+	url, err := store.Upload(context.Background(), "hello", "")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if len(hosts) != 1 || hosts[0] != "minio.local:9000" {
+		t.Fatalf("Expected the upload to go to the custom endpoint, got %v", hosts)
+	}
+	if !strings.HasPrefix(paths[0], "/results/"+defaultKeyPrefix) {
+		t.Errorf("Expected a path-style object path, got %s", paths[0])
+	}
+	if !strings.HasPrefix(url, "http://minio.local:9000/results/") {
+		t.Errorf("Expected the presigned URL to use the endpoint, got %s", url)
+	}
+}
+
+func TestS3NetHosts(t *testing.T) {
+	tests := []struct {
+		store    *S3ArtifactStore
+		expected []string
+	}{
+		{&S3ArtifactStore{}, []string{"s3." + defaultS3Region + ".amazonaws.com", defaultS3Bucket + ".s3." + defaultS3Region + ".amazonaws.com"}},
+		{&S3ArtifactStore{Bucket: "results", Region: "eu-west-1"}, []string{"s3.eu-west-1.amazonaws.com", "results.s3.eu-west-1.amazonaws.com"}},
+		{&S3ArtifactStore{Bucket: "results", Endpoint: "http://minio.local:9000", ForcePathStyle: true}, []string{"minio.local:9000"}},
+		{&S3ArtifactStore{Bucket: "results", Endpoint: "https://r2.example.com"}, []string{"r2.example.com", "results.r2.example.com"}},
+	}
+	for _, tt := range tests {
+		if got := tt.store.netHosts(); !slices.Equal(got, tt.expected) {
+			t.Errorf("%+v: Expected %q, got %q", tt.store, tt.expected, got)
+		}
+	}
+}
+
+func TestS3StoreFromEnvEndpoint(t *testing.T) {
+	t.Setenv("ARTIFACT_STORE", "s3")
+	t.Setenv("S3_ENDPOINT", "https://account.r2.cloudflarestorage.com")
+	t.Setenv("S3_FORCE_PATH_STYLE", "true")
+
+	store, err := newArtifactStoreFromEnv("http://localhost:3000")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	s3Store, ok := store.(*S3ArtifactStore)
+	if !ok {
+		t.Fatalf("Expected an S3 store, got %T", store)
+	}
+	if s3Store.Endpoint != "https://account.r2.cloudflarestorage.com" || !s3Store.ForcePathStyle {
+		t.Errorf("Expected endpoint settings from env, got %+v", s3Store)
+	}
+
+	t.Setenv("S3_FORCE_PATH_STYLE", "maybe")
+	if _, err := newArtifactStoreFromEnv("http://localhost:3000"); err == nil {
+		t.Error("Expected an error for an invalid S3_FORCE_PATH_STYLE")
+	}
+}
+
+func TestS3StoreFromEnvSettings(t *testing.T) {
+	t.Setenv("ARTIFACT_STORE", "s3")
+	t.Setenv("S3_RESULT_EXPIRES", "48h")
+	t.Setenv("S3_MAX_ATTEMPTS", "5")
+
+	store, err := newArtifactStoreFromEnv("")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if s3Store := store.(*S3ArtifactStore); s3Store.Expires != 48*time.Hour || s3Store.MaxAttempts != 5 {
+		t.Errorf("Expected the settings from env, got %+v", s3Store)
+	}
+
+	// Every bad setting is reported, and validation reports the same ones
+	t.Setenv("S3_RESULT_EXPIRES", "-1h")
+	t.Setenv("S3_MAX_ATTEMPTS", "0")
+	_, err = newArtifactStoreFromEnv("")
+	if err == nil || !strings.Contains(err.Error(), "S3_RESULT_EXPIRES") || !strings.Contains(err.Error(), "S3_MAX_ATTEMPTS") {
+		t.Errorf("Expected errors for both settings, got %v", err)
+	}
+	setValidConfig(t)
+	t.Setenv("S3_RESULT_EXPIRES", "-1h")
+	if err := validateConfig(); err == nil || !strings.Contains(err.Error(), "S3_RESULT_EXPIRES") {
+		t.Errorf("Expected validation to refuse a negative S3_RESULT_EXPIRES, got %v", err)
+	}
+}
@@ -0,0 +1,281 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"google.golang.org/adk/tool"
+)
+
+// CalculateParams defines the input parameters for the calculate tool
+type CalculateParams struct {
+	Expression string `json:"expression" jsonschema:"Arithmetic expression, e.g. (2+3)*4^2/7. Supports + - * / % ^, parentheses, pi, e and sqrt, abs, floor, ceil, round, ln, log, sin, cos, tan"`
+}
+
+// CalculateResults defines the output of the calculate tool
+type CalculateResults struct {
+	Status       string  `json:"status"`
+	Result       float64 `json:"result"`
+	ErrorMessage string  `json:"error_message,omitempty"`
+}
+
+// errDivisionByZero is returned when an expression divides by zero
+var errDivisionByZero = errors.New("division by zero")
+
+// calculatorFunctions are the functions an expression may call
+var calculatorFunctions = map[string]func(float64) float64{
+	"sqrt":  math.Sqrt,
+	"abs":   math.Abs,
+	"floor": math.Floor,
+	"ceil":  math.Ceil,
+	"round": math.Round,
+	"ln":    math.Log,
+	"log":   math.Log10,
+	"sin":   math.Sin,
+	"cos":   math.Cos,
+	"tan":   math.Tan,
+}
+
+// calculatorConstants are the named values an expression may use
+var calculatorConstants = map[string]float64{
+	"pi": math.Pi,
+	"e":  math.E,
+}
+
+// Calculate evaluates an arithmetic expression in-process, without Deno
+func Calculate(ctx tool.Context, params CalculateParams) CalculateResults {
+	result, err := evaluateExpression(params.Expression)
+	if err != nil {
+		return CalculateResults{Status: "error", ErrorMessage: err.Error()}
+	}
+	return CalculateResults{Status: "success", Result: result}
+}
+
+// evaluateExpression parses and evaluates expr
+func evaluateExpression(expr string) (float64, error) {
+	p := &exprParser{input: expr}
+	p.skipSpaces()
+	if p.done() {
+		return 0, fmt.Errorf("empty expression")
+	}
+
+	value, err := p.parseSum()
+	if err != nil {
+		return 0, err
+	}
+	if !p.done() {
+		return 0, fmt.Errorf("unexpected %q at position %d", p.input[p.pos], p.pos+1)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("result is not a finite number")
+	}
+	return value, nil
+}
+
+// exprParser is a recursive descent parser for arithmetic expressions:
+//
+//	sum     = product { ("+" | "-") product }
+//	product = unary { ("*" | "/" | "%") unary }
+//	unary   = { "+" | "-" } power
+//	power   = primary [ "^" unary ]
+//	primary = number | name | name "(" sum ")" | "(" sum ")"
+type exprParser struct {
+	input string
+	pos   int
+}
+
+func (p *exprParser) done() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *exprParser) skipSpaces() {
+	for !p.done() && unicode.IsSpace(rune(p.input[p.pos])) {
+		p.pos++
+	}
+}
+
+// accept consumes op if it is the next character
+func (p *exprParser) accept(op byte) bool {
+	if !p.done() && p.input[p.pos] == op {
+		p.pos++
+		p.skipSpaces()
+		return true
+	}
+	return false
+}
+
+func (p *exprParser) parseSum() (float64, error) {
+	left, err := p.parseProduct()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		switch {
+		case p.accept('+'):
+			right, err := p.parseProduct()
+			if err != nil {
+				return 0, err
+			}
+			left += right
+		case p.accept('-'):
+			right, err := p.parseProduct()
+			if err != nil {
+				return 0, err
+			}
+			left -= right
+		default:
+			return left, nil
+		}
+	}
+}
+
+func (p *exprParser) parseProduct() (float64, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return 0, err
+	}
+	for {
+		var op byte
+		switch {
+		case p.accept('*'):
+			op = '*'
+		case p.accept('/'):
+			op = '/'
+		case p.accept('%'):
+			op = '%'
+		default:
+			return left, nil
+		}
+
+		right, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		switch op {
+		case '*':
+			left *= right
+		case '/':
+			if right == 0 {
+				return 0, errDivisionByZero
+			}
+			left /= right
+		case '%':
+			if right == 0 {
+				return 0, errDivisionByZero
+			}
+			left = math.Mod(left, right)
+		}
+	}
+}
+
+func (p *exprParser) parseUnary() (float64, error) {
+	switch {
+	case p.accept('-'):
+		value, err := p.parseUnary()
+		return -value, err
+	case p.accept('+'):
+		return p.parseUnary()
+	}
+	return p.parsePower()
+}
+
+func (p *exprParser) parsePower() (float64, error) {
+	base, err := p.parsePrimary()
+	if err != nil {
+		return 0, err
+	}
+	// ^ is right associative and binds tighter than unary minus on its left
+	if p.accept('^') {
+		exponent, err := p.parseUnary()
+		if err != nil {
+			return 0, err
+		}
+		return math.Pow(base, exponent), nil
+	}
+	return base, nil
+}
+
+func (p *exprParser) parsePrimary() (float64, error) {
+	if p.done() {
+		return 0, fmt.Errorf("unexpected end of expression")
+	}
+
+	if p.accept('(') {
+		value, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if !p.accept(')') {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		return value, nil
+	}
+
+	c := p.input[p.pos]
+	switch {
+	case c >= '0' && c <= '9' || c == '.':
+		return p.parseNumber()
+	case unicode.IsLetter(rune(c)):
+		return p.parseName()
+	}
+	return 0, fmt.Errorf("unexpected %q at position %d", c, p.pos+1)
+}
+
+func (p *exprParser) parseNumber() (float64, error) {
+	start := p.pos
+	for !p.done() && (p.input[p.pos] >= '0' && p.input[p.pos] <= '9' || p.input[p.pos] == '.') {
+		p.pos++
+	}
+	// Optional exponent, as in 1.5e3
+	if !p.done() && (p.input[p.pos] == 'e' || p.input[p.pos] == 'E') {
+		end := p.pos + 1
+		if end < len(p.input) && (p.input[end] == '+' || p.input[end] == '-') {
+			end++
+		}
+		if end < len(p.input) && p.input[end] >= '0' && p.input[end] <= '9' {
+			p.pos = end
+			for !p.done() && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+				p.pos++
+			}
+		}
+	}
+
+	text := p.input[start:p.pos]
+	value, err := strconv.ParseFloat(text, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", text)
+	}
+	p.skipSpaces()
+	return value, nil
+}
+
+func (p *exprParser) parseName() (float64, error) {
+	start := p.pos
+	for !p.done() && unicode.IsLetter(rune(p.input[p.pos])) {
+		p.pos++
+	}
+	name := strings.ToLower(p.input[start:p.pos])
+	p.skipSpaces()
+
+	if fn, ok := calculatorFunctions[name]; ok {
+		if !p.accept('(') {
+			return 0, fmt.Errorf("%s needs an argument in parentheses", name)
+		}
+		arg, err := p.parseSum()
+		if err != nil {
+			return 0, err
+		}
+		if !p.accept(')') {
+			return 0, fmt.Errorf("missing closing parenthesis")
+		}
+		return fn(arg), nil
+	}
+	if value, ok := calculatorConstants[name]; ok {
+		return value, nil
+	}
+	return 0, fmt.Errorf("unknown name %q", name)
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestEvaluateExpression(t *testing.T) {
+	tests := []struct {
+		expr string
+		want float64
+	}{
+		{"2+2", 4},
+		{" 2 + 3 * 4 ", 14},
+		{"(2+3)*4", 20},
+		{"10 - 4 - 3", 3},
+		{"100 / 10 / 5", 2},
+		{"7 % 3", 1},
+		{"2^3^2", 512},
+		{"-2^2", -4},
+		{"2^-1", 0.5},
+		{"--3", 3},
+		{"1.5e3 + .5", 1500.5},
+		{"sqrt(16) + abs(-2)", 6},
+		{"round(pi * 100)", 314},
+	}
+
+	for _, tt := range tests {
+		got, err := evaluateExpression(tt.expr)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", tt.expr, err)
+			continue
+		}
+		if math.Abs(got-tt.want) > 1e-9 {
+			t.Errorf("%q: Expected %v, got %v", tt.expr, tt.want, got)
+		}
+	}
+}
+
+func TestEvaluateExpressionDivisionByZero(t *testing.T) {
+	for _, expr := range []string{"1/0", "5 % (2-2)"} {
+		if _, err := evaluateExpression(expr); !errors.Is(err, errDivisionByZero) {
+			t.Errorf("%q: Expected division by zero, got %v", expr, err)
+		}
+	}
+}
+
+func TestEvaluateExpressionInvalid(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{"", "empty expression"},
+		{"2 +", "unexpected end"},
+		{"(1 + 2", "missing closing parenthesis"},
+		{"1 + 2)", "unexpected ')'"},
+		{"2 $ 3", "unexpected '$'"},
+		{"foo(2)", "unknown name"},
+		{"sqrt 4", "needs an argument"},
+		{"1..2", "invalid number"},
+		{"sqrt(-1)", "not a finite number"},
+	}
+
+	for _, tt := range tests {
+		_, err := evaluateExpression(tt.expr)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%q: Expected error containing %q, got %v", tt.expr, tt.want, err)
+		}
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	result := Calculate(nil, CalculateParams{Expression: "6*7"})
+	if result.Status != "success" || result.Result != 42 {
+		t.Errorf("Expected success with 42, got %+v", result)
+	}
+
+	result = Calculate(nil, CalculateParams{Expression: "1/0"})
+	if result.Status != "error" || result.ErrorMessage != "division by zero" {
+		t.Errorf("Expected division by zero error, got %+v", result)
+	}
+}
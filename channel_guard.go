@@ -0,0 +1,33 @@
+package main
+
+import "strings"
+
+// ChannelGuard restricts the channels the bot operates in. An empty guard
+// allows every channel.
+type ChannelGuard struct {
+	allowed map[string]bool // lowercased channel names
+}
+
+// NewChannelGuard creates a guard allowing only channels, or any channel when
+// channels is empty
+func NewChannelGuard(channels []string) *ChannelGuard {
+	g := &ChannelGuard{allowed: make(map[string]bool)}
+	for _, channel := range channels {
+		g.allowed[strings.ToLower(channel)] = true
+	}
+	return g
+}
+
+// Restricted reports whether the guard limits the bot to a list of channels
+func (g *ChannelGuard) Restricted() bool {
+	return len(g.allowed) > 0
+}
+
+// Allowed reports whether the bot may operate in target. Private messages are
+// always allowed, since they aren't a channel the bot was pulled into.
+func (g *ChannelGuard) Allowed(target string) bool {
+	if !g.Restricted() || !isChannel(target) {
+		return true
+	}
+	return g.allowed[strings.ToLower(target)]
+}
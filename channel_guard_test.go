@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestChannelGuard(t *testing.T) {
+	guard := NewChannelGuard([]string{"#agent", "#Dev"})
+
+	tests := []struct {
+		target  string
+		allowed bool
+	}{
+		{"#agent", true},
+		{"#AGENT", true},
+		{"#dev", true},
+		{"#random", false},
+		{"&local", false},
+		{"alice", true}, // private messages
+	}
+	for _, tt := range tests {
+		if got := guard.Allowed(tt.target); got != tt.allowed {
+			t.Errorf("%s: Expected allowed=%v, got %v", tt.target, tt.allowed, got)
+		}
+	}
+}
+
+func TestEmptyChannelGuardAllowsAll(t *testing.T) {
+	guard := NewChannelGuard(nil)
+	if guard.Restricted() {
+		t.Error("Expected an empty guard not to be restricted")
+	}
+	if !guard.Allowed("#anywhere") {
+		t.Error("Expected an empty guard to allow any channel")
+	}
+}
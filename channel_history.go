@@ -0,0 +1,79 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// ChannelMessage is a single line seen in a channel
+type ChannelMessage struct {
+	Sender  string
+	Message string
+}
+
+// ChannelHistory keeps a rolling buffer of the most recent messages per channel
+type ChannelHistory struct {
+	mu       sync.Mutex
+	size     int                         // maximum messages kept per channel
+	messages map[string][]ChannelMessage // maps lowercased channel -> oldest-first messages
+}
+
+// NewChannelHistory creates a history that keeps the last size messages per channel.
+// A size of zero or less disables recording.
+func NewChannelHistory(size int) *ChannelHistory {
+	return &ChannelHistory{
+		size:     size,
+		messages: make(map[string][]ChannelMessage),
+	}
+}
+
+// Add records a message, dropping the oldest one once the buffer is full.
+// Comma commands are skipped, since they're addressed to the bot rather
+// than part of the conversation.
+func (h *ChannelHistory) Add(channel, sender, message string) {
+	if h.size <= 0 || strings.HasPrefix(message, ",") {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	key := strings.ToLower(channel)
+	buf := append(h.messages[key], ChannelMessage{Sender: sender, Message: message})
+	if len(buf) > h.size {
+		buf = buf[len(buf)-h.size:]
+	}
+	h.messages[key] = buf
+}
+
+// Recent returns a copy of the buffered messages for channel, oldest first
+func (h *ChannelHistory) Recent(channel string) []ChannelMessage {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	buf := h.messages[strings.ToLower(channel)]
+	recent := make([]ChannelMessage, len(buf))
+	copy(recent, buf)
+	return recent
+}
+
+// buildPrompt formats an incoming message for the agent, prefixed with the
+// recent channel conversation so the model can follow group discussions
+func buildPrompt(sender, channel, message string, recent []ChannelMessage) string {
+	var prompt strings.Builder
+
+	if len(recent) > 0 {
+		prompt.WriteString("Recent messages in " + channel + " (for context):\n")
+		for _, m := range recent {
+			if action, ok := parseAction(m.Message); ok {
+				prompt.WriteString("* " + m.Sender + " " + action + "\n")
+			} else {
+				prompt.WriteString("<" + m.Sender + "> " + m.Message + "\n")
+			}
+		}
+		prompt.WriteString("\n")
+	}
+
+	prompt.WriteString("User " + sender + " in channel " + channel + " " + describeMessage(message) + "\n")
+	return prompt.String()
+}
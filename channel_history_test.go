@@ -0,0 +1,82 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestChannelHistoryKeepsMostRecent(t *testing.T) {
+	h := NewChannelHistory(3)
+	for _, m := range []string{"one", "two", "three", "four", "five"} {
+		h.Add("#agent", "alice", m)
+	}
+
+	recent := h.Recent("#agent")
+	if len(recent) != 3 {
+		t.Fatalf("Expected 3 messages, got %d", len(recent))
+	}
+	for i, want := range []string{"three", "four", "five"} {
+		if recent[i].Message != want || recent[i].Sender != "alice" {
+			t.Errorf("Expected message %d to be alice's %q, got %+v", i, want, recent[i])
+		}
+	}
+}
+
+func TestChannelHistoryPerChannel(t *testing.T) {
+	h := NewChannelHistory(5)
+	h.Add("#Agent", "alice", "hello")
+	h.Add("#other", "bob", "hi")
+
+	if recent := h.Recent("#agent"); len(recent) != 1 || recent[0].Message != "hello" {
+		t.Errorf("Expected channel names to be case-insensitive, got %+v", recent)
+	}
+	if recent := h.Recent("#other"); len(recent) != 1 || recent[0].Sender != "bob" {
+		t.Errorf("Expected #other to keep its own messages, got %+v", recent)
+	}
+	if recent := h.Recent("#empty"); len(recent) != 0 {
+		t.Errorf("Expected no messages for an unseen channel, got %+v", recent)
+	}
+}
+
+func TestChannelHistoryRecentIsACopy(t *testing.T) {
+	h := NewChannelHistory(2)
+	h.Add("#agent", "alice", "first")
+	recent := h.Recent("#agent")
+	recent[0].Message = "changed"
+	h.Add("#agent", "alice", "second")
+
+	if got := h.Recent("#agent")[0].Message; got != "first" {
+		t.Errorf("Expected the buffer to be unaffected by callers, got %q", got)
+	}
+}
+
+func TestChannelHistorySkips(t *testing.T) {
+	h := NewChannelHistory(5)
+	h.Add("#agent", "alice", ",ping")
+	h.Add("#agent", "alice", "agent: what's up")
+	if recent := h.Recent("#agent"); len(recent) != 1 || recent[0].Message != "agent: what's up" {
+		t.Errorf("Expected comma commands to be left out, got %+v", recent)
+	}
+
+	disabled := NewChannelHistory(0)
+	disabled.Add("#agent", "alice", "hello")
+	if recent := disabled.Recent("#agent"); len(recent) != 0 {
+		t.Errorf("Expected a zero size to disable recording, got %+v", recent)
+	}
+}
+
+func TestBuildPrompt(t *testing.T) {
+	prompt := buildPrompt("alice", "#agent", "agent: and you?", []ChannelMessage{
+		{Sender: "bob", Message: "how is everyone"},
+	})
+	if !strings.HasPrefix(prompt, "Recent messages in #agent (for context):\n<bob> how is everyone\n\n") {
+		t.Errorf("Expected the recent messages first, got %q", prompt)
+	}
+	if !strings.HasSuffix(prompt, "User alice in channel #agent "+describeMessage("agent: and you?")+"\n") {
+		t.Errorf("Expected the message last, got %q", prompt)
+	}
+
+	if prompt := buildPrompt("alice", "#agent", "hi", nil); strings.Contains(prompt, "Recent messages") {
+		t.Errorf("Expected no context section without history, got %q", prompt)
+	}
+}
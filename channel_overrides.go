@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/agent/llmagent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// ChannelOverride customizes the agent for a single channel. Empty fields fall
+// back to the global defaults.
+type ChannelOverride struct {
+	Instruction string   `json:"instruction,omitempty"`
+	Model       string   `json:"model,omitempty"`
+	Temperature *float32 `json:"temperature,omitempty"`
+	Greeting    string   `json:"greeting,omitempty"` // sent after the bot joins the channel
+}
+
+// ChannelOverrides maps lowercased channel names to their overrides. A key
+// like "libera/#go" applies to one network only and wins over "#go".
+type ChannelOverrides map[string]ChannelOverride
+
+// loadChannelOverrides reads per-channel overrides as a JSON object keyed by
+// channel, from the file in CHANNEL_OVERRIDES_FILE or inline from CHANNEL_OVERRIDES.
+// Returns an empty set when neither is configured.
+func loadChannelOverrides() (ChannelOverrides, error) {
+	var data []byte
+	if path := os.Getenv("CHANNEL_OVERRIDES_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CHANNEL_OVERRIDES_FILE: %w", err)
+		}
+		data = b
+	} else if inline := os.Getenv("CHANNEL_OVERRIDES"); inline != "" {
+		data = []byte(inline)
+	} else {
+		return ChannelOverrides{}, nil
+	}
+
+	var raw map[string]ChannelOverride
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse channel overrides: %w", err)
+	}
+
+	// IRC channel names are case-insensitive
+	overrides := make(ChannelOverrides, len(raw))
+	for channel, override := range raw {
+		if override.Temperature != nil && (*override.Temperature < 0 || *override.Temperature > 1) {
+			return nil, fmt.Errorf("temperature for %s must be between 0 and 1, got %v", channel, *override.Temperature)
+		}
+		overrides[strings.ToLower(channel)] = override
+	}
+	return overrides, nil
+}
+
+// lookup returns the override for a runner user ID, which names the channel
+// and, on named networks, the network, if any
+func (o ChannelOverrides) lookup(userID string) (ChannelOverride, bool) {
+	if override, ok := o[strings.ToLower(userID)]; ok {
+		return override, true
+	}
+	_, channel := splitUserID(userID)
+	override, ok := o[strings.ToLower(channel)]
+	return override, ok
+}
+
+// greeting returns the message to send after joining the channel in userID,
+// preferring the channel's override over defaultGreeting. Empty means stay
+// silent.
+func (o ChannelOverrides) greeting(userID, defaultGreeting string) string {
+	if override, ok := o.lookup(userID); ok && override.Greeting != "" {
+		return override.Greeting
+	}
+	return defaultGreeting
+}
+
+// instructionProvider returns the system instruction for the channel of the
+// current session, falling back to defaultInstruction for its user ID. The
+// runner's user ID names the channel and network, so it identifies them here.
+func (o ChannelOverrides) instructionProvider(defaultInstruction func(userID string) (string, error)) llmagent.InstructionProvider {
+	return func(ctx agent.ReadonlyContext) (string, error) {
+		if override, ok := o.lookup(ctx.UserID()); ok && override.Instruction != "" {
+			return override.Instruction, nil
+		}
+		return defaultInstruction(ctx.UserID())
+	}
+}
+
+// beforeModel applies the channel's model and temperature overrides to the
+// outgoing request. agent.RunConfig has no generation settings, so this is
+// done as a model callback instead.
+func (o ChannelOverrides) beforeModel(ctx agent.CallbackContext, req *model.LLMRequest) (*model.LLMResponse, error) {
+	override, ok := o.lookup(ctx.UserID())
+	if !ok {
+		return nil, nil
+	}
+
+	if override.Model != "" {
+		req.Model = override.Model
+	}
+	if override.Temperature != nil {
+		if req.Config == nil {
+			req.Config = &genai.GenerateContentConfig{}
+		}
+		req.Config.Temperature = override.Temperature
+	}
+	return nil, nil
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLoadChannelOverrides(t *testing.T) {
+	t.Setenv("CHANNEL_OVERRIDES", `{"#Go": {"model": "claude-sonnet-4-5", "temperature": 0.2}, "#help": {"greeting": "hi"}}`)
+	overrides, err := loadChannelOverrides()
+	if err != nil {
+		t.Fatalf("Failed to load overrides: %v", err)
+	}
+
+	tests := []struct {
+		channel string
+		found   bool
+		model   string
+	}{
+		{"#go", true, "claude-sonnet-4-5"},
+		{"#GO", true, "claude-sonnet-4-5"},
+		{"#help", true, ""},
+		{"#other", false, ""},
+	}
+	for _, tt := range tests {
+		override, ok := overrides.lookup(tt.channel)
+		if ok != tt.found || override.Model != tt.model {
+			t.Errorf("%s: Expected found=%v model %q, got found=%v model %q", tt.channel, tt.found, tt.model, ok, override.Model)
+		}
+	}
+	if override, _ := overrides.lookup("#go"); override.Temperature == nil || *override.Temperature != 0.2 {
+		t.Errorf("Expected a temperature of 0.2 for #go, got %v", override.Temperature)
+	}
+}
+
+func TestChannelOverridesPerNetwork(t *testing.T) {
+	overrides := ChannelOverrides{
+		"#go":        {Model: "claude-sonnet-4-5"},
+		"libera/#go": {Model: "claude-opus-4-1"},
+	}
+
+	tests := map[string]string{
+		"libera/#go": "claude-opus-4-1",
+		"Libera/#GO": "claude-opus-4-1",
+		"oftc/#go":   "claude-sonnet-4-5",
+		"#go":        "claude-sonnet-4-5",
+	}
+	for userID, want := range tests {
+		if override, _ := overrides.lookup(userID); override.Model != want {
+			t.Errorf("%s: Expected model %q, got %q", userID, want, override.Model)
+		}
+	}
+}
+
+func TestLoadChannelOverridesErrors(t *testing.T) {
+	for _, raw := range []string{
+		`not json`,
+		`{"#go": {"temperature": 1.5}}`,
+		`{"#go": {"temperature": -0.1}}`,
+	} {
+		t.Setenv("CHANNEL_OVERRIDES", raw)
+		if _, err := loadChannelOverrides(); err == nil {
+			t.Errorf("Expected an error for %s", raw)
+		}
+	}
+
+	t.Setenv("CHANNEL_OVERRIDES", "")
+	if overrides, err := loadChannelOverrides(); err != nil || len(overrides) != 0 {
+		t.Errorf("Expected no overrides when unset, got %v (%v)", overrides, err)
+	}
+}
+
+func TestChannelOverridesGreeting(t *testing.T) {
+	overrides := ChannelOverrides{
+		"#help":  {Greeting: "Ask away"},
+		"#quiet": {Model: "claude-sonnet-4-5"},
+	}
+
+	tests := []struct {
+		channel string
+		want    string
+	}{
+		{"#help", "Ask away"},
+		{"#Help", "Ask away"},
+		{"#quiet", "hello"},
+		{"#other", "hello"},
+	}
+	for _, tt := range tests {
+		if got := overrides.greeting(tt.channel, "hello"); got != tt.want {
+			t.Errorf("%s: Expected greeting %q, got %q", tt.channel, tt.want, got)
+		}
+	}
+}
+
+func TestModelCommandUsesOverride(t *testing.T) {
+	tests := []struct {
+		channel string
+		want    string
+	}{
+		{"#agent", "alice: anthropic/claude-sonnet-4-5 (channel override, default claude-haiku-4-5)"},
+		{"#plain", "alice: anthropic/claude-haiku-4-5"},
+		{"#tuned", "alice: anthropic/claude-haiku-4-5"},
+	}
+	for _, tt := range tests {
+		ia, sink := newTestAgent(nil)
+		ia.outbound.Joined(tt.channel)
+		ia.provider = "anthropic"
+		ia.modelName = "claude-haiku-4-5"
+		temperature := float32(0.5)
+		ia.overrides = ChannelOverrides{
+			"#agent": {Model: "claude-sonnet-4-5"},
+			"#tuned": {Temperature: &temperature},
+		}
+
+		ia.handleCommaCommand("alice", ",model", tt.channel, time.Now())
+		if sent := sink.Messages(); len(sent) != 1 || sent[0] != tt.want {
+			t.Errorf("%s: Expected %q, got %q", tt.channel, tt.want, sent)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/r33drichards/irc-agent/internal/envconf"
+)
+
+// parseChannels splits the comma-separated CHANNEL setting into channel names
+func parseChannels(raw string) ([]string, error) {
+	channels := envconf.SplitList(raw)
+	if len(channels) == 0 {
+		return nil, fmt.Errorf("no channels configured")
+	}
+
+	seen := make(map[string]bool)
+	var unique []string
+	for _, channel := range channels {
+		if !isChannel(channel) || strings.ContainsAny(channel, " \x07") {
+			return nil, fmt.Errorf("invalid channel name %q", channel)
+		}
+		if key := strings.ToLower(channel); !seen[key] {
+			seen[key] = true
+			unique = append(unique, channel)
+		}
+	}
+	return unique, nil
+}
+
+// joinUsage is the reply to a malformed ,join command
+const joinUsage = "Usage: ,join <#channel> [key]"
+
+// parseJoinArgs validates the arguments of ,join: a channel and an optional key
+func parseJoinArgs(args []string) (channel, key string, err error) {
+	if len(args) == 0 || len(args) > 2 {
+		return "", "", fmt.Errorf("expected a channel and an optional key")
+	}
+
+	channel = args[0]
+	if !isChannel(channel) || strings.ContainsAny(channel, ",\x07") {
+		return "", "", fmt.Errorf("invalid channel name %q", channel)
+	}
+	if len(args) == 2 {
+		key = args[1]
+		// Commas would be read as a list of keys
+		if strings.Contains(key, ",") {
+			return "", "", fmt.Errorf("channel keys can't contain commas")
+		}
+	}
+	return channel, key, nil
+}
+
+// replyTarget picks where to answer a PRIVMSG. Channel messages are answered
+// in the channel they came from; private messages, addressed to our own nick,
+// are answered to the sender.
+func replyTarget(target, sender, ourNick string) string {
+	if strings.EqualFold(target, ourNick) || !isChannel(target) {
+		return sender
+	}
+	return target
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseChannels(t *testing.T) {
+	channels, err := parseChannels(" #agent, #Dev ,#agent,&local ")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := []string{"#agent", "#Dev", "&local"}
+	if !reflect.DeepEqual(channels, expected) {
+		t.Errorf("Expected %v, got %v", expected, channels)
+	}
+
+	for _, raw := range []string{"", " , ", "agent", "#has space"} {
+		if _, err := parseChannels(raw); err == nil {
+			t.Errorf("Expected error for %q", raw)
+		}
+	}
+}
+
+func TestReplyTarget(t *testing.T) {
+	tests := []struct {
+		target   string
+		expected string
+	}{
+		{"#agent", "#agent"},
+		{"#dev", "#dev"},
+		{"agent", "alice"}, // private message to the bot
+		{"Agent", "alice"},
+	}
+
+	for _, tt := range tests {
+		if got := replyTarget(tt.target, "alice", "agent"); got != tt.expected {
+			t.Errorf("replyTarget(%q) = %q, expected %q", tt.target, got, tt.expected)
+		}
+	}
+}
+
+func TestParseJoinArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		channel string
+		key     string
+	}{
+		{[]string{"#secret"}, "#secret", ""},
+		{[]string{"#secret", "hunter2"}, "#secret", "hunter2"},
+		{[]string{"&local", "k"}, "&local", "k"},
+	}
+	for _, tt := range tests {
+		channel, key, err := parseJoinArgs(tt.args)
+		if err != nil {
+			t.Errorf("%v: Unexpected error: %v", tt.args, err)
+			continue
+		}
+		if channel != tt.channel || key != tt.key {
+			t.Errorf("%v: Expected %q with key %q, got %q with key %q", tt.args, tt.channel, tt.key, channel, key)
+		}
+	}
+
+	for _, args := range [][]string{
+		nil,
+		{"secret"},
+		{"#a,#b"},
+		{"#secret", "a,b"},
+		{"#secret", "key", "extra"},
+	} {
+		if _, _, err := parseJoinArgs(args); err == nil {
+			t.Errorf("%v: Expected an error", args)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Defaults for the model circuit breaker
+const (
+	defaultBreakerThreshold = 5
+	defaultBreakerCooldown  = time.Minute
+)
+
+// circuitOpenMessage is sent instead of calling the model while the circuit is open
+const circuitOpenMessage = "I'm temporarily unavailable, please try again in a few minutes"
+
+// circuitState is the state of a CircuitBreaker
+type circuitState int
+
+const (
+	circuitClosed   circuitState = iota // calls go through
+	circuitOpen                         // calls are refused until the cooldown ends
+	circuitHalfOpen                     // one trial call is in flight
+)
+
+func (s circuitState) String() string {
+	switch s {
+	case circuitOpen:
+		return "open"
+	case circuitHalfOpen:
+		return "half-open"
+	default:
+		return "closed"
+	}
+}
+
+// CircuitBreaker stops calling the model during a provider outage. After
+// threshold consecutive failures it opens for cooldown, refusing calls, then
+// lets a single trial call through: success closes it, failure reopens it.
+type CircuitBreaker struct {
+	mu        sync.Mutex
+	threshold int           // consecutive failures that open the circuit; zero or less disables it
+	cooldown  time.Duration // how long the circuit stays open before a trial call
+	state     circuitState
+	failures  int       // consecutive failures while closed
+	openedAt  time.Time // when the circuit last opened
+}
+
+// NewCircuitBreaker creates a breaker opening after threshold consecutive
+// failures for cooldown
+func NewCircuitBreaker(threshold int, cooldown time.Duration) *CircuitBreaker {
+	if cooldown <= 0 {
+		cooldown = defaultBreakerCooldown
+	}
+	return &CircuitBreaker{threshold: threshold, cooldown: cooldown}
+}
+
+// Allow reports whether a call may be made at now. Once the cooldown is over
+// the first caller is let through as the trial; the rest are refused until it
+// finishes.
+func (b *CircuitBreaker) Allow(now time.Time) bool {
+	if b.threshold <= 0 {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if now.Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		log.Printf("Model circuit breaker half-open, trying a call")
+		b.state = circuitHalfOpen
+		return true
+	case circuitHalfOpen:
+		return false
+	default:
+		return true
+	}
+}
+
+// Record reports the outcome of an allowed call, nil for success
+func (b *CircuitBreaker) Record(err error, now time.Time) {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err == nil {
+		if b.state != circuitClosed {
+			log.Printf("Model circuit breaker closed, calls succeeding again")
+		}
+		b.state = circuitClosed
+		b.failures = 0
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.threshold {
+		if b.state == circuitClosed {
+			log.Printf("Model circuit breaker open after %d consecutive failures, pausing calls for %s", b.failures, b.cooldown)
+		} else {
+			log.Printf("Model circuit breaker trial call failed, pausing calls for %s", b.cooldown)
+		}
+		b.state = circuitOpen
+		b.openedAt = now
+		b.failures = 0
+	}
+}
+
+// Release gives up an allowed call that ended without an outcome, such as a
+// run cancelled at shutdown, so it counts neither way. A trial call's place
+// goes to the next caller.
+func (b *CircuitBreaker) Release() {
+	if b.threshold <= 0 {
+		return
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == circuitHalfOpen {
+		b.state = circuitOpen
+	}
+}
+
+// State returns the breaker's current state
+func (b *CircuitBreaker) State() circuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
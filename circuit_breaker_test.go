@@ -0,0 +1,89 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreakerTransitions(t *testing.T) {
+	b := NewCircuitBreaker(3, time.Minute)
+	now := time.Now()
+	failure := errors.New("overloaded")
+
+	// Failures below the threshold, or interrupted by a success, keep it closed
+	b.Record(failure, now)
+	b.Record(failure, now)
+	b.Record(nil, now)
+	b.Record(failure, now)
+	b.Record(failure, now)
+	if b.State() != circuitClosed || !b.Allow(now) {
+		t.Fatalf("Expected the circuit to stay closed, got %s", b.State())
+	}
+
+	b.Record(failure, now)
+	if b.State() != circuitOpen {
+		t.Fatalf("Expected the circuit to open after 3 consecutive failures, got %s", b.State())
+	}
+	if b.Allow(now.Add(30 * time.Second)) {
+		t.Error("Expected calls to be refused during the cooldown")
+	}
+
+	// After the cooldown one trial call goes through
+	later := now.Add(time.Minute)
+	if !b.Allow(later) {
+		t.Fatal("Expected a trial call after the cooldown")
+	}
+	if b.State() != circuitHalfOpen || b.Allow(later) {
+		t.Errorf("Expected only one trial call while half-open, got %s", b.State())
+	}
+
+	// A failed trial reopens it for another cooldown
+	b.Record(failure, later)
+	if b.State() != circuitOpen || b.Allow(later.Add(30*time.Second)) {
+		t.Fatalf("Expected a failed trial to reopen the circuit, got %s", b.State())
+	}
+
+	// A successful trial closes it
+	latest := later.Add(time.Minute)
+	if !b.Allow(latest) {
+		t.Fatal("Expected another trial call after the second cooldown")
+	}
+	b.Record(nil, latest)
+	if b.State() != circuitClosed || !b.Allow(latest) {
+		t.Errorf("Expected a successful trial to close the circuit, got %s", b.State())
+	}
+}
+
+func TestCircuitBreakerRelease(t *testing.T) {
+	b := NewCircuitBreaker(1, time.Minute)
+	now := time.Now()
+	b.Record(errors.New("overloaded"), now)
+
+	later := now.Add(time.Minute)
+	if !b.Allow(later) {
+		t.Fatal("Expected a trial call after the cooldown")
+	}
+	b.Release()
+	if b.State() != circuitOpen || !b.Allow(later) {
+		t.Errorf("Expected a released trial to go to the next caller, got %s", b.State())
+	}
+
+	// Releasing a call while closed changes nothing
+	b.Record(nil, later)
+	b.Release()
+	if b.State() != circuitClosed {
+		t.Errorf("Expected the circuit to stay closed, got %s", b.State())
+	}
+}
+
+func TestCircuitBreakerDisabled(t *testing.T) {
+	b := NewCircuitBreaker(0, time.Minute)
+	now := time.Now()
+	for i := 0; i < 10; i++ {
+		b.Record(errors.New("down"), now)
+	}
+	if !b.Allow(now) {
+		t.Error("Expected a disabled breaker to always allow calls")
+	}
+}
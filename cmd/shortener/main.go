@@ -0,0 +1,76 @@
+// Command shortener runs the URL shortener on its own, without the bot, so
+// link resolution can be deployed and scaled separately. It reads the same
+// CONFIG_FILE and SHORTENER_* and REDIS_* settings as the bot; use Redis
+// storage so every instance, and the bot, see the same links.
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/r33drichards/irc-agent/internal/configfile"
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+// defaultArtifactDir matches the bot's default for ARTIFACT_DIR
+const defaultArtifactDir = "artifacts"
+
+func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run serves until SIGINT or SIGTERM. Returning errors rather than exiting
+// lets the deferred cleanup, like closing storage, run on failure too.
+func run() error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Settings from CONFIG_FILE fill in whatever the environment leaves unset
+	if err := configfile.Apply(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+	// Report every shortener setting problem up front, before opening storage
+	if err := shortener.CheckEnv(); err != nil {
+		return fmt.Errorf("invalid configuration:\n%w", err)
+	}
+
+	// Serve artifacts too when the bot writes them to a directory this
+	// process can read
+	var opts []shortener.ShortenerOption
+	if os.Getenv("ARTIFACT_STORE") == "filesystem" {
+		dir := os.Getenv("ARTIFACT_DIR")
+		if dir == "" {
+			dir = defaultArtifactDir
+		}
+		opts = append(opts, shortener.WithArtifactDir(dir))
+	}
+
+	urlShortener, closeStorage, err := shortener.FromEnv(shortener.Host(), opts...)
+	if err != nil {
+		return fmt.Errorf("invalid URL shortener configuration: %w", err)
+	}
+	defer closeStorage()
+
+	go func() {
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		if err := urlShortener.Shutdown(shutdownCtx); err != nil {
+			log.Printf("URL Shortener shutdown error: %v", err)
+		}
+	}()
+
+	port := shortener.Port()
+	log.Printf("Starting URL Shortener on port %s...", port)
+	if err := urlShortener.Serve(port); err != nil {
+		return fmt.Errorf("URL Shortener failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// CodeDenylist is a cheap pre-execution guardrail that rejects code mentioning
+// forbidden APIs. It is a text scan, not a sandbox: obfuscated code gets past
+// it, so Deno's permission flags remain the real boundary.
+type CodeDenylist struct {
+	substrings []string         // matched literally
+	patterns   []*regexp.Regexp // entries written as /regexp/
+}
+
+// NewCodeDenylist builds a denylist from entries such as "Deno.run" or
+// "/Deno\.(run|Command)/". Entries wrapped in slashes are regular expressions;
+// anything else is matched as a literal substring.
+func NewCodeDenylist(entries []string) (*CodeDenylist, error) {
+	l := &CodeDenylist{}
+	for _, entry := range entries {
+		if len(entry) > 2 && strings.HasPrefix(entry, "/") && strings.HasSuffix(entry, "/") {
+			re, err := regexp.Compile(entry[1 : len(entry)-1])
+			if err != nil {
+				return nil, fmt.Errorf("invalid denied code pattern %q: %w", entry, err)
+			}
+			l.patterns = append(l.patterns, re)
+			continue
+		}
+		l.substrings = append(l.substrings, entry)
+	}
+	return l, nil
+}
+
+// deniedCodeFromEnv builds the denylist in DENIED_CODE_PATTERNS, one entry
+// per line. Entries aren't comma-separated since regular expressions like
+// /\d{1,3}/ contain commas.
+func deniedCodeFromEnv() (*CodeDenylist, error) {
+	var entries []string
+	for _, line := range strings.Split(os.Getenv("DENIED_CODE_PATTERNS"), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			entries = append(entries, line)
+		}
+	}
+	l, err := NewCodeDenylist(entries)
+	if err != nil {
+		return nil, fmt.Errorf("DENIED_CODE_PATTERNS: %w", err)
+	}
+	return l, nil
+}
+
+// Check returns the first denied text found in code, if any
+func (l *CodeDenylist) Check(code string) (string, bool) {
+	if l == nil {
+		return "", false
+	}
+	for _, s := range l.substrings {
+		if strings.Contains(code, s) {
+			return s, true
+		}
+	}
+	for _, re := range l.patterns {
+		if match := re.FindString(code); match != "" {
+			return match, true
+		}
+	}
+	return "", false
+}
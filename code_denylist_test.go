@@ -0,0 +1,87 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCodeDenylist(t *testing.T) {
+	denylist, err := NewCodeDenylist([]string{"Deno.env", `/Deno\.(run|Command)\b/`})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		code   string
+		denied string
+	}{
+		{`console.log(2 + 2)`, ""},
+		{`const data = await fetch("https://example.com")`, ""},
+		{`// Deno.runtime is fine` + "\n" + `console.log(Deno.version)`, ""},
+		{`console.log(Deno.env.get("AWS_SECRET_ACCESS_KEY"))`, "Deno.env"},
+		{`Deno.run({ cmd: ["ls"] })`, "Deno.run"},
+		{`new Deno.Command("ls").output()`, "Deno.Command"},
+	}
+
+	for _, tt := range tests {
+		match, denied := denylist.Check(tt.code)
+		if denied != (tt.denied != "") || match != tt.denied {
+			t.Errorf("%q: Expected denied %q, got %q (%v)", tt.code, tt.denied, match, denied)
+		}
+	}
+}
+
+func TestCodeDenylistInvalidPattern(t *testing.T) {
+	if _, err := NewCodeDenylist([]string{"/([/"}); err == nil {
+		t.Error("Expected an error for an invalid regexp")
+	}
+}
+
+func TestNilCodeDenylistAllowsEverything(t *testing.T) {
+	var denylist *CodeDenylist
+	if _, denied := denylist.Check("Deno.run()"); denied {
+		t.Error("Expected a nil denylist to allow all code")
+	}
+}
+
+func TestExecuteRejectsDeniedCode(t *testing.T) {
+	denylist, err := NewCodeDenylist([]string{"Deno.env"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{
+		DenoPath:   writeFakeDeno(t, shellDeno),
+		DeniedCode: denylist,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "echo Deno.env"})
+	if result.Status != "error" || !strings.Contains(result.ErrorMessage, "Deno.env") || result.Output != "" {
+		t.Errorf("Expected denied code to be rejected before running, got %+v", result)
+	}
+
+	if result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "echo fine"}); result.Status != "success" {
+		t.Errorf("Expected allowed code to run, got %+v", result)
+	}
+}
+
+func TestDeniedCodeFromEnv(t *testing.T) {
+	t.Setenv("DENIED_CODE_PATTERNS", "Deno.env\n  /\\d{1,3}\\.\\d{1,3}/  \n\n")
+	denylist, err := deniedCodeFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if match, denied := denylist.Check(`fetch("http://10.0.0.1")`); !denied || match != "10.0" {
+		t.Errorf("Expected the pattern with a comma to stay whole, got %q (%v)", match, denied)
+	}
+	if _, denied := denylist.Check("console.log(Deno.env)"); !denied {
+		t.Error("Expected Deno.env to be denied")
+	}
+
+	t.Setenv("DENIED_CODE_PATTERNS", "/([/")
+	if _, err := deniedCodeFromEnv(); err == nil || !strings.Contains(err.Error(), "DENIED_CODE_PATTERNS") {
+		t.Errorf("Expected an error naming DENIED_CODE_PATTERNS, got %v", err)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// CommandRequest is a comma command as received
+type CommandRequest struct {
+	Sender   string
+	Channel  string    // where the command was sent, and where replies go
+	Args     []string  // the words after the command name
+	Received time.Time // when the message arrived, used to report latency
+}
+
+// CommandHandler is a comma command the bot answers without the model
+type CommandHandler struct {
+	Name  string // including the comma, e.g. ",ping"
+	Usage string // one-line description for ,help
+	Admin bool   // only verified admin accounts may run it
+	Run   func(ia *IRCAgent, req CommandRequest)
+}
+
+// CommandRegistry holds the comma commands by name
+type CommandRegistry struct {
+	handlers map[string]CommandHandler
+}
+
+// NewCommandRegistry creates an empty registry
+func NewCommandRegistry() *CommandRegistry {
+	return &CommandRegistry{
+		handlers: make(map[string]CommandHandler),
+	}
+}
+
+// Register adds a command. Names are case-insensitive and must start with a
+// comma and be unique.
+func (r *CommandRegistry) Register(h CommandHandler) error {
+	name := strings.ToLower(h.Name)
+	if !strings.HasPrefix(name, ",") || len(name) < 2 || strings.ContainsAny(name, " \t") {
+		return fmt.Errorf("invalid command name %q", h.Name)
+	}
+	if h.Run == nil {
+		return fmt.Errorf("command %s has no handler", h.Name)
+	}
+	if _, exists := r.handlers[name]; exists {
+		return fmt.Errorf("command %s is already registered", h.Name)
+	}
+	h.Name = name
+	r.handlers[name] = h
+	return nil
+}
+
+// Lookup returns the command called name
+func (r *CommandRegistry) Lookup(name string) (CommandHandler, bool) {
+	h, ok := r.handlers[strings.ToLower(name)]
+	return h, ok
+}
+
+// Names returns the registered command names, sorted
+func (r *CommandRegistry) Names() []string {
+	names := make([]string, 0, len(r.handlers))
+	for name := range r.handlers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Help describes one command, or lists them all when name is ""
+func (r *CommandRegistry) Help(name string) string {
+	if name == "" {
+		return "Available commands: " + strings.Join(r.Names(), ", ") + ". Use ,help <command> for details"
+	}
+	if !strings.HasPrefix(name, ",") {
+		name = "," + name
+	}
+	h, ok := r.Lookup(name)
+	if !ok {
+		return fmt.Sprintf("Unknown command: %s. %s", name, r.Help(""))
+	}
+	help := h.Name + ": " + h.Usage
+	if h.Admin {
+		help += " (admin only)"
+	}
+	return help
+}
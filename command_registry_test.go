@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommandRegistryRegister(t *testing.T) {
+	r := NewCommandRegistry()
+	run := func(*IRCAgent, CommandRequest) {}
+
+	if err := r.Register(CommandHandler{Name: ",Echo", Usage: "repeat words", Run: run}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if h, ok := r.Lookup(",ECHO"); !ok || h.Name != ",echo" {
+		t.Errorf("Expected a case-insensitive lookup, got %+v (found %v)", h, ok)
+	}
+
+	invalid := []CommandHandler{
+		{Name: ",echo", Run: run}, // duplicate
+		{Name: "echo", Run: run},
+		{Name: ",", Run: run},
+		{Name: ",two words", Run: run},
+		{Name: ",norun"},
+	}
+	for _, h := range invalid {
+		if err := r.Register(h); err == nil {
+			t.Errorf("Expected an error registering %q", h.Name)
+		}
+	}
+}
+
+func TestCommandRegistryHelp(t *testing.T) {
+	r := NewCommandRegistry()
+	run := func(*IRCAgent, CommandRequest) {}
+	r.Register(CommandHandler{Name: ",zap", Usage: "zap things", Admin: true, Run: run})
+	r.Register(CommandHandler{Name: ",echo", Usage: "repeat words", Run: run})
+
+	if help := r.Help(""); !strings.HasPrefix(help, "Available commands: ,echo, ,zap.") {
+		t.Errorf("Expected sorted commands, got %q", help)
+	}
+	if help := r.Help("zap"); help != ",zap: zap things (admin only)" {
+		t.Errorf("Expected the usage of ,zap, got %q", help)
+	}
+	if help := r.Help(",nope"); !strings.HasPrefix(help, "Unknown command: ,nope") {
+		t.Errorf("Expected an unknown command, got %q", help)
+	}
+}
+
+func TestBuiltinCommandsRegister(t *testing.T) {
+	r := builtinCommands()
+	for _, name := range []string{",die", ",ping", ",join", ",help", ",channels"} {
+		if _, ok := r.Lookup(name); !ok {
+			t.Errorf("Expected %s to be registered", name)
+		}
+	}
+}
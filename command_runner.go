@@ -0,0 +1,30 @@
+package main
+
+import (
+	"context"
+	"io"
+	"os/exec"
+	"time"
+)
+
+// CommandRunner starts the processes the TypeScript executor runs, so tests
+// can exercise the executor without Deno installed. A command that exits
+// non-zero returns an error with an ExitCode() int method, like *exec.ExitError.
+type CommandRunner interface {
+	Run(ctx context.Context, dir, name string, args []string, stdout, stderr io.Writer) error
+}
+
+// execRunner runs commands with os/exec, killing them when ctx is cancelled
+type execRunner struct {
+	waitDelay time.Duration // how long a killed process may hold its output pipes open
+}
+
+// Run runs name with args in dir and waits for it to exit
+func (r execRunner) Run(ctx context.Context, dir, name string, args []string, stdout, stderr io.Writer) error {
+	cmd := exec.CommandContext(ctx, name, args...)
+	cmd.Dir = dir
+	cmd.WaitDelay = r.waitDelay
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	return cmd.Run()
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+// builtinCommands returns the registry of the bot's comma commands
+func builtinCommands() *CommandRegistry {
+	r := NewCommandRegistry()
+	for _, h := range []CommandHandler{
+		{Name: ",die", Usage: "restart the bot", Admin: true, Run: (*IRCAgent).cmdDie},
+		{Name: ",ping", Usage: "check the bot is responding", Run: (*IRCAgent).cmdPing},
+		{Name: ",model", Usage: "show the model answering in this channel", Run: (*IRCAgent).cmdModel},
+		{Name: ",stats", Usage: "show uptime and usage", Run: (*IRCAgent).cmdStats},
+		{Name: ",expand", Usage: ",expand <short-url> shows where a short link points", Run: (*IRCAgent).cmdExpand},
+		{Name: ",join", Usage: joinUsage, Admin: true, Run: (*IRCAgent).cmdJoin},
+		{Name: ",forget", Usage: "forget everything remembered about you here", Run: (*IRCAgent).cmdForget},
+		{Name: ",channels", Usage: "list the channels the bot is in", Run: (*IRCAgent).cmdChannels},
+		{Name: ",users", Usage: "list the users in this channel", Run: (*IRCAgent).cmdUsers},
+		{Name: ",help", Usage: ",help [command] lists commands or describes one", Run: (*IRCAgent).cmdHelp},
+	} {
+		if err := r.Register(h); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// reply sends message to the channel a command came from, addressed to its sender
+func (ia *IRCAgent) reply(req CommandRequest, format string, args ...any) {
+	ia.outbound.Send(req.Channel, req.Sender+": "+fmt.Sprintf(format, args...))
+}
+
+func (ia *IRCAgent) cmdDie(req CommandRequest) {
+	log.Printf("Die command received from %s - triggering panic to restart process", req.Sender)
+	ia.reply(req, "Restarting agent...")
+	panic("message died")
+}
+
+func (ia *IRCAgent) cmdPing(req CommandRequest) {
+	// Answered without the model, so it measures IRC and bot responsiveness only
+	latency := time.Since(req.Received)
+	ia.reply(req, "pong (%s)", latency.Round(time.Microsecond))
+}
+
+func (ia *IRCAgent) cmdModel(req CommandRequest) {
+	name := ia.modelName
+	if override, ok := ia.overrides.lookup(ia.userID(req.Channel)); ok && override.Model != "" {
+		name = override.Model + " (channel override, default " + ia.modelName + ")"
+	}
+	ia.reply(req, "%s/%s", ia.provider, name)
+}
+
+func (ia *IRCAgent) cmdStats(req CommandRequest) {
+	ia.reply(req, "%s", ia.stats.Summary(time.Now()))
+}
+
+func (ia *IRCAgent) cmdExpand(req CommandRequest) {
+	if len(req.Args) != 1 {
+		ia.reply(req, "Usage: ,expand <short-url>")
+		return
+	}
+	if ia.urlShortener == nil {
+		ia.reply(req, "URL shortener is not available")
+		return
+	}
+	short := req.Args[0]
+	target, err := ia.urlShortener.Resolve(context.Background(), short)
+	if errors.Is(err, shortener.ErrNotFound) {
+		ia.reply(req, "Unknown short URL: %s", short)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to expand %s: %v", short, err)
+		ia.reply(req, "Failed to look up %s", short)
+		return
+	}
+	ia.sendToIRC(fmt.Sprintf("%s: %s -> %s", req.Sender, short, target), req.Channel)
+}
+
+func (ia *IRCAgent) cmdJoin(req CommandRequest) {
+	channel, key, err := parseJoinArgs(req.Args)
+	if err != nil {
+		ia.reply(req, "%v. %s", err, joinUsage)
+		return
+	}
+	if !ia.guard.Allowed(channel) {
+		ia.reply(req, "%s is not in ALLOWED_CHANNELS", channel)
+		return
+	}
+	// JOIN takes the key as a second parameter, so it's passed along with the name
+	if key != "" {
+		ia.ircConn.Join(channel + " " + key)
+	} else {
+		ia.ircConn.Join(channel)
+	}
+	log.Printf("Joining %s at the request of %s", channel, req.Sender)
+	ia.reply(req, "Joining %s", channel)
+}
+
+func (ia *IRCAgent) cmdForget(req CommandRequest) {
+	// Only ever the sender's own facts, in the channel they asked from
+	if ia.memory == nil {
+		ia.reply(req, "Memory is not enabled")
+		return
+	}
+	n, err := ia.memory.Forget(context.Background(), ia.userID(req.Channel), req.Sender)
+	if err != nil {
+		log.Printf("Failed to forget %s in %s: %v", req.Sender, req.Channel, err)
+		ia.reply(req, "Failed to clear what I remember about you")
+		return
+	}
+	log.Printf("Forgot %d facts about %s in %s", n, req.Sender, req.Channel)
+	ia.reply(req, "Forgot %d things I remembered about you", n)
+}
+
+func (ia *IRCAgent) cmdChannels(req CommandRequest) {
+	channels := ia.joined.List()
+	if len(channels) == 0 {
+		ia.reply(req, "Not in any channels")
+		return
+	}
+	ia.sendToIRC(fmt.Sprintf("%s: In %d channels: %s", req.Sender, len(channels), strings.Join(channels, ", ")), req.Channel)
+}
+
+func (ia *IRCAgent) cmdUsers(req CommandRequest) {
+	members := ia.members.Members(req.Channel)
+	if len(members) == 0 {
+		ia.reply(req, "No membership information for %s yet", req.Channel)
+		return
+	}
+	ia.sendToIRC(fmt.Sprintf("%s: %d users in %s: %s", req.Sender, len(members), req.Channel, strings.Join(members, ", ")), req.Channel)
+}
+
+func (ia *IRCAgent) cmdHelp(req CommandRequest) {
+	name := ""
+	if len(req.Args) > 0 {
+		name = req.Args[0]
+	}
+	ia.reply(req, "%s", ia.commands.Help(name))
+}
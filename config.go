@@ -0,0 +1,123 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/r33drichards/irc-agent/internal/envconf"
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+// Settings NewIRCAgents reads from the environment. validateConfig checks the
+// same definitions, so the two agree on what is valid.
+var (
+	channelContextSizeSetting  = envconf.Int{Name: "CHANNEL_CONTEXT_SIZE", Default: 10}
+	dedupWindowSetting         = envconf.Duration{Name: "DEDUP_WINDOW", Default: 30 * time.Second}
+	thinkingNoticeDelaySetting = envconf.Duration{Name: "THINKING_NOTICE_DELAY", Default: 3 * time.Second}
+	kickRejoinDelaySetting     = envconf.Duration{Name: "KICK_REJOIN_DELAY"} // zero leaves rejoining off
+	kickRejoinMaxSetting       = envconf.Int{Name: "KICK_REJOIN_MAX", Default: defaultKickRejoinMax, Min: 1}
+	breakerThresholdSetting    = envconf.Int{Name: "CIRCUIT_BREAKER_THRESHOLD", Default: defaultBreakerThreshold}
+	breakerCooldownSetting     = envconf.Duration{Name: "CIRCUIT_BREAKER_COOLDOWN", Default: defaultBreakerCooldown, Min: time.Nanosecond}
+	messageWorkersSetting      = envconf.Int{Name: "MESSAGE_WORKERS", Default: defaultMessageWorkers, Min: 1}
+	messageQueueSizeSetting    = envconf.Int{Name: "MESSAGE_QUEUE_SIZE", Default: defaultMessageQueueSize}
+	maxResponseLinesSetting    = envconf.Int{Name: "MAX_RESPONSE_LINES", Default: defaultMaxResponseLines}
+	maxToolCallsSetting        = envconf.Int{Name: "MAX_TOOL_CALLS", Default: 10}
+	maxScriptBytesSetting      = envconf.Int{Name: "MAX_SCRIPT_BYTES", Min: 1} // zero uses the executor's default
+	uploadResultsSetting       = envconf.Bool{Name: "UPLOAD_RESULTS", Default: true}
+	executionNoticesSetting    = envconf.Bool{Name: "EXECUTION_NOTICES", Default: true}
+	modelVisionSetting         = envconf.Bool{Name: "MODEL_VISION"}
+	memoryEnabledSetting       = envconf.Bool{Name: "MEMORY_ENABLED"}
+	memoryMaxFactsSetting      = envconf.Int{Name: "MEMORY_MAX_FACTS", Default: defaultMemoryFacts, Min: 1}
+)
+
+// envSettings are the settings validateConfig checks
+var envSettings = []envconf.Setting{
+	channelContextSizeSetting,
+	dedupWindowSetting,
+	thinkingNoticeDelaySetting,
+	kickRejoinDelaySetting,
+	kickRejoinMaxSetting,
+	breakerThresholdSetting,
+	breakerCooldownSetting,
+	messageWorkersSetting,
+	messageQueueSizeSetting,
+	maxResponseLinesSetting,
+	maxToolCallsSetting,
+	maxScriptBytesSetting,
+	uploadResultsSetting,
+	executionNoticesSetting,
+	modelVisionSetting,
+	memoryEnabledSetting,
+	memoryMaxFactsSetting,
+}
+
+// validateConfig checks the combined config file and environment before the
+// bot connects anywhere, and reports every problem it finds in one error
+// rather than stopping at the first
+func validateConfig() error {
+	var problems []error
+	check := func(err error) {
+		if err != nil {
+			problems = append(problems, err)
+		}
+	}
+
+	// Servers, channels and identities, from NETWORKS or SERVER and CHANNEL
+	if _, err := loadNetworks(); err != nil {
+		check(err)
+	}
+	if os.Getenv("ANTHROPIC_API_KEY") == "" {
+		check(fmt.Errorf("ANTHROPIC_API_KEY is required (model.api_key in the config file)"))
+	}
+
+	if _, err := newArtifactStoreFromEnv(""); err != nil {
+		check(err)
+	}
+	if bucket := os.Getenv("S3_BUCKET"); bucket != "" && !validS3Bucket(bucket) {
+		check(fmt.Errorf("S3_BUCKET %q is not a valid bucket name", bucket))
+	}
+
+	for _, setting := range envSettings {
+		check(setting.Check())
+	}
+	check(shortener.CheckEnv())
+
+	if _, err := parseReplyType(os.Getenv("REPLY_TYPE")); err != nil {
+		check(fmt.Errorf("REPLY_TYPE: %w", err))
+	}
+	if _, err := parseInviteJoinAll(os.Getenv("INVITE_AUTO_JOIN")); err != nil {
+		check(err)
+	}
+	if _, err := deniedCodeFromEnv(); err != nil {
+		check(err)
+	}
+	if _, err := loadInstructionTemplate(); err != nil {
+		check(err)
+	}
+	if _, err := loadChannelOverrides(); err != nil {
+		check(err)
+	}
+
+	return errors.Join(problems...)
+}
+
+// validS3Bucket reports whether name follows S3's bucket naming rules: 3-63
+// lowercase letters, digits, dots and hyphens, starting and ending with a
+// letter or digit
+func validS3Bucket(name string) bool {
+	if len(name) < 3 || len(name) > 63 {
+		return false
+	}
+	for i, r := range name {
+		alnum := r >= 'a' && r <= 'z' || r >= '0' && r <= '9'
+		if (i == 0 || i == len(name)-1) && !alnum {
+			return false
+		}
+		if !alnum && r != '.' && r != '-' {
+			return false
+		}
+	}
+	return true
+}
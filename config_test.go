@@ -0,0 +1,118 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/r33drichards/irc-agent/internal/configfile"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// setValidConfig sets a minimal complete configuration, clearing variables
+// that other tests or the environment may have set
+func setValidConfig(t *testing.T) {
+	t.Helper()
+	for _, name := range []string{
+		"NETWORKS", "NETWORKS_FILE", "IRC_NICK", "IRC_USER", "IRC_REALNAME",
+		"ARTIFACT_STORE", "S3_BUCKET", "S3_MAX_ATTEMPTS", "S3_FORCE_PATH_STYLE", "S3_RESULT_EXPIRES",
+		"CHANNEL_CONTEXT_SIZE", "MAX_TOOL_CALLS", "MAX_SCRIPT_BYTES", "MEMORY_MAX_FACTS", "REDIS_DB",
+		"DEDUP_WINDOW", "THINKING_NOTICE_DELAY", "UPLOAD_RESULTS", "MODEL_VISION", "MEMORY_ENABLED",
+		"SHORTENER_REDIRECT_STATUS", "REPLY_TYPE", "INVITE_AUTO_JOIN", "DENIED_CODE_PATTERNS",
+		"INSTRUCTION", "INSTRUCTION_FILE", "CHANNEL_OVERRIDES", "CHANNEL_OVERRIDES_FILE",
+	} {
+		t.Setenv(name, "")
+	}
+	t.Setenv("SERVER", "irc.example.com")
+	t.Setenv("CHANNEL", "#a")
+	t.Setenv("ANTHROPIC_API_KEY", "sk-test")
+}
+
+func TestValidateConfig(t *testing.T) {
+	setValidConfig(t)
+	if err := validateConfig(); err != nil {
+		t.Errorf("Expected a complete configuration to pass, got %v", err)
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	if err := validateConfig(); err == nil {
+		t.Error("Expected an error without an API key")
+	}
+
+	t.Setenv("ANTHROPIC_API_KEY", "sk-test")
+	t.Setenv("SERVER", "")
+	if err := validateConfig(); err == nil {
+		t.Error("Expected an error without a server")
+	}
+
+	// Networks replace SERVER and CHANNEL
+	t.Setenv("NETWORKS", `[{"server": "irc.example.com", "channels": ["#a"]}]`)
+	if err := validateConfig(); err != nil {
+		t.Errorf("Expected NETWORKS to stand in for SERVER, got %v", err)
+	}
+}
+
+func TestValidateConfigReportsEveryProblem(t *testing.T) {
+	setValidConfig(t)
+	t.Setenv("SERVER", "")
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("S3_BUCKET", "Not_A_Bucket")
+	t.Setenv("MAX_TOOL_CALLS", "lots")
+	t.Setenv("DEDUP_WINDOW", "soon")
+	t.Setenv("UPLOAD_RESULTS", "maybe")
+	t.Setenv("REPLY_TYPE", "smoke-signal")
+
+	err := validateConfig()
+	if err == nil {
+		t.Fatal("Expected an error")
+	}
+	for _, want := range []string{"SERVER", "ANTHROPIC_API_KEY", "S3_BUCKET", "MAX_TOOL_CALLS", "DEDUP_WINDOW", "UPLOAD_RESULTS", "REPLY_TYPE"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected the error to mention %s, got:\n%v", want, err)
+		}
+	}
+	if lines := strings.Count(err.Error(), "\n") + 1; lines != 7 {
+		t.Errorf("Expected 7 problems, one per line, got %d:\n%v", lines, err)
+	}
+}
+
+func TestValidS3Bucket(t *testing.T) {
+	for _, name := range []string{"results", "my.bucket-1", "abc"} {
+		if !validS3Bucket(name) {
+			t.Errorf("Expected %q to be valid", name)
+		}
+	}
+	for _, name := range []string{"ab", "Results", "under_score", "-leading", "trailing.", strings.Repeat("a", 64)} {
+		if validS3Bucket(name) {
+			t.Errorf("Expected %q to be invalid", name)
+		}
+	}
+}
+
+func TestConfigFileDeniedCodeKeepsCommas(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+executor:
+  denied_code: ["Deno.run", "/\\d{1,3}/"]
+`)
+	cfg, err := configfile.Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	t.Setenv("DENIED_CODE_PATTERNS", cfg.Env()["DENIED_CODE_PATTERNS"])
+	denylist, err := deniedCodeFromEnv()
+	if err != nil {
+		t.Fatalf("Expected the file's patterns to compile, got %v", err)
+	}
+	if _, denied := denylist.Check("const n = 123"); !denied {
+		t.Error("Expected the regular expression to be applied whole")
+	}
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"io"
+)
+
+// disconnectMarker starts the line go-ircevent's Loop logs each time it finds
+// the connection lost, before reconnecting
+var disconnectMarker = []byte("Error, disconnected:")
+
+// disconnectLog is the writer behind go-ircevent's logger. It passes output
+// through and calls onDisconnect when the library reports a lost connection;
+// the library has no disconnect callback, so its log line is the only notice
+// a dropped socket gets.
+type disconnectLog struct {
+	out          io.Writer
+	onDisconnect func()
+}
+
+func (w disconnectLog) Write(p []byte) (int, error) {
+	if bytes.Contains(p, disconnectMarker) {
+		w.onDisconnect()
+	}
+	return w.out.Write(p)
+}
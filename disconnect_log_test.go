@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bytes"
+	"log"
+	"testing"
+)
+
+func TestDisconnectLog(t *testing.T) {
+	var out bytes.Buffer
+	disconnects := 0
+	logger := log.New(disconnectLog{out: &out, onDisconnect: func() { disconnects++ }}, "", 0)
+
+	logger.Printf("Connected to irc.example.com:6697 (1.2.3.4:6697)")
+	if disconnects != 0 {
+		t.Errorf("Expected no disconnect for an ordinary line, got %d", disconnects)
+	}
+	logger.Printf("Error, disconnected: %s\n", "read tcp: i/o timeout")
+	if disconnects != 1 {
+		t.Errorf("Expected one disconnect, got %d", disconnects)
+	}
+	if got := out.String(); got != "Connected to irc.example.com:6697 (1.2.3.4:6697)\nError, disconnected: read tcp: i/o timeout\n" {
+		t.Errorf("Expected the output to pass through, got %q", got)
+	}
+}
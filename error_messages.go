@@ -0,0 +1,56 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+
+	anthropicmodel "github.com/r33drichards/irc-agent/model/anthropic"
+)
+
+// modelUnavailableMessage is sent to IRC when the language model rejects our credentials
+const modelUnavailableMessage = "I'm having trouble reaching my language model right now"
+
+// errorCategory maps a class of errors to a message that is safe to show in IRC
+type errorCategory struct {
+	name    string
+	target  error
+	message string
+}
+
+// errorCategories lists the known error classes, checked in order with errors.Is
+var errorCategories = []errorCategory{
+	{"auth", anthropicmodel.ErrAuthentication, modelUnavailableMessage},
+	{"rate_limit", anthropicmodel.ErrRateLimited, "I'm getting too many requests right now, please try again in a minute"},
+	{"overloaded", anthropicmodel.ErrOverloaded, "My language model is overloaded right now, please try again shortly"},
+	{"timeout", context.DeadlineExceeded, "That took too long, so I gave up on it"},
+	{"canceled", context.Canceled, "I stopped working on that request"},
+}
+
+// userFacingError maps an error from an agent run to a message that is safe to
+// show in IRC. The raw error is only ever logged server-side. Errors outside the
+// known categories get a correlation ID that appears in both the log and the reply.
+func userFacingError(err error) string {
+	for _, category := range errorCategories {
+		if errors.Is(err, category.target) {
+			log.Printf("Error processing message (%s): %v", category.name, err)
+			return category.message
+		}
+	}
+
+	ref := newCorrelationID()
+	log.Printf("Error processing message [ref %s]: %v", ref, err)
+	return fmt.Sprintf("Sorry, something went wrong (ref %s)", ref)
+}
+
+// newCorrelationID returns a short random ID used to match IRC replies with log lines
+func newCorrelationID() string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		return "unknown"
+	}
+	return hex.EncodeToString(b)
+}
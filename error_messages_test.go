@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	anthropicmodel "github.com/r33drichards/irc-agent/model/anthropic"
+)
+
+func TestUserFacingErrorAuthFailure(t *testing.T) {
+	// Mirror how the error arrives from the runner: wrapped by the adapter
+	err := fmt.Errorf("failed to call Anthropic API: %w: POST \"https://api.anthropic.com/v1/messages\": 401 Unauthorized {\"type\":\"authentication_error\",\"message\":\"invalid x-api-key\"}", anthropicmodel.ErrAuthentication)
+
+	msg := userFacingError(err)
+	if msg != modelUnavailableMessage {
+		t.Errorf("Expected sanitized message %q, got %q", modelUnavailableMessage, msg)
+	}
+	if strings.Contains(msg, "x-api-key") || strings.Contains(msg, "api.anthropic.com") {
+		t.Errorf("Sanitized message leaked provider details: %q", msg)
+	}
+}
+
+func TestUserFacingErrorKnownCategories(t *testing.T) {
+	tests := []struct {
+		err      error
+		expected string
+	}{
+		{fmt.Errorf("stream error: %w", anthropicmodel.ErrRateLimited), errorCategories[1].message},
+		{fmt.Errorf("stream error: %w", anthropicmodel.ErrOverloaded), errorCategories[2].message},
+		{fmt.Errorf("run failed: %w", context.DeadlineExceeded), errorCategories[3].message},
+	}
+
+	for _, tt := range tests {
+		if msg := userFacingError(tt.err); msg != tt.expected {
+			t.Errorf("Expected %q for %v, got %q", tt.expected, tt.err, msg)
+		}
+	}
+}
+
+func TestUserFacingErrorUnknownHidesDetails(t *testing.T) {
+	err := errors.New("open /home/agent/secrets.json: permission denied")
+
+	msg := userFacingError(err)
+	if strings.Contains(msg, "/home/agent") || strings.Contains(msg, "secrets.json") {
+		t.Errorf("Sanitized message leaked error details: %q", msg)
+	}
+	if !strings.Contains(msg, "ref ") {
+		t.Errorf("Expected a correlation ID in %q", msg)
+	}
+
+	// Each failure should get its own correlation ID
+	if msg2 := userFacingError(err); msg2 == msg {
+		t.Errorf("Expected distinct correlation IDs, got %q twice", msg)
+	}
+}
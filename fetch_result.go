@@ -0,0 +1,96 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"unicode/utf8"
+
+	"github.com/r33drichards/irc-agent/shortener"
+	"google.golang.org/adk/tool"
+)
+
+// fetchResultChunkLen is how much stored content fetch_result returns per call
+const fetchResultChunkLen = 2000
+
+// FetchResultParams defines the input parameters for the fetch_result tool
+type FetchResultParams struct {
+	ID     string `json:"id" jsonschema:"The short_url or short ID of an uploaded result, or its storage key"`
+	Offset int    `json:"offset,omitempty" jsonschema:"Byte offset to start reading from, for results longer than one call returns"`
+}
+
+// FetchResultResults defines the output of the fetch_result tool
+type FetchResultResults struct {
+	Status       string `json:"status"`
+	Content      string `json:"content,omitempty"`
+	TotalBytes   int    `json:"total_bytes,omitempty"`
+	NextOffset   int    `json:"next_offset,omitempty"` // set when more content follows
+	URL          string `json:"url,omitempty"`         // link to the full content
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// ResultFetcher reads previously uploaded results back for the model, so it
+// doesn't have to run code to download truncated output
+type ResultFetcher struct {
+	store     ArtifactReader
+	shortener *shortener.URLShortener
+}
+
+// NewResultFetcher creates a fetcher reading from store and resolving short IDs with shortener
+func NewResultFetcher(store ArtifactReader, shortener *shortener.URLShortener) *ResultFetcher {
+	return &ResultFetcher{store: store, shortener: shortener}
+}
+
+// Fetch returns a chunk of the stored content for params.ID
+func (f *ResultFetcher) Fetch(ctx tool.Context, params FetchResultParams) FetchResultResults {
+	if params.ID == "" {
+		return FetchResultResults{Status: "error", ErrorMessage: "id is required"}
+	}
+	if params.Offset < 0 {
+		return FetchResultResults{Status: "error", ErrorMessage: "offset must not be negative"}
+	}
+
+	// Short IDs resolve to the store's URL; anything else is treated as a key
+	ref := params.ID
+	shortURL := ""
+	if f.shortener != nil {
+		original, err := f.shortener.Resolve(toolContext(ctx), params.ID)
+		switch {
+		case err == nil:
+			ref = original
+			if shortURL, err = f.shortener.GetShortURL(original); err != nil {
+				log.Printf("Warning: Failed to shorten %s: %v", original, err)
+			}
+		case !errors.Is(err, shortener.ErrNotFound):
+			return FetchResultResults{Status: "error", ErrorMessage: fmt.Sprintf("Failed to resolve short ID: %v", err)}
+		}
+	}
+
+	content, err := f.store.Read(toolContext(ctx), ref)
+	if err != nil {
+		return FetchResultResults{Status: "error", ErrorMessage: fmt.Sprintf("Failed to read result: %v", err)}
+	}
+
+	if params.Offset > len(content) {
+		return FetchResultResults{Status: "error", ErrorMessage: fmt.Sprintf("offset %d is past the end of the result (%d bytes)", params.Offset, len(content))}
+	}
+
+	// Chunks start and end on character boundaries, so an offset in the
+	// middle of a character moves on to the next one
+	offset := params.Offset
+	for offset < len(content) && !utf8.RuneStart(content[offset]) {
+		offset++
+	}
+	chunk := truncateUTF8(content[offset:], fetchResultChunkLen)
+	end := offset + len(chunk)
+	results := FetchResultResults{
+		Status:     "success",
+		Content:    chunk,
+		TotalBytes: len(content),
+		URL:        shortURL,
+	}
+	if end < len(content) {
+		results.NextOffset = end
+	}
+	return results
+}
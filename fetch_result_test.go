@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+func TestFetchResultByShortID(t *testing.T) {
+	store := &FileArtifactStore{Dir: t.TempDir(), BaseURL: "http://short.test"}
+	shortener := shortener.NewURLShortener("http://short.test", shortener.NewInMemoryStorage())
+
+	content := strings.Repeat("a", fetchResultChunkLen) + "tail"
+	signedURL, err := store.Upload(context.Background(), content, "")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	shortURL, err := shortener.GetShortURL(signedURL)
+	if err != nil {
+		t.Fatalf("GetShortURL failed: %v", err)
+	}
+
+	fetcher := NewResultFetcher(store, shortener)
+	result := fetcher.Fetch(nil, FetchResultParams{ID: shortURL})
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+	if len(result.Content) != fetchResultChunkLen || result.TotalBytes != len(content) {
+		t.Errorf("Expected first chunk of %d bytes out of %d, got %d of %d", fetchResultChunkLen, len(content), len(result.Content), result.TotalBytes)
+	}
+	if result.NextOffset != fetchResultChunkLen {
+		t.Errorf("Expected next offset %d, got %d", fetchResultChunkLen, result.NextOffset)
+	}
+	if result.URL != shortURL {
+		t.Errorf("Expected full-content URL %q, got %q", shortURL, result.URL)
+	}
+
+	// The bare ID works too, and the offset picks up the rest
+	id := shortURL[strings.LastIndex(shortURL, "/")+1:]
+	result = fetcher.Fetch(nil, FetchResultParams{ID: id, Offset: result.NextOffset})
+	if result.Content != "tail" || result.NextOffset != 0 {
+		t.Errorf("Expected final chunk \"tail\", got %+v", result)
+	}
+}
+
+func TestFetchResultChunksOnCharacterBoundaries(t *testing.T) {
+	store := &FileArtifactStore{Dir: t.TempDir(), BaseURL: "http://short.test"}
+	// The chunk boundary falls inside the two-byte é
+	content := strings.Repeat("a", fetchResultChunkLen-1) + "é" + "tail"
+	signedURL, err := store.Upload(context.Background(), content, "")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	key := signedURL[strings.LastIndex(signedURL, "/")+1:]
+
+	fetcher := NewResultFetcher(store, nil)
+	result := fetcher.Fetch(nil, FetchResultParams{ID: key})
+	if result.Content != strings.Repeat("a", fetchResultChunkLen-1) || result.NextOffset != fetchResultChunkLen-1 {
+		t.Fatalf("Expected the chunk to stop before é, got %d bytes, next offset %d", len(result.Content), result.NextOffset)
+	}
+	result = fetcher.Fetch(nil, FetchResultParams{ID: key, Offset: result.NextOffset})
+	if result.Content != "étail" {
+		t.Errorf("Expected the rest to start with é, got %q", result.Content)
+	}
+
+	// An offset inside a character skips to the next one
+	result = fetcher.Fetch(nil, FetchResultParams{ID: key, Offset: fetchResultChunkLen})
+	if result.Content != "tail" {
+		t.Errorf("Expected the partial character to be skipped, got %q", result.Content)
+	}
+}
+
+func TestFetchResultByKey(t *testing.T) {
+	store := &FileArtifactStore{Dir: t.TempDir(), BaseURL: "http://short.test"}
+	signedURL, err := store.Upload(context.Background(), "hello", "")
+	if err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	key := signedURL[strings.LastIndex(signedURL, "/")+1:]
+
+	fetcher := NewResultFetcher(store, shortener.NewURLShortener("http://short.test", shortener.NewInMemoryStorage()))
+	result := fetcher.Fetch(nil, FetchResultParams{ID: key})
+	if result.Status != "success" || result.Content != "hello" {
+		t.Errorf("Expected to read by key, got %+v", result)
+	}
+}
+
+func TestFetchResultErrors(t *testing.T) {
+	store := &FileArtifactStore{Dir: t.TempDir()}
+	fetcher := NewResultFetcher(store, nil)
+
+	for _, params := range []FetchResultParams{
+		{},
+		{ID: "missing.txt"},
+		{ID: "..", Offset: 0},
+		{ID: "x", Offset: -1},
+	} {
+		if result := fetcher.Fetch(nil, params); result.Status != "error" {
+			t.Errorf("%+v: Expected error, got %+v", params, result)
+		}
+	}
+}
+
+func TestS3KeyFromRef(t *testing.T) {
+	tests := []struct {
+		ref  string
+		want string
+	}{
+		{"code-results/1-abc.txt", "code-results/1-abc.txt"},
+		{"https://robust-cicada.s3.us-west-2.amazonaws.com/code-results/1-abc.txt?X-Amz-Signature=x", "code-results/1-abc.txt"},
+		{"https://s3.us-west-2.amazonaws.com/robust-cicada/code-results/1-abc.txt", "code-results/1-abc.txt"},
+	}
+
+	for _, tt := range tests {
+		if got := s3KeyFromRef("robust-cicada", tt.ref); got != tt.want {
+			t.Errorf("%q: Expected %q, got %q", tt.ref, tt.want, got)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+
+	"google.golang.org/genai"
+)
+
+// loadGenerationConfig builds the default generation settings from
+// MODEL_TEMPERATURE and MODEL_TOP_P. Unset values are left nil so the
+// provider's defaults apply. Anthropic accepts 0-1 for both.
+func loadGenerationConfig() (*genai.GenerateContentConfig, error) {
+	config := &genai.GenerateContentConfig{}
+
+	temperature, err := parseUnitFloat("MODEL_TEMPERATURE")
+	if err != nil {
+		return nil, err
+	}
+	config.Temperature = temperature
+
+	topP, err := parseUnitFloat("MODEL_TOP_P")
+	if err != nil {
+		return nil, err
+	}
+	config.TopP = topP
+
+	return config, nil
+}
+
+// parseUnitFloat reads an optional float in [0, 1] from the named env var
+func parseUnitFloat(name string) (*float32, error) {
+	raw := os.Getenv(name)
+	if raw == "" {
+		return nil, nil
+	}
+
+	v, err := strconv.ParseFloat(raw, 32)
+	if err != nil {
+		return nil, fmt.Errorf("%s must be a number, got %q", name, raw)
+	}
+	if v < 0 || v > 1 {
+		return nil, fmt.Errorf("%s must be between 0 and 1, got %v", name, v)
+	}
+
+	f := float32(v)
+	return &f, nil
+}
@@ -0,0 +1,41 @@
+package main
+
+import "testing"
+
+func TestLoadGenerationConfig(t *testing.T) {
+	t.Setenv("MODEL_TEMPERATURE", "0.3")
+	t.Setenv("MODEL_TOP_P", "0.95")
+
+	config, err := loadGenerationConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Temperature == nil || *config.Temperature != 0.3 {
+		t.Errorf("Expected temperature 0.3, got %v", config.Temperature)
+	}
+	if config.TopP == nil || *config.TopP != 0.95 {
+		t.Errorf("Expected top_p 0.95, got %v", config.TopP)
+	}
+}
+
+func TestLoadGenerationConfigDefaults(t *testing.T) {
+	t.Setenv("MODEL_TEMPERATURE", "")
+	t.Setenv("MODEL_TOP_P", "")
+
+	config, err := loadGenerationConfig()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if config.Temperature != nil || config.TopP != nil {
+		t.Errorf("Expected provider defaults (nil), got temperature %v top_p %v", config.Temperature, config.TopP)
+	}
+}
+
+func TestLoadGenerationConfigRejectsOutOfRange(t *testing.T) {
+	for _, raw := range []string{"1.5", "-0.1", "warm"} {
+		t.Setenv("MODEL_TEMPERATURE", raw)
+		if _, err := loadGenerationConfig(); err == nil {
+			t.Errorf("Expected error for MODEL_TEMPERATURE=%q", raw)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/r33drichards/irc-agent/shortener"
+	"google.golang.org/adk/tool"
+)
+
+// HTTPFetchParams defines the input parameters for the http_fetch tool
+type HTTPFetchParams struct {
+	URL     string            `json:"url" jsonschema:"The http or https URL to fetch. Only allowlisted hosts can be fetched"`
+	Method  string            `json:"method,omitempty" jsonschema:"GET (default) or POST"`
+	Headers map[string]string `json:"headers,omitempty" jsonschema:"Optional request headers"`
+	Body    string            `json:"body,omitempty" jsonschema:"Optional request body, for POST"`
+}
+
+// HTTPFetchResults defines the output of the http_fetch tool
+type HTTPFetchResults struct {
+	Status       string `json:"status"`
+	StatusCode   int    `json:"status_code,omitempty"`
+	ContentType  string `json:"content_type,omitempty"`
+	Body         string `json:"body,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+	ShortURL     string `json:"short_url,omitempty"`
+}
+
+// Limits for http_fetch
+const (
+	httpFetchTimeout      = 10 * time.Second
+	httpFetchMaxBody      = 1 << 20 // bytes read from the response
+	httpFetchMaxInline    = 500     // bytes of the body returned to the model
+	httpFetchMaxRedirects = 5
+)
+
+// HTTPFetcher performs HTTP requests for the model against an allowlist of hosts
+type HTTPFetcher struct {
+	client       *http.Client
+	allowedHosts []string      // exact hosts, or "*.example.com" for subdomains
+	store        ArtifactStore // where full bodies are uploaded; nil disables uploads
+	shortener    *shortener.URLShortener
+}
+
+// NewHTTPFetcher creates a fetcher that may only contact allowedHosts
+func NewHTTPFetcher(allowedHosts []string, store ArtifactStore, shortener *shortener.URLShortener) *HTTPFetcher {
+	f := &HTTPFetcher{
+		allowedHosts: allowedHosts,
+		store:        store,
+		shortener:    shortener,
+	}
+	f.client = &http.Client{
+		Timeout: httpFetchTimeout,
+		// Redirects must stay on allowlisted hosts too
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			if len(via) >= httpFetchMaxRedirects {
+				return fmt.Errorf("stopped after %d redirects", httpFetchMaxRedirects)
+			}
+			if !f.allowed(req.URL) {
+				return fmt.Errorf("redirect to %s is not allowed", req.URL.Host)
+			}
+			return nil
+		},
+	}
+	return f
+}
+
+// allowed reports whether u is an http(s) URL on an allowlisted host
+func (f *HTTPFetcher) allowed(u *url.URL) bool {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, pattern := range f.allowedHosts {
+		pattern = strings.ToLower(pattern)
+		if suffix, ok := strings.CutPrefix(pattern, "*."); ok {
+			if strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+		} else if host == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// toolContext returns ctx as a context.Context, or a background context
+// when the tool is called without one, as in tests
+func toolContext(ctx tool.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
+
+// Fetch performs the request and returns the status and a truncated body
+func (f *HTTPFetcher) Fetch(ctx tool.Context, params HTTPFetchParams) HTTPFetchResults {
+	u, err := url.Parse(params.URL)
+	if err != nil {
+		return HTTPFetchResults{Status: "error", ErrorMessage: fmt.Sprintf("Invalid URL: %v", err)}
+	}
+	if !f.allowed(u) {
+		return HTTPFetchResults{Status: "error", ErrorMessage: fmt.Sprintf("Host %q is not on the allowlist", u.Hostname())}
+	}
+
+	method := strings.ToUpper(params.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	if method != http.MethodGet && method != http.MethodPost {
+		return HTTPFetchResults{Status: "error", ErrorMessage: "Method must be GET or POST"}
+	}
+
+	var body io.Reader
+	if params.Body != "" {
+		body = strings.NewReader(params.Body)
+	}
+	req, err := http.NewRequestWithContext(toolContext(ctx), method, u.String(), body)
+	if err != nil {
+		return HTTPFetchResults{Status: "error", ErrorMessage: fmt.Sprintf("Failed to build request: %v", err)}
+	}
+	for name, value := range params.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return HTTPFetchResults{Status: "error", ErrorMessage: fmt.Sprintf("Request failed: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, httpFetchMaxBody))
+	if err != nil {
+		return HTTPFetchResults{Status: "error", StatusCode: resp.StatusCode, ErrorMessage: fmt.Sprintf("Failed to read response: %v", err)}
+	}
+
+	results := HTTPFetchResults{
+		Status:      "success",
+		StatusCode:  resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        string(data),
+	}
+
+	// Upload the full body and hand the model a truncated copy
+	if len(data) > httpFetchMaxInline {
+		if f.store != nil {
+			signedURL, err := f.store.Upload(toolContext(ctx), string(data), results.ContentType)
+			if err != nil {
+				log.Printf("Warning: Failed to upload fetched body: %v", err)
+			} else if f.shortener == nil {
+				results.ShortURL = signedURL
+			} else if results.ShortURL, err = f.shortener.GetShortURL(signedURL); err != nil {
+				// The signed URL still reaches the body, just less compactly
+				log.Printf("Warning: Failed to shorten fetched body URL: %v", err)
+				results.ShortURL = signedURL
+			}
+		}
+		inline := truncateUTF8(string(data), httpFetchMaxInline)
+		results.Body = inline + fmt.Sprintf("\n... (body truncated, %d more bytes)", len(data)-len(inline))
+	}
+
+	return results
+}
+
+// truncateUTF8 returns the longest prefix of s of at most n bytes that
+// doesn't cut a multi-byte UTF-8 character in half
+func truncateUTF8(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
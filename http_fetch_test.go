@@ -0,0 +1,127 @@
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// fakeArtifactStore records uploads instead of storing them anywhere
+type fakeArtifactStore struct {
+	uploads      []string
+	contentTypes []string
+}
+
+func (s *fakeArtifactStore) Upload(ctx context.Context, content, contentType string) (string, error) {
+	s.uploads = append(s.uploads, content)
+	s.contentTypes = append(s.contentTypes, contentType)
+	return "https://artifacts.example.com/upload", nil
+}
+
+func TestHTTPFetchAllowedHost(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "text/plain")
+		w.Write([]byte(r.Method + " " + r.Header.Get("X-Test") + " " + string(body)))
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher([]string{"127.0.0.1"}, nil, nil)
+	result := fetcher.Fetch(nil, HTTPFetchParams{
+		URL:     server.URL,
+		Method:  "post",
+		Headers: map[string]string{"X-Test": "yes"},
+		Body:    "hello",
+	})
+
+	if result.Status != "success" || result.StatusCode != http.StatusOK {
+		t.Fatalf("Expected success with 200, got %+v", result)
+	}
+	if result.Body != "POST yes hello" {
+		t.Errorf("Expected echoed request, got %q", result.Body)
+	}
+}
+
+func TestHTTPFetchRejectsHosts(t *testing.T) {
+	fetcher := NewHTTPFetcher([]string{"example.com", "*.githubusercontent.com"}, nil, nil)
+
+	tests := []struct {
+		url     string
+		allowed bool
+	}{
+		{"https://example.com/a", true},
+		{"https://raw.githubusercontent.com/x", true},
+		{"https://githubusercontent.com/x", false},
+		{"https://evil-example.com/", false},
+		{"file:///etc/passwd", false},
+		{"http://169.254.169.254/latest/meta-data", false},
+	}
+
+	for _, tt := range tests {
+		result := fetcher.Fetch(nil, HTTPFetchParams{URL: tt.url, Method: "DELETE"})
+		// An allowed host still fails on the method, with a different message
+		rejected := strings.Contains(result.ErrorMessage, "allowlist")
+		if rejected == tt.allowed {
+			t.Errorf("%s: expected allowed=%v, got %q", tt.url, tt.allowed, result.ErrorMessage)
+		}
+	}
+}
+
+func TestHTTPFetchRejectsRedirectOffAllowlist(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "http://169.254.169.254/", http.StatusFound)
+	}))
+	defer server.Close()
+
+	fetcher := NewHTTPFetcher([]string{"127.0.0.1"}, nil, nil)
+	result := fetcher.Fetch(nil, HTTPFetchParams{URL: server.URL})
+	if result.Status != "error" || !strings.Contains(result.ErrorMessage, "not allowed") {
+		t.Errorf("Expected redirect off the allowlist to fail, got %+v", result)
+	}
+}
+
+func TestHTTPFetchUploadsLargeBody(t *testing.T) {
+	large := strings.Repeat("x", httpFetchMaxInline+100)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(large))
+	}))
+	defer server.Close()
+
+	store := &fakeArtifactStore{}
+	fetcher := NewHTTPFetcher([]string{"127.0.0.1"}, store, nil)
+	result := fetcher.Fetch(nil, HTTPFetchParams{URL: server.URL})
+
+	if len(store.uploads) != 1 || store.uploads[0] != large {
+		t.Fatalf("Expected the full body to be uploaded once, got %d uploads", len(store.uploads))
+	}
+	if result.ShortURL != "https://artifacts.example.com/upload" {
+		t.Errorf("Expected upload URL in results, got %q", result.ShortURL)
+	}
+	if !strings.HasPrefix(result.Body, large[:httpFetchMaxInline]) || !strings.Contains(result.Body, "100 more bytes") {
+		t.Errorf("Expected truncated body, got %d bytes", len(result.Body))
+	}
+}
+
+func TestTruncateUTF8(t *testing.T) {
+	tests := []struct {
+		s    string
+		n    int
+		want string
+	}{
+		{"hello", 10, "hello"},
+		{"hello", 3, "hel"},
+		{"héllo", 2, "h"}, // é is two bytes
+		{"héllo", 3, "hé"},
+		{"日本", 4, "日"},
+		{"日本", 2, ""},
+		{"", 0, ""},
+	}
+	for _, tt := range tests {
+		if got := truncateUTF8(tt.s, tt.n); got != tt.want {
+			t.Errorf("truncateUTF8(%q, %d): Expected %q, got %q", tt.s, tt.n, tt.want, got)
+		}
+	}
+}
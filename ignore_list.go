@@ -0,0 +1,71 @@
+package main
+
+import (
+	"strings"
+)
+
+// IgnoreList holds nicks and hostmasks whose messages the bot never responds to
+type IgnoreList struct {
+	nicks     map[string]struct{} // lowercased nicks
+	hostmasks []string            // lowercased nick!user@host glob patterns
+}
+
+// NewIgnoreList creates an ignore list from nicks and hostmask patterns such as
+// "*!*@spam.example". Matching is case-insensitive.
+func NewIgnoreList(nicks, hostmasks []string) *IgnoreList {
+	l := &IgnoreList{nicks: make(map[string]struct{})}
+	for _, nick := range nicks {
+		l.nicks[strings.ToLower(nick)] = struct{}{}
+	}
+	for _, mask := range hostmasks {
+		l.hostmasks = append(l.hostmasks, strings.ToLower(mask))
+	}
+	return l
+}
+
+// Ignored reports whether a message from nick!user@host should be ignored
+func (l *IgnoreList) Ignored(nick, user, host string) bool {
+	if _, ok := l.nicks[strings.ToLower(nick)]; ok {
+		return true
+	}
+
+	mask := strings.ToLower(nick + "!" + user + "@" + host)
+	for _, pattern := range l.hostmasks {
+		if matchHostmask(pattern, mask) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchHostmask reports whether mask matches an IRC glob pattern, where '*'
+// matches any run of characters and '?' matches exactly one. Unlike path.Match,
+// characters such as '[' and '/' are literal, since they are valid in nicks and hosts.
+func matchHostmask(pattern, mask string) bool {
+	p, m := 0, 0
+	starP, starM := -1, 0
+
+	for m < len(mask) {
+		switch {
+		case p < len(pattern) && (pattern[p] == '?' || pattern[p] == mask[m]):
+			p++
+			m++
+		case p < len(pattern) && pattern[p] == '*':
+			// Remember the star and first try matching it against nothing
+			starP, starM = p, m
+			p++
+		case starP >= 0:
+			// Backtrack: let the last star swallow one more character
+			starM++
+			p, m = starP+1, starM
+		default:
+			return false
+		}
+	}
+
+	// Any trailing stars match the empty remainder
+	for p < len(pattern) && pattern[p] == '*' {
+		p++
+	}
+	return p == len(pattern)
+}
@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestMatchHostmask(t *testing.T) {
+	tests := []struct {
+		pattern string
+		mask    string
+		match   bool
+	}{
+		{"*!*@spam.example", "troll!~t@spam.example", true},
+		{"*!*@spam.example", "alice!~a@good.example", false},
+		{"*!*@*.spam.example", "troll!t@host1.spam.example", true},
+		{"bot?!*@*", "bot1!b@host", true},
+		{"bot?!*@*", "bot12!b@host", false},
+		{"[bot]*!*@*", "[bot]helper!h@host", true},
+		{"*", "anyone!a@anywhere", true},
+		{"*!*@host", "a!b@host.evil", false},
+		{"a*b*c", "aXXbYYc", true},
+		{"a*b*c", "aXXbYY", false},
+	}
+
+	for _, tt := range tests {
+		if got := matchHostmask(tt.pattern, tt.mask); got != tt.match {
+			t.Errorf("matchHostmask(%q, %q) = %v, expected %v", tt.pattern, tt.mask, got, tt.match)
+		}
+	}
+}
+
+func TestIgnoreList(t *testing.T) {
+	list := NewIgnoreList([]string{"OtherBot"}, []string{"*!*@Spam.Example"})
+
+	if !list.Ignored("otherbot", "ob", "bots.example") {
+		t.Errorf("Expected nick match to be case-insensitive")
+	}
+	if !list.Ignored("troll", "~t", "spam.example") {
+		t.Errorf("Expected hostmask match to be case-insensitive")
+	}
+	if list.Ignored("alice", "~a", "good.example") {
+		t.Errorf("Expected alice not to be ignored")
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"path"
+	"strings"
+
+	"google.golang.org/genai"
+)
+
+// imageURLParts returns a file part for each http(s) image link in message,
+// recognized by its file extension, so a vision model can look at them
+func imageURLParts(message string) []*genai.Part {
+	var parts []*genai.Part
+	seen := make(map[string]bool)
+
+	for _, word := range strings.Fields(message) {
+		// Links are often wrapped in brackets or end a sentence
+		word = strings.Trim(word, "<>()[],")
+		if !strings.HasPrefix(word, "https://") && !strings.HasPrefix(word, "http://") {
+			continue
+		}
+
+		// Ignore any query string when looking at the extension
+		urlPath := word
+		if i := strings.IndexAny(urlPath, "?#"); i >= 0 {
+			urlPath = urlPath[:i]
+		}
+
+		mimeType := imageMIMEType(path.Ext(urlPath))
+		if mimeType == "" || seen[word] {
+			continue
+		}
+		seen[word] = true
+		parts = append(parts, genai.NewPartFromURI(word, mimeType))
+	}
+
+	return parts
+}
+
+// imageMIMETypes maps the image extensions Anthropic accepts to MIME types
+var imageMIMETypes = map[string]string{
+	".jpg":  "image/jpeg",
+	".jpeg": "image/jpeg",
+	".png":  "image/png",
+	".gif":  "image/gif",
+	".webp": "image/webp",
+}
+
+// imageMIMEType returns the MIME type for an accepted image extension, or ""
+func imageMIMEType(ext string) string {
+	return imageMIMETypes[strings.ToLower(ext)]
+}
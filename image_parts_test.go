@@ -0,0 +1,17 @@
+package main
+
+import "testing"
+
+func TestImageURLParts(t *testing.T) {
+	parts := imageURLParts("look at https://example.com/a.PNG and (https://example.com/b.jpg?size=large), not https://example.com/page.html or ftp://x/c.gif https://example.com/a.PNG")
+
+	if len(parts) != 2 {
+		t.Fatalf("Expected 2 image parts, got %d", len(parts))
+	}
+	if parts[0].FileData.FileURI != "https://example.com/a.PNG" || parts[0].FileData.MIMEType != "image/png" {
+		t.Errorf("Unexpected first part: %+v", parts[0].FileData)
+	}
+	if parts[1].FileData.FileURI != "https://example.com/b.jpg?size=large" || parts[1].FileData.MIMEType != "image/jpeg" {
+		t.Errorf("Unexpected second part: %+v", parts[1].FileData)
+	}
+}
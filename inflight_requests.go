@@ -0,0 +1,60 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+// alreadyWorkingMessage is sent when a message repeats one still being answered
+const alreadyWorkingMessage = "already working on that"
+
+// inflightKey identifies a request: who asked what, where
+type inflightKey struct {
+	channel string // lowercased
+	nick    string // lowercased
+	message string // normalized with normalizeRequest
+}
+
+// InflightRequests tracks messages being processed so a repeat of one, like a
+// double-pressed enter, isn't answered twice while the first is still running
+type InflightRequests struct {
+	mu      sync.Mutex
+	pending map[inflightKey]bool
+}
+
+// NewInflightRequests creates an empty tracker
+func NewInflightRequests() *InflightRequests {
+	return &InflightRequests{pending: make(map[inflightKey]bool)}
+}
+
+// normalizeRequest folds case and whitespace so trivially different repeats match
+func normalizeRequest(message string) string {
+	return strings.Join(strings.Fields(strings.ToLower(message)), " ")
+}
+
+// Start records that nick's message in channel is being processed. It
+// returns false if the same request is already in flight; otherwise the
+// returned function must be called once processing finishes.
+func (r *InflightRequests) Start(channel, nick, message string) (done func(), ok bool) {
+	key := inflightKey{
+		channel: strings.ToLower(channel),
+		nick:    strings.ToLower(nick),
+		message: normalizeRequest(message),
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.pending[key] {
+		return nil, false
+	}
+	r.pending[key] = true
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			defer r.mu.Unlock()
+			delete(r.pending, key)
+		})
+	}, true
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestInflightRequestsSkipsRepeats(t *testing.T) {
+	r := NewInflightRequests()
+
+	done, ok := r.Start("#agent", "alice", "agent: what is  Go?")
+	if !ok {
+		t.Fatal("Expected the first request to start")
+	}
+	if _, ok := r.Start("#Agent", "Alice", "agent: what is go?"); ok {
+		t.Error("Expected a repeat differing only in case and spacing to be skipped")
+	}
+	// Other users, channels and questions aren't affected
+	if _, ok := r.Start("#agent", "bob", "agent: what is go?"); !ok {
+		t.Error("Expected another user's request to start")
+	}
+	if _, ok := r.Start("#go", "alice", "agent: what is go?"); !ok {
+		t.Error("Expected the request in another channel to start")
+	}
+	if _, ok := r.Start("#agent", "alice", "agent: what is rust?"); !ok {
+		t.Error("Expected a different question to start")
+	}
+
+	// Once the first finishes the question can be asked again
+	done()
+	done()
+	if _, ok := r.Start("#agent", "alice", "agent: what is go?"); !ok {
+		t.Error("Expected the request to start again after the first finished")
+	}
+}
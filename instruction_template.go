@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// InstructionData holds the values a custom system instruction can use, e.g.
+// "You are the helper bot for {{.Channel}}"
+type InstructionData struct {
+	Channel       string // channel the conversation is in
+	Channels      string // all configured channels, comma-separated
+	Nick          string // the bot's nick
+	ShortenerPort string // port of the local URL shortener
+}
+
+// loadInstructionTemplate reads a custom system instruction template from the
+// file in INSTRUCTION_FILE or inline from INSTRUCTION. Returns nil when neither
+// is configured, meaning the built-in instruction is used.
+func loadInstructionTemplate() (*template.Template, error) {
+	var text string
+	if path := os.Getenv("INSTRUCTION_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read INSTRUCTION_FILE: %w", err)
+		}
+		text = string(b)
+	} else if inline := os.Getenv("INSTRUCTION"); inline != "" {
+		text = inline
+	} else {
+		return nil, nil
+	}
+
+	if strings.TrimSpace(text) == "" {
+		return nil, fmt.Errorf("instruction template is empty")
+	}
+	tmpl, err := template.New("instruction").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse instruction template: %w", err)
+	}
+
+	// Render once so references to unknown fields fail at startup, not mid-conversation
+	if _, err := renderInstruction(tmpl, InstructionData{}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+// renderInstruction executes the instruction template for data
+func renderInstruction(tmpl *template.Template, data InstructionData) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", fmt.Errorf("failed to render instruction template: %w", err)
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestRenderInstructionTemplate(t *testing.T) {
+	t.Setenv("INSTRUCTION_FILE", "")
+	t.Setenv("INSTRUCTION", "You are {{.Nick}}, the helper for {{.Channel}} (also in {{.Channels}}).")
+
+	tmpl, err := loadInstructionTemplate()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	got, err := renderInstruction(tmpl, InstructionData{Channel: "#go", Channels: "#go, #rust", Nick: "helperbot"})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	expected := "You are helperbot, the helper for #go (also in #go, #rust)."
+	if got != expected {
+		t.Errorf("Expected %q, got %q", expected, got)
+	}
+}
+
+func TestLoadInstructionTemplateFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "instruction.txt")
+	if err := os.WriteFile(path, []byte("Channel: {{.Channel}}"), 0644); err != nil {
+		t.Fatalf("Failed to write instruction file: %v", err)
+	}
+	t.Setenv("INSTRUCTION_FILE", path)
+	t.Setenv("INSTRUCTION", "ignored when the file is set")
+
+	tmpl, err := loadInstructionTemplate()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got, _ := renderInstruction(tmpl, InstructionData{Channel: "#agent"}); got != "Channel: #agent" {
+		t.Errorf("Expected the file's template, got %q", got)
+	}
+}
+
+func TestLoadInstructionTemplateUnset(t *testing.T) {
+	t.Setenv("INSTRUCTION_FILE", "")
+	t.Setenv("INSTRUCTION", "")
+
+	tmpl, err := loadInstructionTemplate()
+	if err != nil || tmpl != nil {
+		t.Errorf("Expected no template, got %v (%v)", tmpl, err)
+	}
+}
+
+func TestLoadInstructionTemplateErrors(t *testing.T) {
+	t.Setenv("INSTRUCTION_FILE", "")
+	for _, text := range []string{"Hello {{.Channel", "Hello {{.Topic}}", "   "} {
+		t.Setenv("INSTRUCTION", text)
+		if _, err := loadInstructionTemplate(); err == nil {
+			t.Errorf("%q: Expected an error", text)
+		}
+	}
+
+	t.Setenv("INSTRUCTION_FILE", filepath.Join(t.TempDir(), "missing.txt"))
+	if _, err := loadInstructionTemplate(); err == nil {
+		t.Error("Expected an error for a missing INSTRUCTION_FILE")
+	}
+}
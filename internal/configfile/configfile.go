@@ -0,0 +1,244 @@
+// Package configfile loads the configuration file named by CONFIG_FILE and
+// exports its settings as environment variables, so the bot and the
+// standalone shortener read one file the same way.
+package configfile
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Config is the configuration file named by CONFIG_FILE, in YAML or JSON. Each
+// setting stands in for an environment variable, named in its comment, and
+// variables that are set take precedence over the file.
+type Config struct {
+	IRC       IRCConfig       `yaml:"irc" json:"irc"`
+	Model     ModelConfig     `yaml:"model" json:"model"`
+	S3        S3Config        `yaml:"s3" json:"s3"`
+	Executor  ExecutorConfig  `yaml:"executor" json:"executor"`
+	Shortener ShortenerConfig `yaml:"shortener" json:"shortener"`
+}
+
+// IRCConfig configures the IRC connection
+type IRCConfig struct {
+	Server           string   `yaml:"server" json:"server"`                         // SERVER
+	TLS              bool     `yaml:"tls" json:"tls"`                               // connect with TLS, as an ircs:// SERVER does
+	Channels         []string `yaml:"channels" json:"channels"`                     // CHANNEL
+	Nick             string   `yaml:"nick" json:"nick"`                             // IRC_NICK
+	User             string   `yaml:"user" json:"user"`                             // IRC_USER
+	RealName         string   `yaml:"realname" json:"realname"`                     // IRC_REALNAME
+	Password         string   `yaml:"password" json:"password"`                     // PASS, the NickServ password
+	SASLLogin        string   `yaml:"sasl_login" json:"sasl_login"`                 // SASL_LOGIN
+	SASLPassword     string   `yaml:"sasl_password" json:"sasl_password"`           // SASL_PASSWORD
+	AllowedChannels  []string `yaml:"allowed_channels" json:"allowed_channels"`     // ALLOWED_CHANNELS
+	AdminAccounts    []string `yaml:"admin_accounts" json:"admin_accounts"`         // ADMIN_ACCOUNTS
+	ReplyType        string   `yaml:"reply_type" json:"reply_type"`                 // REPLY_TYPE
+	KickRejoinDelay  string   `yaml:"kick_rejoin_delay" json:"kick_rejoin_delay"`   // KICK_REJOIN_DELAY
+	KickRejoinMax    int      `yaml:"kick_rejoin_max" json:"kick_rejoin_max"`       // KICK_REJOIN_MAX
+	MaxResponseLines *int     `yaml:"max_response_lines" json:"max_response_lines"` // MAX_RESPONSE_LINES
+	MessageWorkers   int      `yaml:"message_workers" json:"message_workers"`       // MESSAGE_WORKERS
+	MessageQueueSize *int     `yaml:"message_queue_size" json:"message_queue_size"` // MESSAGE_QUEUE_SIZE
+}
+
+// ModelConfig configures the model provider
+type ModelConfig struct {
+	APIKey                  string `yaml:"api_key" json:"api_key"`                                     // ANTHROPIC_API_KEY
+	Vision                  *bool  `yaml:"vision" json:"vision"`                                       // MODEL_VISION
+	CircuitBreakerThreshold *int   `yaml:"circuit_breaker_threshold" json:"circuit_breaker_threshold"` // CIRCUIT_BREAKER_THRESHOLD
+	CircuitBreakerCooldown  string `yaml:"circuit_breaker_cooldown" json:"circuit_breaker_cooldown"`   // CIRCUIT_BREAKER_COOLDOWN
+}
+
+// S3Config configures the S3 artifact store
+type S3Config struct {
+	Bucket         string `yaml:"bucket" json:"bucket"`                     // S3_BUCKET
+	Region         string `yaml:"region" json:"region"`                     // S3_REGION
+	Endpoint       string `yaml:"endpoint" json:"endpoint"`                 // S3_ENDPOINT
+	ForcePathStyle *bool  `yaml:"force_path_style" json:"force_path_style"` // S3_FORCE_PATH_STYLE
+	MaxAttempts    int    `yaml:"max_attempts" json:"max_attempts"`         // S3_MAX_ATTEMPTS
+	Profile        string `yaml:"profile" json:"profile"`                   // AWS_PROFILE
+	AccessKey      string `yaml:"access_key" json:"access_key"`             // S3_ACCESS_KEY
+	SecretKey      string `yaml:"secret_key" json:"secret_key"`             // S3_SECRET_KEY
+}
+
+// ExecutorConfig configures TypeScript execution
+type ExecutorConfig struct {
+	DenoPath       string   `yaml:"deno_path" json:"deno_path"`               // DENO_PATH
+	WorkspaceDir   string   `yaml:"workspace_dir" json:"workspace_dir"`       // WORKSPACE_DIR
+	MaxScriptBytes int      `yaml:"max_script_bytes" json:"max_script_bytes"` // MAX_SCRIPT_BYTES
+	MaxToolCalls   int      `yaml:"max_tool_calls" json:"max_tool_calls"`     // MAX_TOOL_CALLS
+	UploadResults  *bool    `yaml:"upload_results" json:"upload_results"`     // UPLOAD_RESULTS
+	DeniedCode     []string `yaml:"denied_code" json:"denied_code"`           // DENIED_CODE_PATTERNS
+	Notices        *bool    `yaml:"notices" json:"notices"`                   // EXECUTION_NOTICES
+}
+
+// ShortenerConfig configures the URL shortener and its storage
+type ShortenerConfig struct {
+	Host                string   `yaml:"host" json:"host"`                                   // SHORTENER_HOST
+	Port                string   `yaml:"port" json:"port"`                                   // SHORTENER_PORT
+	RedirectStatus      int      `yaml:"redirect_status" json:"redirect_status"`             // SHORTENER_REDIRECT_STATUS
+	IDLength            int      `yaml:"id_length" json:"id_length"`                         // SHORTENER_ID_LENGTH
+	MaxEntries          int      `yaml:"max_entries" json:"max_entries"`                     // SHORTENER_MAX_ENTRIES
+	MaxBodyBytes        int      `yaml:"max_body_bytes" json:"max_body_bytes"`               // SHORTENER_MAX_BODY_BYTES
+	AdminToken          string   `yaml:"admin_token" json:"admin_token"`                     // SHORTENER_ADMIN_TOKEN
+	LogFile             string   `yaml:"log_file" json:"log_file"`                           // SHORTENER_LOG_FILE
+	CORSOrigins         []string `yaml:"cors_origins" json:"cors_origins"`                   // SHORTENER_CORS_ORIGINS
+	RedisAddr           string   `yaml:"redis_addr" json:"redis_addr"`                       // REDIS_ADDR
+	RedisPassword       string   `yaml:"redis_password" json:"redis_password"`               // REDIS_PASSWORD
+	RedisDB             int      `yaml:"redis_db" json:"redis_db"`                           // REDIS_DB
+	RedisClusterAddrs   []string `yaml:"redis_cluster_addrs" json:"redis_cluster_addrs"`     // REDIS_CLUSTER_ADDRS
+	RedisSentinelMaster string   `yaml:"redis_sentinel_master" json:"redis_sentinel_master"` // REDIS_SENTINEL_MASTER
+	RedisSentinelAddrs  []string `yaml:"redis_sentinel_addrs" json:"redis_sentinel_addrs"`   // REDIS_SENTINEL_ADDRS
+	RedisPoolSize       int      `yaml:"redis_pool_size" json:"redis_pool_size"`             // REDIS_POOL_SIZE
+	RedisDialTimeout    string   `yaml:"redis_dial_timeout" json:"redis_dial_timeout"`       // REDIS_DIAL_TIMEOUT
+	RedisReadTimeout    string   `yaml:"redis_read_timeout" json:"redis_read_timeout"`       // REDIS_READ_TIMEOUT
+	RedisWriteTimeout   string   `yaml:"redis_write_timeout" json:"redis_write_timeout"`     // REDIS_WRITE_TIMEOUT
+}
+
+// Load reads and parses the configuration file at path, as JSON
+// when it ends in .json and YAML otherwise. Unknown settings are refused so a
+// misspelt key isn't silently ignored.
+func Load(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config file: %w", err)
+	}
+
+	var cfg Config
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&cfg)
+	} else {
+		dec := yaml.NewDecoder(bytes.NewReader(data))
+		dec.KnownFields(true)
+		err = dec.Decode(&cfg)
+	}
+	// An empty file configures nothing
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("failed to parse config file %s: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+// Env returns the environment variables the file sets, leaving out settings
+// it doesn't mention
+func (c *Config) Env() map[string]string {
+	env := make(map[string]string)
+	set := func(name, value string) {
+		if value != "" {
+			env[name] = value
+		}
+	}
+	setInt := func(name string, value int) {
+		if value != 0 {
+			env[name] = strconv.Itoa(value)
+		}
+	}
+	// For settings where zero means something, like no limit
+	setIntPtr := func(name string, value *int) {
+		if value != nil {
+			env[name] = strconv.Itoa(*value)
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			env[name] = strconv.FormatBool(*value)
+		}
+	}
+
+	server := c.IRC.Server
+	if c.IRC.TLS && server != "" && !strings.Contains(server, "://") {
+		server = "ircs://" + server
+	}
+	set("SERVER", server)
+	set("CHANNEL", strings.Join(c.IRC.Channels, ","))
+	set("IRC_NICK", c.IRC.Nick)
+	set("IRC_USER", c.IRC.User)
+	set("IRC_REALNAME", c.IRC.RealName)
+	set("PASS", c.IRC.Password)
+	set("SASL_LOGIN", c.IRC.SASLLogin)
+	set("SASL_PASSWORD", c.IRC.SASLPassword)
+	set("ALLOWED_CHANNELS", strings.Join(c.IRC.AllowedChannels, ","))
+	set("ADMIN_ACCOUNTS", strings.Join(c.IRC.AdminAccounts, ","))
+	set("REPLY_TYPE", c.IRC.ReplyType)
+	set("KICK_REJOIN_DELAY", c.IRC.KickRejoinDelay)
+	setInt("KICK_REJOIN_MAX", c.IRC.KickRejoinMax)
+	setIntPtr("MAX_RESPONSE_LINES", c.IRC.MaxResponseLines)
+	setInt("MESSAGE_WORKERS", c.IRC.MessageWorkers)
+	setIntPtr("MESSAGE_QUEUE_SIZE", c.IRC.MessageQueueSize)
+
+	set("ANTHROPIC_API_KEY", c.Model.APIKey)
+	setBool("MODEL_VISION", c.Model.Vision)
+	setIntPtr("CIRCUIT_BREAKER_THRESHOLD", c.Model.CircuitBreakerThreshold)
+	set("CIRCUIT_BREAKER_COOLDOWN", c.Model.CircuitBreakerCooldown)
+
+	set("S3_BUCKET", c.S3.Bucket)
+	set("S3_REGION", c.S3.Region)
+	set("S3_ENDPOINT", c.S3.Endpoint)
+	setBool("S3_FORCE_PATH_STYLE", c.S3.ForcePathStyle)
+	setInt("S3_MAX_ATTEMPTS", c.S3.MaxAttempts)
+	set("AWS_PROFILE", c.S3.Profile)
+	set("S3_ACCESS_KEY", c.S3.AccessKey)
+	set("S3_SECRET_KEY", c.S3.SecretKey)
+
+	set("DENO_PATH", c.Executor.DenoPath)
+	set("WORKSPACE_DIR", c.Executor.WorkspaceDir)
+	setInt("MAX_SCRIPT_BYTES", c.Executor.MaxScriptBytes)
+	setInt("MAX_TOOL_CALLS", c.Executor.MaxToolCalls)
+	setBool("UPLOAD_RESULTS", c.Executor.UploadResults)
+	set("DENIED_CODE_PATTERNS", strings.Join(c.Executor.DeniedCode, "\n"))
+	setBool("EXECUTION_NOTICES", c.Executor.Notices)
+
+	set("SHORTENER_HOST", c.Shortener.Host)
+	set("SHORTENER_PORT", c.Shortener.Port)
+	setInt("SHORTENER_REDIRECT_STATUS", c.Shortener.RedirectStatus)
+	setInt("SHORTENER_ID_LENGTH", c.Shortener.IDLength)
+	setInt("SHORTENER_MAX_ENTRIES", c.Shortener.MaxEntries)
+	setInt("SHORTENER_MAX_BODY_BYTES", c.Shortener.MaxBodyBytes)
+	set("SHORTENER_ADMIN_TOKEN", c.Shortener.AdminToken)
+	set("SHORTENER_LOG_FILE", c.Shortener.LogFile)
+	set("SHORTENER_CORS_ORIGINS", strings.Join(c.Shortener.CORSOrigins, ","))
+	set("REDIS_ADDR", c.Shortener.RedisAddr)
+	set("REDIS_PASSWORD", c.Shortener.RedisPassword)
+	setInt("REDIS_DB", c.Shortener.RedisDB)
+	set("REDIS_CLUSTER_ADDRS", strings.Join(c.Shortener.RedisClusterAddrs, ","))
+	set("REDIS_SENTINEL_MASTER", c.Shortener.RedisSentinelMaster)
+	set("REDIS_SENTINEL_ADDRS", strings.Join(c.Shortener.RedisSentinelAddrs, ","))
+	setInt("REDIS_POOL_SIZE", c.Shortener.RedisPoolSize)
+	set("REDIS_DIAL_TIMEOUT", c.Shortener.RedisDialTimeout)
+	set("REDIS_READ_TIMEOUT", c.Shortener.RedisReadTimeout)
+	set("REDIS_WRITE_TIMEOUT", c.Shortener.RedisWriteTimeout)
+	return env
+}
+
+// Apply loads CONFIG_FILE, if set, and exports its settings as environment
+// variables that aren't already set. Call it before anything reads the
+// environment.
+func Apply() error {
+	path := os.Getenv("CONFIG_FILE")
+	if path == "" {
+		return nil
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		return err
+	}
+	for name, value := range cfg.Env() {
+		if _, set := os.LookupEnv(name); set {
+			continue
+		}
+		if err := os.Setenv(name, value); err != nil {
+			return fmt.Errorf("failed to set %s: %w", name, err)
+		}
+	}
+	return nil
+}
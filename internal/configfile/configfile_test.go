@@ -0,0 +1,197 @@
+package configfile
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadYAML(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+irc:
+  server: irc.example.com:6697
+  tls: true
+  channels: ["#a", "#b"]
+  nick: helper
+model:
+  api_key: sk-test
+  vision: false
+s3:
+  bucket: results
+  max_attempts: 2
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	env := cfg.Env()
+	expected := map[string]string{
+		"SERVER":            "ircs://irc.example.com:6697",
+		"CHANNEL":           "#a,#b",
+		"IRC_NICK":          "helper",
+		"ANTHROPIC_API_KEY": "sk-test",
+		"MODEL_VISION":      "false",
+		"S3_BUCKET":         "results",
+		"S3_MAX_ATTEMPTS":   "2",
+	}
+	for name, value := range expected {
+		if env[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, env[name])
+		}
+	}
+	if len(env) != len(expected) {
+		t.Errorf("Expected only the settings in the file, got %v", env)
+	}
+}
+
+func TestLoadJSON(t *testing.T) {
+	path := writeConfigFile(t, "config.json", `{"irc": {"server": "irc.example.com", "channels": ["#a"]}, "shortener": {"redis_db": 3}}`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	env := cfg.Env()
+	if env["SERVER"] != "irc.example.com" || env["REDIS_DB"] != "3" {
+		t.Errorf("Unexpected settings %v", env)
+	}
+}
+
+func TestLoadOperationalSettings(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+irc:
+  kick_rejoin_delay: 10s
+  kick_rejoin_max: 3
+  max_response_lines: 0
+  message_workers: 2
+  message_queue_size: 0
+model:
+  circuit_breaker_threshold: 0
+  circuit_breaker_cooldown: 30s
+executor:
+  notices: false
+shortener:
+  redis_cluster_addrs: ["redis-1:6379", "redis-2:6379"]
+  redis_sentinel_master: mymaster
+  redis_sentinel_addrs: ["sentinel-1:26379"]
+  redis_pool_size: 20
+  redis_dial_timeout: 5s
+  redis_read_timeout: 2s
+  redis_write_timeout: 3s
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// Zero is kept where it means something, like no limit
+	env := cfg.Env()
+	expected := map[string]string{
+		"KICK_REJOIN_DELAY":         "10s",
+		"KICK_REJOIN_MAX":           "3",
+		"MAX_RESPONSE_LINES":        "0",
+		"MESSAGE_WORKERS":           "2",
+		"MESSAGE_QUEUE_SIZE":        "0",
+		"CIRCUIT_BREAKER_THRESHOLD": "0",
+		"CIRCUIT_BREAKER_COOLDOWN":  "30s",
+		"EXECUTION_NOTICES":         "false",
+		"REDIS_CLUSTER_ADDRS":       "redis-1:6379,redis-2:6379",
+		"REDIS_SENTINEL_MASTER":     "mymaster",
+		"REDIS_SENTINEL_ADDRS":      "sentinel-1:26379",
+		"REDIS_POOL_SIZE":           "20",
+		"REDIS_DIAL_TIMEOUT":        "5s",
+		"REDIS_READ_TIMEOUT":        "2s",
+		"REDIS_WRITE_TIMEOUT":       "3s",
+	}
+	for name, value := range expected {
+		if env[name] != value {
+			t.Errorf("Expected %s=%q, got %q", name, value, env[name])
+		}
+	}
+	if len(env) != len(expected) {
+		t.Errorf("Expected only the settings in the file, got %v", env)
+	}
+}
+
+func TestLoadInvalid(t *testing.T) {
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.yaml")); err == nil {
+		t.Error("Expected an error for a missing file")
+	}
+	path := writeConfigFile(t, "config.yaml", "irc: [not, a, map]")
+	if _, err := Load(path); err == nil {
+		t.Error("Expected an error for a malformed file")
+	}
+	// Misspelt settings are refused rather than ignored
+	path = writeConfigFile(t, "config.yaml", "irc:\n  nik: helper\n")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "nik") {
+		t.Errorf("Expected an error naming the unknown YAML field, got %v", err)
+	}
+	path = writeConfigFile(t, "config.json", `{"irc": {"sasl_pasword": "x"}}`)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "sasl_pasword") {
+		t.Errorf("Expected an error naming the unknown JSON field, got %v", err)
+	}
+}
+
+func TestLoadEmpty(t *testing.T) {
+	cfg, err := Load(writeConfigFile(t, "config.yaml", ""))
+	if err != nil {
+		t.Fatalf("Expected an empty file to load, got %v", err)
+	}
+	if env := cfg.Env(); len(env) != 0 {
+		t.Errorf("Expected no settings, got %v", env)
+	}
+}
+
+func TestLoadExample(t *testing.T) {
+	cfg, err := Load("../../config.example.yaml")
+	if err != nil {
+		t.Fatalf("Expected the example config to load, got %v", err)
+	}
+	if env := cfg.Env(); env["SERVER"] != "ircs://irc.example.com:6697" {
+		t.Errorf("Unexpected settings %v", env)
+	}
+}
+
+func TestLoadSASL(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", `
+irc:
+  sasl_login: helper
+  sasl_password: hunter2
+`)
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if env := cfg.Env(); env["SASL_LOGIN"] != "helper" || env["SASL_PASSWORD"] != "hunter2" {
+		t.Errorf("Expected the SASL settings, got %v", env)
+	}
+}
+
+func TestApplyEnvOverrides(t *testing.T) {
+	path := writeConfigFile(t, "config.yaml", "irc:\n  server: file.example.com\n  nick: filenick\n")
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("SERVER", "env.example.com")
+	// Registered with t.Setenv so it's restored after the test
+	t.Setenv("IRC_NICK", "")
+	os.Unsetenv("IRC_NICK")
+
+	if err := Apply(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got := os.Getenv("SERVER"); got != "env.example.com" {
+		t.Errorf("Expected the environment to win, got %q", got)
+	}
+	if got := os.Getenv("IRC_NICK"); got != "filenick" {
+		t.Errorf("Expected the file to fill in IRC_NICK, got %q", got)
+	}
+}
@@ -0,0 +1,116 @@
+// Package envconf reads typed settings from environment variables. A setting
+// declares its variable, default and bounds once, so the up-front config
+// check and the code that uses the value can't disagree about what is valid.
+package envconf
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Int is an integer setting of at least Min
+type Int struct {
+	Name    string
+	Default int
+	Min     int
+}
+
+// Get returns the setting's value, or its default when the variable is unset
+func (s Int) Get() (int, error) {
+	raw := os.Getenv(s.Name)
+	if raw == "" {
+		return s.Default, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < s.Min {
+		switch s.Min {
+		case 0:
+			return 0, fmt.Errorf("%s must be a non-negative integer, got %q", s.Name, raw)
+		case 1:
+			return 0, fmt.Errorf("%s must be a positive integer, got %q", s.Name, raw)
+		default:
+			return 0, fmt.Errorf("%s must be an integer of at least %d, got %q", s.Name, s.Min, raw)
+		}
+	}
+	return n, nil
+}
+
+// Check reports whether the variable, if set, is valid
+func (s Int) Check() error {
+	_, err := s.Get()
+	return err
+}
+
+// Duration is a duration setting of at least Min, so never negative. A
+// positive Min rules out zero too.
+type Duration struct {
+	Name    string
+	Default time.Duration
+	Min     time.Duration
+}
+
+// Get returns the setting's value, or its default when the variable is unset
+func (s Duration) Get() (time.Duration, error) {
+	raw := os.Getenv(s.Name)
+	if raw == "" {
+		return s.Default, nil
+	}
+	d, err := time.ParseDuration(raw)
+	if err != nil || d < max(s.Min, 0) {
+		if s.Min > 0 {
+			return 0, fmt.Errorf("%s must be a positive duration like 1m, got %q", s.Name, raw)
+		}
+		return 0, fmt.Errorf("%s must be a duration like 30s, got %q", s.Name, raw)
+	}
+	return d, nil
+}
+
+// Check reports whether the variable, if set, is valid
+func (s Duration) Check() error {
+	_, err := s.Get()
+	return err
+}
+
+// Bool is a true or false setting
+type Bool struct {
+	Name    string
+	Default bool
+}
+
+// Get returns the setting's value, or its default when the variable is unset
+func (s Bool) Get() (bool, error) {
+	raw := os.Getenv(s.Name)
+	if raw == "" {
+		return s.Default, nil
+	}
+	b, err := strconv.ParseBool(raw)
+	if err != nil {
+		return false, fmt.Errorf("%s must be true or false, got %q", s.Name, raw)
+	}
+	return b, nil
+}
+
+// Check reports whether the variable, if set, is valid
+func (s Bool) Check() error {
+	_, err := s.Get()
+	return err
+}
+
+// Setting is any of the settings, for checking a list of them
+type Setting interface {
+	Check() error
+}
+
+// SplitList parses a comma-separated value, dropping empty entries
+func SplitList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
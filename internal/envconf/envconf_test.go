@@ -0,0 +1,72 @@
+package envconf
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestInt(t *testing.T) {
+	s := Int{Name: "TEST_ENVCONF_INT", Default: 10, Min: 1}
+
+	t.Setenv(s.Name, "")
+	if n, err := s.Get(); err != nil || n != 10 {
+		t.Errorf("Expected the default when unset, got %d (err %v)", n, err)
+	}
+	t.Setenv(s.Name, "3")
+	if n, err := s.Get(); err != nil || n != 3 {
+		t.Errorf("Expected 3, got %d (err %v)", n, err)
+	}
+	for _, raw := range []string{"0", "-1", "ten"} {
+		t.Setenv(s.Name, raw)
+		if err := s.Check(); err == nil || !strings.Contains(err.Error(), "positive integer") {
+			t.Errorf("%q: Expected a positive integer error, got %v", raw, err)
+		}
+	}
+}
+
+func TestDuration(t *testing.T) {
+	s := Duration{Name: "TEST_ENVCONF_DURATION", Default: time.Second}
+
+	t.Setenv(s.Name, "0s")
+	if d, err := s.Get(); err != nil || d != 0 {
+		t.Errorf("Expected zero to be allowed, got %s (err %v)", d, err)
+	}
+	t.Setenv(s.Name, "-1s")
+	if err := s.Check(); err == nil {
+		t.Error("Expected a negative duration to be refused")
+	}
+
+	positive := Duration{Name: s.Name, Default: time.Minute, Min: time.Nanosecond}
+	t.Setenv(s.Name, "0s")
+	if err := positive.Check(); err == nil || !strings.Contains(err.Error(), "positive duration") {
+		t.Errorf("Expected a positive duration error, got %v", err)
+	}
+}
+
+func TestBool(t *testing.T) {
+	s := Bool{Name: "TEST_ENVCONF_BOOL", Default: true}
+
+	t.Setenv(s.Name, "")
+	if b, err := s.Get(); err != nil || !b {
+		t.Errorf("Expected the default when unset, got %v (err %v)", b, err)
+	}
+	t.Setenv(s.Name, "false")
+	if b, err := s.Get(); err != nil || b {
+		t.Errorf("Expected false, got %v (err %v)", b, err)
+	}
+	t.Setenv(s.Name, "maybe")
+	if err := s.Check(); err == nil {
+		t.Error("Expected an error for maybe")
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	got := SplitList(" a, ,b,,c ")
+	if strings.Join(got, "|") != "a|b|c" {
+		t.Errorf("Expected [a b c], got %q", got)
+	}
+	if got := SplitList(""); got != nil {
+		t.Errorf("Expected nil for an empty value, got %q", got)
+	}
+}
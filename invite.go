@@ -0,0 +1,41 @@
+package main
+
+import "fmt"
+
+// inviteAction is what the bot does with an INVITE
+type inviteAction int
+
+const (
+	inviteIgnore inviteAction = iota // drop the invite silently
+	inviteJoin                       // join the channel
+	inviteNotify                     // decline, but tell the invite notify nick
+)
+
+// parseInviteJoinAll reads INVITE_AUTO_JOIN: "allowed" (the default) joins
+// invites only to channels named in ALLOWED_CHANNELS, "all" joins any invite
+func parseInviteJoinAll(raw string) (bool, error) {
+	switch raw {
+	case "", "allowed":
+		return false, nil
+	case "all":
+		return true, nil
+	}
+	return false, fmt.Errorf("INVITE_AUTO_JOIN must be \"allowed\" or \"all\", got %q", raw)
+}
+
+// decideInvite picks how to handle an invite to channel. Without joinAll only
+// channels explicitly allowlisted are joined, so an unrestricted guard doesn't
+// let anyone pull the bot anywhere. notify reports whether someone should hear
+// about declined invites.
+func decideInvite(guard *ChannelGuard, joinAll bool, channel string, notify bool) inviteAction {
+	if !isChannel(channel) {
+		return inviteIgnore
+	}
+	if guard.Allowed(channel) && (joinAll || guard.Restricted()) {
+		return inviteJoin
+	}
+	if notify {
+		return inviteNotify
+	}
+	return inviteIgnore
+}
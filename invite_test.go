@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestDecideInvite(t *testing.T) {
+	restricted := NewChannelGuard([]string{"#agent", "#dev"})
+	open := NewChannelGuard(nil)
+
+	tests := []struct {
+		name    string
+		guard   *ChannelGuard
+		joinAll bool
+		channel string
+		notify  bool
+		want    inviteAction
+	}{
+		{"allowlisted", restricted, false, "#dev", false, inviteJoin},
+		{"allowlisted, any case", restricted, false, "#DEV", false, inviteJoin},
+		{"not allowlisted", restricted, false, "#random", false, inviteIgnore},
+		{"not allowlisted, notify", restricted, false, "#random", true, inviteNotify},
+		{"join all still respects the allowlist", restricted, true, "#random", false, inviteIgnore},
+		{"no allowlist", open, false, "#random", true, inviteNotify},
+		{"no allowlist, join all", open, true, "#random", false, inviteJoin},
+		{"not a channel", open, true, "alice", true, inviteIgnore},
+	}
+	for _, tt := range tests {
+		if got := decideInvite(tt.guard, tt.joinAll, tt.channel, tt.notify); got != tt.want {
+			t.Errorf("%s: Expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestParseInviteJoinAll(t *testing.T) {
+	for raw, want := range map[string]bool{"": false, "allowed": false, "all": true} {
+		got, err := parseInviteJoinAll(raw)
+		if err != nil || got != want {
+			t.Errorf("%q: Expected %v, got %v (%v)", raw, want, got, err)
+		}
+	}
+	if _, err := parseInviteJoinAll("some"); err == nil {
+		t.Error("Expected an error for an unknown value")
+	}
+}
@@ -2,13 +2,22 @@ package main
 
 import (
 	"context"
+	"crypto/tls"
 	"fmt"
+	"iter"
 	"log"
+	"log/slog"
+	"net"
 	"os"
+	"runtime/debug"
 	"strings"
+	"sync"
+	"time"
 
-	irc "github.com/thoj/go-ircevent"
+	"github.com/r33drichards/irc-agent/internal/envconf"
 	anthropicmodel "github.com/r33drichards/irc-agent/model/anthropic"
+	"github.com/r33drichards/irc-agent/shortener"
+	irc "github.com/thoj/go-ircevent"
 	"google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/artifact"
@@ -20,49 +29,239 @@ import (
 	"google.golang.org/genai"
 )
 
+// agentRunner runs the agent for one message. It is a *runner.Runner outside of tests.
+type agentRunner interface {
+	Run(ctx context.Context, userID, sessionID string, msg *genai.Content, cfg agent.RunConfig) iter.Seq2[*session.Event, error]
+}
+
 // IRCAgent wraps the ADK agent with IRC functionality
 type IRCAgent struct {
 	agent          agent.Agent
-	runner         *runner.Runner
+	runner         agentRunner
 	sessionService session.Service
+	sessions       *SessionLocks   // serializes runs on the same session
+	breaker        *CircuitBreaker // stops calling the model during an outage, shared across networks
+	queue          *MessageQueue   // bounds concurrent message processing, shared across networks
+	memory         *MemoryKeeper   // facts about users; nil when memory is off
+	network        string          // network name, empty for the single network from SERVER
 	ircConn        *irc.Connection
-	channel        string
+	serverAddr     string // normalized host:port from SERVER
+	useTLS         bool
+	channel        string           // first configured channel
+	channels       []string         // all channels joined on connect
+	joined         *JoinedChannels  // channels the bot is in right now
+	rejoiner       *KickRejoiner    // decides whether to rejoin after a kick
+	commands       *CommandRegistry // comma commands, shared across networks
 	handler        *IRCMessageHandler
+	members        *ChannelMembership
+	history        *ChannelHistory
+	ignore         *IgnoreList
+	dedup          *MessageDeduper
+	maxToolCalls   int                     // per-message cap on tool invocations, 0 for unlimited
+	replyCap       *ResponseCap            // cuts long model replies short with a link to the full text
+	thinkingDelay  time.Duration           // how long before a "thinking" notice is sent, 0 to never send one
+	thinkingMsg    string                  // the notice sent for slow replies
+	vision         bool                    // attach image URLs from messages for the model to look at
+	provider       string                  // model provider, reported by ,model
+	modelName      string                  // default model name, reported by ,model
+	overrides      ChannelOverrides        // per-channel settings, including model overrides
+	stats          *AgentStats             // counters reported by ,stats
+	accounts       *AccountVerifier        // resolves nicks to NickServ accounts via WHOIS
+	adminAccounts  []string                // accounts allowed to run admin commands, empty for anyone
+	joinGreeting   string                  // sent after joining a channel unless overridden, empty to stay silent
+	outbound       *OutboundBuffer         // all outgoing messages, held while disconnected
+	nickServPass   string                  // password sent when NickServ asks the bot to identify
+	identified     identifyWaiter          // NickServ confirmation for the current connection
+	urlShortener   *shortener.URLShortener // resolves short links for ,expand
+	inflight       sync.WaitGroup          // processMessage runs still going
+	pending        *InflightRequests       // messages being answered, so repeats are skipped
+	ircUser        string                  // username sent at registration, part of the prefix on relayed messages
+	isupport       *ISupport               // limits the server advertised in 005, used to size outgoing messages
+	guard          *ChannelGuard           // channels the bot may operate in, from ALLOWED_CHANNELS
+	inviteJoinAll  bool                    // join invites to any allowed channel, not just allowlisted ones
+	inviteNotify   string                  // nick told about declined invites, empty for nobody
 }
 
-// NewIRCAgent creates a new IRC agent with ADK integration
-func NewIRCAgent(ctx context.Context, urlShortener *URLShortener) (*IRCAgent, error) {
+// outboundBufferSize caps how many messages are held while disconnected
+const outboundBufferSize = 100
+
+// NewIRCAgents creates an IRC agent with ADK integration for each configured
+// network. The agents share one model, runner and set of tools; each has its
+// own connection and answers on the network a message came from.
+func NewIRCAgents(ctx context.Context, urlShortener *shortener.URLShortener, artifactStore ArtifactStore) ([]*IRCAgent, error) {
 	// Get environment variables
-	server := os.Getenv("SERVER")
-	channel := os.Getenv("CHANNEL")
 	apiKey := os.Getenv("ANTHROPIC_API_KEY")
 
-	if server == "" || channel == "" {
-		return nil, fmt.Errorf("SERVER and CHANNEL environment variables are required")
+	networks, err := loadNetworks()
+	if err != nil {
+		return nil, err
 	}
 
 	if apiKey == "" {
 		return nil, fmt.Errorf("ANTHROPIC_API_KEY environment variable is required")
 	}
 
-	// Create IRC connection
-	ircConn := irc.IRC("agent", "agent")
-	ircConn.UseTLS = false
+	// Every channel the bot joins, across networks
+	var channels []string
+	for _, network := range networks {
+		channels = append(channels, network.channels...)
+	}
+
+	// Number of recent channel messages included as context in each prompt
+	contextSize, err := channelContextSizeSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Default generation settings (temperature, top_p)
+	generationConfig, err := loadGenerationConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	// Identical replies to a channel within this window are suppressed to avoid bot loops
+	dedupWindow, err := dedupWindowSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// A notice is sent when a reply takes longer than this, so users know the bot heard them
+	thinkingDelay, err := thinkingNoticeDelaySetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Rejoining allowlisted channels after a kick is off unless a delay is set
+	kickRejoinDelay, err := kickRejoinDelaySetting.Get()
+	if err != nil {
+		return nil, err
+	}
+	kickRejoinMax, err := kickRejoinMaxSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Consecutive model failures before the bot stops calling it for a while
+	breakerThreshold, err := breakerThresholdSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+	breakerCooldown, err := breakerCooldownSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Messages are processed by a fixed pool of workers with a bounded queue
+	messageWorkers, err := messageWorkersSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+	messageQueueSize, err := messageQueueSizeSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies longer than this many IRC lines are cut short with a link
+	maxResponseLines, err := maxResponseLinesSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Cap on tool calls per message so a confused model can't loop indefinitely
+	maxToolCalls, err := maxToolCallsSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Largest script execute_typescript accepts, 0 for the executor's default
+	maxCodeBytes, err := maxScriptBytesSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Optional guardrail against code using forbidden Deno APIs
+	deniedCode, err := deniedCodeFromEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	// Uploading code and results can be turned off for deployments without storage
+	uploadResults, err := uploadResultsSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Progress notices from execute_typescript, on unless disabled
+	executionNotices, err := executionNoticesSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Image URLs in messages are passed to the model only when it supports vision
+	vision, err := modelVisionSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Long-term memory of facts about users, off unless MEMORY_ENABLED is set
+	memoryEnabled, err := memoryEnabledSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+	memoryFacts, err := memoryMaxFactsSetting.Get()
+	if err != nil {
+		return nil, err
+	}
+
+	// Channels the bot may operate in. Each network's configured channels are
+	// always allowed there; with no ALLOWED_CHANNELS every channel is.
+	allowedChannels := envconf.SplitList(os.Getenv("ALLOWED_CHANNELS"))
+
+	// Which invites are accepted
+	inviteJoinAll, err := parseInviteJoinAll(os.Getenv("INVITE_AUTO_JOIN"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Per-channel instruction/model/temperature overrides
+	overrides, err := loadChannelOverrides()
+	if err != nil {
+		return nil, err
+	}
+
+	// Replies go out as PRIVMSG by default, or NOTICE where channels prefer it
+	replyType, err := parseReplyType(os.Getenv("REPLY_TYPE"))
+	if err != nil {
+		return nil, err
+	}
 
 	// Create Anthropic model (Claude Haiku 4.5)
-	model, err := anthropicmodel.NewModel(ctx, "claude-haiku-4-5", apiKey)
+	const provider = "anthropic"
+	model, err := anthropicmodel.NewModel(ctx, "claude-haiku-4-5", apiKey, anthropicmodel.WithVision(vision))
 	if err != nil {
 		return nil, fmt.Errorf("failed to create model: %w", err)
 	}
 
-	// Create IRC message handler
-	ircHandler := &IRCMessageHandler{
-		conn: ircConn,
+	// Scripts may reach the result bucket, wherever it is configured
+	var allowNet []string
+	if s3Store, ok := artifactStore.(*S3ArtifactStore); ok {
+		allowNet = s3Store.netHosts()
 	}
 
 	// Create TypeScript executor
-	tsExecutor := &TypeScriptExecutor{
-		URLShortener: urlShortener,
+	tsExecutor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{
+		URLShortener:  urlShortener,
+		Store:         artifactStore,
+		UploadResults: &uploadResults,
+		DenoPath:      os.Getenv("DENO_PATH"),
+		WorkspaceDir:  os.Getenv("WORKSPACE_DIR"),
+		MaxCodeBytes:  maxCodeBytes,
+		DeniedCode:    deniedCode,
+		Notices:       executionNotices,
+		AllowNet:      allowNet,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create TypeScript executor: %w", err)
 	}
 
 	// Create TypeScript execution tool using functiontool
@@ -77,16 +276,168 @@ func NewIRCAgent(ctx context.Context, urlShortener *URLShortener) (*IRCAgent, er
 		return nil, fmt.Errorf("failed to create TypeScript execution tool: %w", err)
 	}
 
-	// Create ADK agent
-	agent, err := llmagent.New(llmagent.Config{
-		Name:        "irc_agent",
-		Model:       model,
-		Description: "An intelligent IRC bot that listens to messages and responds to users in the IRC channel.",
-		Instruction: fmt.Sprintf(`You are a helpful IRC bot in the %s channel.
+	// Arithmetic without spinning up Deno
+	calcTool, err := functiontool.New(
+		functiontool.Config{
+			Name:        "calculate",
+			Description: "Evaluates an arithmetic expression and returns the numeric result. Use this instead of execute_typescript for plain arithmetic",
+		},
+		Calculate,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create calculate tool: %w", err)
+	}
+
+	tools := []tool.Tool{tsTool, calcTool}
+
+	// HTTP fetches without writing code, only for allowlisted hosts
+	if hosts := envconf.SplitList(os.Getenv("HTTP_FETCH_ALLOWED_HOSTS")); len(hosts) > 0 {
+		var fetchStore ArtifactStore
+		if uploadResults {
+			fetchStore = artifactStore
+		}
+		fetcher := NewHTTPFetcher(hosts, fetchStore, urlShortener)
+		fetchTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "http_fetch",
+				Description: "Fetches a URL with GET or POST and returns the status code and body. Only these hosts are allowed: " + strings.Join(hosts, ", ") + ". Prefer this over execute_typescript for simple HTTP requests",
+			},
+			fetcher.Fetch,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create HTTP fetch tool: %w", err)
+		}
+		tools = append(tools, fetchTool)
+	}
+
+	// Reading uploaded results back, when the store supports it
+	if reader, ok := artifactStore.(ArtifactReader); ok && uploadResults {
+		fetcher := NewResultFetcher(reader, urlShortener)
+		fetchResultTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "fetch_result",
+				Description: "Reads the full content of a previously uploaded result by its short_url, short ID or storage key, 2000 bytes at a time. Use this when execute_typescript output was truncated",
+			},
+			fetcher.Fetch,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create fetch result tool: %w", err)
+		}
+		tools = append(tools, fetchResultTool)
+	}
+
+	// Listing uploaded results, when the store supports it
+	if lister, ok := artifactStore.(ArtifactLister); ok && uploadResults {
+		resultLister := NewResultLister(lister)
+		listTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "list_results",
+				Description: "Lists uploaded results and files in key order, with their size, last-modified time and a download link. Filter with prefix and page with next_cursor. Prefer this over writing code to list the bucket",
+			},
+			resultLister.List,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create list results tool: %w", err)
+		}
+		tools = append(tools, listTool)
+	}
+
+	// Pastes and charts for sharing, when uploads are enabled
+	if uploadResults && artifactStore != nil {
+		paster := NewPaster(artifactStore, urlShortener)
+		pasteTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "create_paste",
+				Description: "Shares long code or text as a paste page and returns a short link. Use this instead of sending many lines to the channel",
+			},
+			paster.CreatePaste,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create paste tool: %w", err)
+		}
+		tools = append(tools, pasteTool)
+
+		renderer := NewChartRenderer(artifactStore, urlShortener)
+		chartTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "render_chart",
+				Description: "Renders a line, bar or pie chart from series data as a PNG image and returns a short link to it. Prefer this over writing code to draw charts",
+			},
+			renderer.RenderChart,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create chart tool: %w", err)
+		}
+		tools = append(tools, chartTool)
+	}
+
+	// Presigned uploads, so executed code can write to S3 without credentials
+	if s3Store, ok := artifactStore.(*S3ArtifactStore); ok && uploadResults {
+		issuer := NewUploadURLIssuer(s3Store, urlShortener)
+		uploadTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "presign_upload",
+				Description: "Returns a presigned upload_url that executed code can send a file to with fetch(upload_url, {method: 'PUT', body}), without AWS credentials, and the download_url to share once it is uploaded. The upload_url expires after " + presignedPutTTL.String(),
+			},
+			issuer.PresignUpload,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create presign upload tool: %w", err)
+		}
+		tools = append(tools, uploadTool)
+	}
+
+	// Facts about users, kept in the memory service across sessions
+	memoryService := memory.InMemoryService()
+	var keeper *MemoryKeeper
+	if memoryEnabled {
+		keeper = NewMemoryKeeper(memoryService, memoryFacts)
+		rememberTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "remember",
+				Description: "Remembers a short fact about the user you are answering, such as a preference, for later conversations. Only store what the user would expect you to remember",
+			},
+			keeper.RememberTool,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create remember tool: %w", err)
+		}
+		recallTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "recall",
+				Description: "Searches the facts you remember about the user you are answering",
+			},
+			keeper.RecallTool,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create recall tool: %w", err)
+		}
+		tools = append(tools, rememberTool, recallTool)
+	}
+
+	// Web search, only when a search API key is configured
+	if apiKey := os.Getenv("SEARCH_API_KEY"); apiKey != "" {
+		searcher := NewWebSearcher(os.Getenv("SEARCH_API_URL"), apiKey, urlShortener)
+		searchTool, err := functiontool.New(
+			functiontool.Config{
+				Name:        "web_search",
+				Description: "Searches the web and returns the top results with title, url and snippet. Use this for current events or anything you don't know",
+			},
+			searcher.Search,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create web search tool: %w", err)
+		}
+		tools = append(tools, searchTool)
+	}
+
+	// Built-in system instruction, used unless INSTRUCTION_FILE, INSTRUCTION or a channel override replaces it
+	instruction := fmt.Sprintf(`You are a helpful IRC bot in these channels: %[1]s.
 Your role is to assist users with their questions and engage in friendly conversation.
 When users ask you questions or mention you, provide helpful and concise responses.
 Your responses are automatically sent to the IRC channel, so just respond naturally.
 Keep your responses brief and appropriate for IRC chat (usually 1-2 lines).
+Users may emote with /me. You can too: a reply starting with "/me " is sent as an action.
 
 CRITICAL - Your Code Execution Capabilities:
 You have the execute_typescript tool which gives you POWERFUL capabilities to accomplish virtually ANY task users request.
@@ -95,36 +446,39 @@ You have the execute_typescript tool which gives you POWERFUL capabilities to ac
 - Don't ask for permission or additional tools - you already have the necessary permissions
 - Be proactive and write the code needed to accomplish the user's goals
 - If something doesn't exist (a function, API wrapper, etc.), write the code to create it yourself
+- For plain arithmetic, use the calculate tool instead - it's much faster than running code
 
-IMPORTANT - Code Execution Results Workflow:
-1. When you use execute_typescript, results are AUTOMATICALLY uploaded to S3
+`, strings.Join(channels, ", "))
+	// Results are only described when they are uploaded somewhere
+	if uploadResults && artifactStore != nil {
+		instruction += `IMPORTANT - Code Execution Results Workflow:
+1. When you use execute_typescript, results are AUTOMATICALLY uploaded
 2. The response includes TWO URL fields (both are OUTPUT, not input):
-   - "signed_url": The full S3 presigned URL (long)
+   - "signed_url": The full presigned URL (long)
    - "short_url": The shortened version (automatically displayed in IRC after tool execution)
 3. The "output" field may be TRUNCATED (max 500 chars) to save tokens
-4. If truncated, use execute_typescript again with Deno to download the full results from the signed_url
+4. If truncated, call fetch_result with the short_url to read the full results (pass next_offset to continue) instead of running more code
 5. Signed URLs are valid for 24 hours
 6. The short_url is automatically shown in IRC - you don't need to mention it in your response
 
 Note: Both signed_url and short_url are OUTPUT fields, NOT input parameters to execute_typescript.
 
-Deno Environment & Permissions:
-- Deno runs with: --allow-env="AWS_*", --allow-net=s3.us-west-2.amazonaws.com,robust-cicada.s3.us-west-2.amazonaws.com,localhost:3000, --allow-read=., --allow-write=.
-- AWS credentials are available via environment variables
-- Full access to S3 bucket: s3://robust-cicada
-- AWS SDK is available for Deno
+`
+	}
+	instruction += fmt.Sprintf(`Deno Environment & Permissions:
+- Deno runs with: --allow-env="AWS_*", --allow-net=%[1]s, --allow-read=., --allow-write=.
 - You can use npm packages with "npm:" prefix (e.g., "npm:@aws-sdk/client-s3@3")
 
 URL Shortening Service:
-- A URL shortener is running at http://localhost:3000
+- A URL shortener is running at http://localhost:%[2]s
 - Use POST requests to shorten long URLs (especially AWS S3 signed/presigned URLs)
 - IMPORTANT: When users need to access URLs (especially signed URLs from S3), ALWAYS shorten them first
 - This makes URLs much easier to copy, paste, and share in IRC
 - Example use cases: S3 presigned URLs, API endpoints, any long URL a user might need
 
 Example: Shorten a URL using fetch in Deno:
-const longUrl = "https://robust-cicada.s3.us-west-2.amazonaws.com/...very-long-signed-url...";
-const response = await fetch("http://localhost:3000/", {
+const longUrl = "https://example.com/...very-long-signed-url...";
+const response = await fetch("http://localhost:%[2]s/", {
   method: "POST",
   body: longUrl
 });
@@ -137,48 +491,39 @@ const text = await response.text();
 await Deno.writeTextFile("./result.txt", text);
 const content = await Deno.readTextFile("./result.txt");
 console.log(content);
+`, strings.Join(tsExecutor.allowedHosts(), ","), shortener.Port())
+	// The S3 section names the configured bucket; other stores have none to describe
+	if s3Store, ok := artifactStore.(*S3ArtifactStore); ok && uploadResults {
+		instruction += s3Instruction(s3Store)
+	}
 
-Example: Use AWS SDK in Deno to interact with S3:
-import { S3Client, GetObjectCommand } from "npm:@aws-sdk/client-s3@3";
-const client = new S3Client({ region: "us-west-2" });
-const command = new GetObjectCommand({
-  Bucket: "robust-cicada",
-  Key: "code-results/1234567890-abcdef.txt"
-});
-const response = await client.send(command);
-const body = await response.Body.transformToString();
-console.log(body);
-
-Example: List all objects in an S3 bucket:
-import { S3Client, ListObjectsV2Command } from "npm:@aws-sdk/client-s3@3";
-const client = new S3Client({ region: "us-west-2" });
-const command = new ListObjectsV2Command({
-  Bucket: "robust-cicada"
-});
-const response = await client.send(command);
-console.log(JSON.stringify(response.Contents, null, 2));
-
-Example: Rename an S3 object (copy then delete):
-import { S3Client, CopyObjectCommand, DeleteObjectCommand } from "npm:@aws-sdk/client-s3@3";
-const client = new S3Client({ region: "us-west-2" });
-const oldKey = "1719040270770.jpeg";
-const newKey = "hdsht.jpeg";
-// Copy to new name
-await client.send(new CopyObjectCommand({
-  Bucket: "robust-cicada",
-  CopySource: "robust-cicada/" + oldKey,
-  Key: newKey
-}));
-// Delete old object
-await client.send(new DeleteObjectCommand({
-  Bucket: "robust-cicada",
-  Key: oldKey
-}));
-console.log("Renamed " + oldKey + " to " + newKey);
-`, channel),
-		Tools: []tool.Tool{
-			tsTool,
-		},
+	// Operators can replace the built-in instruction with their own template
+	instructionTmpl, err := loadInstructionTemplate()
+	if err != nil {
+		return nil, err
+	}
+	defaultInstruction := func(userID string) (string, error) {
+		if instructionTmpl == nil {
+			return instruction, nil
+		}
+		network, channel := splitUserID(userID)
+		return renderInstruction(instructionTmpl, InstructionData{
+			Channel:       channel,
+			Channels:      strings.Join(channels, ", "),
+			Nick:          nickFor(networks, network, channel),
+			ShortenerPort: shortener.Port(),
+		})
+	}
+
+	// Create ADK agent
+	agent, err := llmagent.New(llmagent.Config{
+		Name:                  "irc_agent",
+		Model:                 model,
+		Description:           "An intelligent IRC bot that listens to messages and responds to users in the IRC channel.",
+		InstructionProvider:   overrides.instructionProvider(defaultInstruction),
+		GenerateContentConfig: generationConfig,
+		BeforeModelCallbacks:  []llmagent.BeforeModelCallback{overrides.beforeModel},
+		Tools:                 tools,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create agent: %w", err)
@@ -187,89 +532,552 @@ console.log("Renamed " + oldKey + " to " + newKey);
 	// Create session service
 	sessionService := session.InMemoryService()
 
+	// Shared by every network
+	stats := NewAgentStats(time.Now())
+	ignore := NewIgnoreList(
+		envconf.SplitList(os.Getenv("IGNORE_NICKS")),
+		envconf.SplitList(os.Getenv("IGNORE_HOSTMASKS")),
+	)
+	adminAccounts := envconf.SplitList(os.Getenv("ADMIN_ACCOUNTS"))
+
 	// Create runner with in-memory services
 	agentRunner, err := runner.New(runner.Config{
 		AppName:         "irc_agent",
 		Agent:           agent,
 		SessionService:  sessionService,
 		ArtifactService: artifact.InMemoryService(),
-		MemoryService:   memory.InMemoryService(),
+		MemoryService:   memoryService,
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to create runner: %w", err)
 	}
 
-	return &IRCAgent{
-		agent:          agent,
-		runner:         agentRunner,
-		sessionService: sessionService,
-		ircConn:        ircConn,
-		channel:        channel,
-		handler:        ircHandler,
-	}, nil
+	// Sessions are shared, so are their locks
+	sessionLocks := NewSessionLocks()
+	breaker := NewCircuitBreaker(breakerThreshold, breakerCooldown)
+	queue := NewMessageQueue(messageWorkers, messageQueueSize)
+	var replyStore ArtifactStore
+	if uploadResults {
+		replyStore = artifactStore
+	}
+	replyCap := NewResponseCap(maxResponseLines, replyStore, urlShortener)
+	commands := builtinCommands()
+
+	agents := make([]*IRCAgent, 0, len(networks))
+	for _, network := range networks {
+		// Create IRC connection, enabling TLS for ircs:// servers
+		ircConn := irc.IRC(network.identity.Nick, network.identity.User)
+		ircConn.RealName = network.identity.RealName
+		ircConn.UseTLS = network.useTLS
+		if network.useTLS {
+			host, _, _ := net.SplitHostPort(network.serverAddr)
+			ircConn.TLSConfig = &tls.Config{ServerName: host}
+		}
+		if network.sasl.login != "" {
+			ircConn.UseSASL = true
+			ircConn.SASLMech = "PLAIN"
+			ircConn.SASLLogin = network.sasl.login
+			ircConn.SASLPassword = network.sasl.password
+		}
+
+		// Replies are held while the connection is down, and pending rejoins
+		// dropped, from the moment the library reports the connection lost
+		outbound := NewOutboundBuffer(outboundBufferSize, NewIRCSink(ircConn, replyType))
+		rejoiner := NewKickRejoiner(kickRejoinDelay, kickRejoinMax)
+		ircConn.Log = log.New(disconnectLog{
+			out: ircConn.Log.Writer(),
+			onDisconnect: func() {
+				outbound.Disconnected()
+				rejoiner.Cancel()
+			},
+		}, ircConn.Log.Prefix(), ircConn.Log.Flags())
+
+		guardChannels := allowedChannels
+		if len(guardChannels) > 0 {
+			guardChannels = append(append([]string{}, allowedChannels...), network.channels...)
+		}
+
+		agents = append(agents, &IRCAgent{
+			agent:          agent,
+			runner:         agentRunner,
+			sessionService: sessionService,
+			sessions:       sessionLocks,
+			breaker:        breaker,
+			queue:          queue,
+			memory:         keeper,
+			network:        network.name,
+			ircConn:        ircConn,
+			serverAddr:     network.serverAddr,
+			useTLS:         network.useTLS,
+			channel:        network.channels[0],
+			channels:       network.channels,
+			handler:        &IRCMessageHandler{conn: ircConn},
+			joined:         NewJoinedChannels(),
+			rejoiner:       rejoiner,
+			commands:       commands,
+			members:        NewChannelMembership(),
+			history:        NewChannelHistory(contextSize),
+			ignore:         ignore,
+			dedup:          NewMessageDeduper(dedupWindow),
+			pending:        NewInflightRequests(),
+			maxToolCalls:   maxToolCalls,
+			replyCap:       replyCap,
+			thinkingDelay:  thinkingDelay,
+			thinkingMsg:    envOrDefault("THINKING_NOTICE_MESSAGE", defaultThinkingMessage),
+			vision:         vision,
+			provider:       provider,
+			modelName:      model.Name(),
+			overrides:      overrides,
+			stats:          stats,
+			accounts:       NewAccountVerifier(ircConn.Whois, time.Minute),
+			// Admin commands are verified against NickServ accounts, not nicks
+			adminAccounts: adminAccounts,
+			joinGreeting:  strings.TrimSpace(os.Getenv("JOIN_GREETING")),
+			outbound:      outbound,
+			nickServPass:  network.password,
+			urlShortener:  urlShortener,
+			ircUser:       network.identity.User,
+			isupport:      NewISupport(),
+			guard:         NewChannelGuard(guardChannels),
+			inviteJoinAll: inviteJoinAll,
+			inviteNotify:  os.Getenv("INVITE_NOTIFY"),
+		})
+	}
+	return agents, nil
+}
+
+// nickFor returns the bot's nick on the named network or, without one, on the
+// first network with channel
+func nickFor(networks []ircNetwork, name, channel string) string {
+	for _, network := range networks {
+		if name != "" && strings.EqualFold(network.name, name) {
+			return network.identity.Nick
+		}
+	}
+	for _, network := range networks {
+		for _, c := range network.channels {
+			if strings.EqualFold(c, channel) {
+				return network.identity.Nick
+			}
+		}
+	}
+	return networks[0].identity.Nick
 }
 
 // Start connects to IRC and starts listening for messages
 func (ia *IRCAgent) Start(ctx context.Context) error {
-	server := os.Getenv("SERVER")
-
 	// Set up IRC event handlers
 	ia.ircConn.AddCallback("001", func(e *irc.Event) {
 		log.Printf("Connected to IRC server")
-		ia.ircConn.Join("#agent")
-		log.Printf("Joined channel: #agent")
+		// The server re-advertises its limits on every connection
+		ia.isupport.Reset()
+		ia.outbound.Connected()
+		ia.joined.Reset()
+		// Channels are joined afresh, so rejoins from before the reconnect are dropped
+		ia.rejoiner.Cancel()
+		// Ask for IRCv3 tags; servers without CAP support just reject this
+		ia.ircConn.SendRawf("CAP REQ :%s", ircv3Caps)
+		// Identify before joining so channels that require it let us in
+		if ia.nickServPass == "" {
+			ia.joinChannels()
+			return
+		}
+		confirmed := ia.identified.Reset()
+		ia.identify()
+		go func() {
+			select {
+			case <-confirmed:
+				log.Printf("NickServ confirmed identification")
+			case <-time.After(nickServTimeout):
+				log.Printf("No NickServ confirmation after %s, joining anyway", nickServTimeout)
+			}
+			ia.joinChannels()
+		}()
 	})
 
-	// Handle PRIVMSG events
-	ia.ircConn.AddCallback("PRIVMSG", func(e *irc.Event) {
-		message := e.Message()
-		sender := e.Nick
-		// Extract the channel from the event (first argument)
+	// The server's line length arrives in 005 RPL_ISUPPORT
+	ia.ircConn.AddCallback("005", func(e *irc.Event) {
+		ia.isupport.Update(e.Arguments)
+	})
+
+	// Track channel membership from NAMES replies and JOIN/PART/KICK/QUIT/NICK
+	ia.ircConn.AddCallback("353", func(e *irc.Event) {
+		// 353 arguments: <me> <type> <channel> :<names>
+		if len(e.Arguments) < 3 {
+			return
+		}
+		channel := e.Arguments[len(e.Arguments)-2]
+		ia.members.AddNames(channel, strings.Fields(e.Message()))
+	})
+
+	ia.ircConn.AddCallback("JOIN", func(e *irc.Event) {
+		if len(e.Arguments) == 0 {
+			return
+		}
 		channel := e.Arguments[0]
+		if strings.EqualFold(e.Nick, ia.ircConn.GetNick()) {
+			// Leave channels we were pulled into (e.g. forced joins) that aren't allowed
+			if !ia.guard.Allowed(channel) {
+				log.Printf("Joined %s, which is not in ALLOWED_CHANNELS; parting", channel)
+				ia.ircConn.Part(channel)
+				return
+			}
 
-		log.Printf("[%s] <%s> %s", channel, sender, message)
+			// We joined; the server follows up with a fresh NAMES reply
+			ia.joined.Add(channel)
+			ia.members.Reset(channel)
+			ia.outbound.Joined(channel)
+
+			if greeting := ia.overrides.greeting(ia.userID(channel), ia.joinGreeting); greeting != "" {
+				ia.outbound.Send(channel, greeting)
+			}
+		}
+		ia.members.Join(channel, e.Nick)
+	})
+
+	// INVITE arguments: <our nick> <channel>
+	ia.ircConn.AddCallback("INVITE", func(e *irc.Event) {
+		if len(e.Arguments) < 2 {
+			return
+		}
+		channel := e.Arguments[1]
+		switch decideInvite(ia.guard, ia.inviteJoinAll, channel, ia.inviteNotify != "") {
+		case inviteJoin:
+			log.Printf("Invited to %s by %s, joining", channel, e.Nick)
+			ia.ircConn.Join(channel)
+		case inviteNotify:
+			log.Printf("Declining invite to %s from %s", channel, e.Nick)
+			ia.outbound.Send(ia.inviteNotify, fmt.Sprintf("Declined an invite to %s from %s (%s@%s)", channel, e.Nick, e.User, e.Host))
+		default:
+			log.Printf("Ignoring invite to %s from %s", channel, e.Nick)
+		}
+	})
 
-		if e.Nick != "agent" {
-			go ia.processMessage(ctx, sender, message, channel)
+	ia.ircConn.AddCallback("PART", func(e *irc.Event) {
+		if len(e.Arguments) == 0 {
+			return
+		}
+		channel := e.Arguments[0]
+		if strings.EqualFold(e.Nick, ia.ircConn.GetNick()) {
+			ia.joined.Remove(channel)
+			ia.members.Reset(channel)
+			ia.outbound.Parted(channel)
+			return
 		}
+		ia.members.Part(channel, e.Nick)
+	})
 
+	// The server sends ERROR as it closes the link; replies are held and
+	// rejoins dropped until the bot is back
+	ia.ircConn.AddCallback("ERROR", func(e *irc.Event) {
+		log.Printf("Disconnected by server: %s", e.Message())
+		ia.outbound.Disconnected()
+		ia.rejoiner.Cancel()
+	})
+	ia.ircConn.AddCallback("KICK", func(e *irc.Event) {
+		// KICK arguments: <channel> <nick> [:<reason>]
+		if len(e.Arguments) < 2 {
+			return
+		}
+		channel, kicked := e.Arguments[0], e.Arguments[1]
+		if strings.EqualFold(kicked, ia.ircConn.GetNick()) {
+			reason := ""
+			if len(e.Arguments) > 2 {
+				reason = e.Arguments[2]
+			}
+			log.Printf("Kicked from %s by %s: %s", channel, e.Nick, reason)
+			ia.joined.Remove(channel)
+			ia.members.Reset(channel)
+			ia.outbound.Parted(channel)
+
+			if ia.rejoiner.Kicked(channel, ia.allowlisted(channel), time.Now()) {
+				log.Printf("Rejoining %s in %s", channel, ia.rejoiner.Delay())
+				ia.rejoiner.Schedule(channel, func() { ia.ircConn.Join(channel) })
+			}
+			return
+		}
+		ia.members.Part(channel, kicked)
+	})
+
+	ia.ircConn.AddCallback("QUIT", func(e *irc.Event) {
+		ia.members.Quit(e.Nick)
+		ia.accounts.Forget(e.Nick)
+	})
+
+	ia.ircConn.AddCallback("NICK", func(e *irc.Event) {
+		ia.members.Rename(e.Nick, e.Message())
+		ia.accounts.Forget(e.Nick)
+		ia.accounts.Forget(e.Message())
+	})
+
+	// WHOIS replies used to verify admin accounts: 330 RPL_WHOISACCOUNT, 318 RPL_ENDOFWHOIS
+	ia.ircConn.AddCallback("330", func(e *irc.Event) {
+		ia.accounts.HandleAccountReply(e.Arguments)
+	})
+	ia.ircConn.AddCallback("318", func(e *irc.Event) {
+		ia.accounts.HandleEndOfWhois(e.Arguments)
+	})
+
+	// 900 RPL_LOGGEDIN also confirms identification, on servers that send it
+	ia.ircConn.AddCallback("900", func(e *irc.Event) {
+		ia.identified.Confirm()
+	})
+
+	// Handle NOTICEs, mostly from services. They are never answered by the
+	// model, since bots must not reply to notices.
+	ia.ircConn.AddCallback("NOTICE", func(e *irc.Event) {
+		log.Printf("-%s- %s", e.Nick, e.Message())
+
+		if isNickServConfirmation(e.Nick, e.Message()) {
+			ia.identified.Confirm()
+			return
+		}
+
+		if isNickServIdentifyPrompt(e.Nick, e.Message()) {
+			if ia.nickServPass == "" {
+				log.Printf("NickServ asked us to identify but PASS is not set")
+				return
+			}
+			ia.identify()
+		}
+	})
+
+	// Handle PRIVMSG events. The library hands /me actions to CTCP_ACTION
+	// with the CTCP wrapper stripped, so they're rewrapped to be told apart.
+	ia.ircConn.AddCallback("PRIVMSG", func(e *irc.Event) {
+		ia.handlePrivmsg(ctx, e, e.Message())
+	})
+	ia.ircConn.AddCallback("CTCP_ACTION", func(e *irc.Event) {
+		ia.handlePrivmsg(ctx, e, ctcpAction(e.Message()))
 	})
 
 	// Connect to IRC server
-	log.Printf("Connecting to IRC server: %s", server)
-	err := ia.ircConn.Connect(server)
+	log.Printf("Connecting to IRC server: %s (TLS: %v)", ia.serverAddr, ia.useTLS)
+	err := ia.ircConn.Connect(ia.serverAddr)
 	if err != nil {
 		return fmt.Errorf("failed to connect to IRC: %w", err)
 	}
 
+	// Leave IRC on shutdown. Cancelling ctx also stops in-flight model calls
+	// and kills running Deno processes.
+	go func() {
+		<-ctx.Done()
+		log.Printf("Shutting down, leaving IRC")
+		ia.rejoiner.Cancel()
+		ia.ircConn.Quit()
+	}()
+
 	// Start IRC event loop
 	ia.ircConn.Loop()
+
+	// Let cancelled runs finish before returning
+	ia.inflight.Wait()
 	return nil
 }
 
+// handlePrivmsg handles a message or, wrapped as a CTCP ACTION, a /me action
+// sent to a channel the bot is in or to the bot itself
+func (ia *IRCAgent) handlePrivmsg(ctx context.Context, e *irc.Event, message string) {
+	received := time.Now()
+
+	// Drop messages from ignored nicks/hostmasks before doing any work
+	if ia.ignore.Ignored(e.Nick, e.User, e.Host) {
+		slog.Debug("ignoring message", "nick", e.Nick, "user", e.User, "host", e.Host)
+		return
+	}
+
+	sender := e.Nick
+	// Reply where the message came from: the channel, or the sender for
+	// private messages
+	channel := replyTarget(e.Arguments[0], sender, ia.ircConn.GetNick())
+	if !ia.guard.Allowed(channel) {
+		log.Printf("Ignoring message from %s in %s: channel is not in ALLOWED_CHANNELS", sender, channel)
+		return
+	}
+
+	// IRCv3 tags, when the server sends them, give the sender's account and
+	// the server's timestamp
+	tags := parseMessageTags(e.Tags)
+	if tags.Account != "" {
+		ia.accounts.Remember(sender, tags.Account)
+	}
+	if !tags.ServerTime.IsZero() {
+		log.Printf("[%s] [%s] <%s> %s", tags.ServerTime.Format(time.RFC3339), channel, sender, message)
+	} else {
+		log.Printf("[%s] <%s> %s", channel, sender, message)
+	}
+
+	// Skip our own messages, using the current nick in case the server changed it
+	if !strings.EqualFold(e.Nick, ia.ircConn.GetNick()) {
+		// Snapshot the conversation leading up to this message before recording it
+		recent := ia.history.Recent(channel)
+		ia.history.Add(channel, sender, message)
+
+		ia.dispatch(ctx, sender, message, channel, recent, received)
+	}
+}
+
+// dispatch queues a message for processing, unless the same request from the
+// sender is still being answered or the queue is full. It runs on the IRC
+// read loop, so it must never wait.
+func (ia *IRCAgent) dispatch(ctx context.Context, sender, message, channel string, recent []ChannelMessage, received time.Time) {
+	// Commands skip the queue so ,ping answers during a burst. They run off
+	// the read loop since admin checks wait for the WHOIS reply it delivers.
+	if strings.HasPrefix(message, ",") {
+		ia.inflight.Add(1)
+		go func() {
+			defer ia.inflight.Done()
+			ia.handleCommaCommand(sender, message, channel, received)
+		}()
+		return
+	}
+
+	done, ok := ia.pending.Start(channel, sender, message)
+	if !ok {
+		log.Printf("Skipping repeated message from %s in %s while the first is in flight", sender, channel)
+		ia.outbound.Send(channel, sender+": "+alreadyWorkingMessage)
+		return
+	}
+
+	ia.inflight.Add(1)
+	// Keyed by session, so a channel's messages wait their turn without
+	// holding a worker that another channel could use
+	accepted := ia.queue.Submit(ia.sessionID(channel), func() {
+		defer ia.inflight.Done()
+		defer done()
+		ia.processMessage(ctx, sender, message, channel, recent, received)
+	})
+	if !accepted {
+		done()
+		ia.inflight.Done()
+		log.Printf("Message queue full, dropping message from %s in %s", sender, channel)
+		ia.outbound.Send(channel, sender+": "+busyMessage)
+	}
+}
+
+// allowlisted reports whether channel is one the bot is meant to be in: a
+// configured channel, or one named in ALLOWED_CHANNELS
+func (ia *IRCAgent) allowlisted(channel string) bool {
+	for _, c := range ia.channels {
+		if strings.EqualFold(c, channel) {
+			return true
+		}
+	}
+	return ia.guard.Restricted() && ia.guard.Allowed(channel)
+}
+
+// identify sends IDENTIFY to NickServ unless it was already sent on this
+// connection, as when NickServ's prompt follows the IDENTIFY sent on connect
+func (ia *IRCAgent) identify() {
+	if !ia.identified.TryIdentify() {
+		log.Printf("Already identified with NickServ on this connection")
+		return
+	}
+	log.Printf("Identifying with NickServ")
+	ia.ircConn.Privmsg(nickServNick, "IDENTIFY "+ia.nickServPass)
+}
+
+// joinChannels joins the bot's channels once connected and identified
+func (ia *IRCAgent) joinChannels() {
+	for _, channel := range ia.channels {
+		ia.ircConn.Join(channel)
+		log.Printf("Joined channel: %s", channel)
+	}
+}
+
+// sessionID returns the session a channel's conversation is kept in. Channels
+// on named networks get their own sessions so "#go" on two networks stay apart.
+func (ia *IRCAgent) sessionID(channel string) string {
+	if ia.network == "" {
+		return fmt.Sprintf("irc-session-%s", channel)
+	}
+	return fmt.Sprintf("irc-session-%s-%s", ia.network, channel)
+}
+
+// userID returns the runner user ID for channel. It scopes the channel's
+// workspace, overrides and remembered facts, so like sessionID it includes
+// the network on named networks.
+func (ia *IRCAgent) userID(channel string) string {
+	if ia.network == "" {
+		return channel
+	}
+	return ia.network + "/" + channel
+}
+
+// splitUserID returns the network and channel (or nick) in a user ID from
+// userID. Network names can't contain "/" and never look like channels.
+func splitUserID(userID string) (network, channel string) {
+	if network, channel, ok := strings.Cut(userID, "/"); ok && network != "" && !isChannel(network) {
+		return network, channel
+	}
+	return "", userID
+}
+
 // processMessage sends the IRC message to the ADK agent for processing
-func (ia *IRCAgent) processMessage(ctx context.Context, sender, message, channel string) {
+func (ia *IRCAgent) processMessage(ctx context.Context, sender, message, channel string, recent []ChannelMessage, received time.Time) {
 	// Handle comma-prefixed commands
 	if strings.HasPrefix(message, ",") {
-		ia.handleCommaCommand(sender, message, channel)
+		ia.handleCommaCommand(sender, message, channel, received)
 		return
 	}
 
+	// Once the breaker allows the model call, the run reports exactly one
+	// outcome to it: runErr when finished, otherwise a release
+	var (
+		allowed  bool
+		finished bool
+		runErr   error
+	)
+
+	// A panic in the model adapter or the event stream must not take the bot
+	// down. Registered after the commands so ,die can still restart it.
+	defer func() {
+		if r := recover(); r != nil {
+			log.Printf("Recovered from panic processing message from %s in %s: %q\n%s", sender, channel, message, debug.Stack())
+			ia.stats.errors.Add(1)
+			runErr, finished = fmt.Errorf("panic: %v", r), true
+			ia.outbound.Send(channel, userFacingError(runErr))
+		}
+		switch {
+		case !allowed:
+		case finished:
+			ia.breaker.Record(runErr, time.Now())
+		default:
+			ia.breaker.Release()
+		}
+	}()
+
 	// Create a prompt for the agent that includes the channel context
-	prompt := fmt.Sprintf("User %s in channel %s said: %s\n", sender, channel, message)
+	prompt := buildPrompt(sender, channel, message, recent)
+	if ia.memory != nil {
+		prompt = memoryPrompt(sender, ia.memory.Facts(ia.userID(channel), sender)) + prompt
+	}
 
 	log.Printf("Processing message from %s in %s: %s", sender, channel, message)
+	ia.stats.messages.Add(1)
 
 	// Create the content for the agent
 	content := genai.NewContentFromText(prompt, genai.RoleUser)
+	if ia.vision {
+		content.Parts = append(content.Parts, imageURLParts(message)...)
+	}
 
 	// Use a unique session ID for the channel to maintain conversation history
-	sessionID := fmt.Sprintf("irc-session-%s", channel)
+	sessionID := ia.sessionID(channel)
+	userID := ia.userID(channel)
+
+	// One run per session at a time; a second message in the channel waits
+	// for the first reply instead of interleaving with its history
+	unlock := ia.sessions.Lock(sessionID)
+	defer unlock()
+	// Holding the session's lock is what makes it active
+	ia.stats.sessions.Add(1)
+	defer ia.stats.sessions.Add(-1)
 
 	// Ensure session exists - create it if it doesn't
 	_, err := ia.sessionService.Get(ctx, &session.GetRequest{
 		AppName:   "irc_agent",
-		UserID:    channel,
+		UserID:    userID,
 		SessionID: sessionID,
 	})
 	if err != nil {
@@ -277,7 +1085,7 @@ func (ia *IRCAgent) processMessage(ctx context.Context, sender, message, channel
 		log.Printf("Creating new session for channel %s", channel)
 		_, err = ia.sessionService.Create(ctx, &session.CreateRequest{
 			AppName:   "irc_agent",
-			UserID:    channel,
+			UserID:    userID,
 			SessionID: sessionID,
 			State:     make(map[string]any),
 		})
@@ -287,28 +1095,70 @@ func (ia *IRCAgent) processMessage(ctx context.Context, sender, message, channel
 		}
 	}
 
+	// Don't pile more calls onto a provider that keeps failing
+	if !ia.breaker.Allow(time.Now()) {
+		log.Printf("Model circuit breaker %s, not answering %s in %s", ia.breaker.State(), sender, channel)
+		ia.outbound.Send(channel, circuitOpenMessage)
+		return
+	}
+	allowed = true
+
 	// Run the agent with the message
+	// Cancelled if the run is stopped early, e.g. for exceeding the tool-call cap.
+	// It carries the sender so tools like remember know whose facts they are,
+	// and the way back to this connection for tools that post to IRC.
+	runCtx, cancel := context.WithCancel(withReplyRoute(withSender(ctx, sender), ia.outbound, channel))
+	defer cancel()
+
+	// Let the channel know we're working on it if the first reply is slow
+	thinking := startThinkingNotice(ia.thinkingDelay, func() {
+		ia.outbound.Send(channel, ia.thinkingMsg)
+	})
+	defer thinking.Stop()
+
 	runConfig := agent.RunConfig{}
-	events := ia.runner.Run(ctx, channel, sessionID, content, runConfig)
+	events := ia.runner.Run(runCtx, userID, sessionID, content, runConfig)
 
 	// Process the events
+	toolCalls := 0
+	var tracker responseTracker
 	for event, err := range events {
 		if err != nil {
-			log.Printf("Error processing message: %v", err)
-			ia.ircConn.Privmsg(channel, fmt.Sprintf("Error: %v", err))
+			if ctx.Err() != nil {
+				log.Printf("Run for %s in %s cancelled: %v", sender, channel, err)
+				return
+			}
+			ia.stats.errors.Add(1)
+			runErr, finished = err, true
+			// userFacingError logs the raw error; only a sanitized message reaches IRC
+			ia.outbound.Send(channel, userFacingError(err))
 			return
 		}
+		tracker.observe(event)
+
+		// Record token usage reported by the model
+		if event != nil && event.UsageMetadata != nil {
+			llmTokens.WithLabelValues(ia.provider, "prompt").Add(float64(event.UsageMetadata.PromptTokenCount))
+			llmTokens.WithLabelValues(ia.provider, "completion").Add(float64(event.UsageMetadata.CandidatesTokenCount))
+		}
 
 		// Process event content
 		if event != nil && event.Content != nil && len(event.Content.Parts) > 0 {
+			if event.Author != genai.RoleUser {
+				thinking.Stop()
+			}
 			log.Printf("Agent event - Author: %s, InvocationID: %s", event.Author, event.InvocationID)
 
 			for _, part := range event.Content.Parts {
 				// Handle text responses - send directly to IRC
 				if part.Text != "" && event.Author != genai.RoleUser {
 					log.Printf("Agent text response: %s", part.Text)
-					// Split long messages if needed (IRC has message length limits)
-					ia.sendToIRC(part.Text, channel)
+					if ia.dedup.Allow(channel, part.Text, time.Now()) {
+						// Split long messages if needed (IRC has message length limits)
+						ia.sendReply(ctx, part.Text, channel)
+					} else {
+						log.Printf("Suppressing duplicate response to %s: %s", channel, part.Text)
+					}
 				}
 
 				// Handle function calls - send summary to IRC
@@ -316,10 +1166,19 @@ func (ia *IRCAgent) processMessage(ctx context.Context, sender, message, channel
 					toolName := part.FunctionCall.Name
 					log.Printf("Agent calling tool: %s", toolName)
 
+					// Stop before the tool runs once the per-message cap is exceeded
+					toolCalls++
+					if ia.maxToolCalls > 0 && toolCalls > ia.maxToolCalls {
+						log.Printf("Stopping run for %s in %s: exceeded %d tool calls", sender, channel, ia.maxToolCalls)
+						ia.outbound.Send(channel, "Stopping, too many tool calls for one request")
+						finished = true
+						return
+					}
+
 					// Don't send notification for send_irc_message tool to avoid clutter
 					if toolName != "send_irc_message" {
 						summary := fmt.Sprintf("[Using tool: %s]", toolName)
-						ia.ircConn.Privmsg(channel, summary)
+						ia.outbound.Send(channel, summary)
 					}
 				}
 
@@ -327,25 +1186,13 @@ func (ia *IRCAgent) processMessage(ctx context.Context, sender, message, channel
 				if part.FunctionResponse != nil {
 					toolName := part.FunctionResponse.Name
 					log.Printf("Tool %s responded", toolName)
+					if toolName == "execute_typescript" {
+						ia.stats.executions.Add(1)
+					}
 
-					// For non-IRC tools, show completion
+					// For non-IRC tools, summarize the result in one line
 					if toolName != "send_irc_message" {
-						summary := fmt.Sprintf("[Tool %s completed]", toolName)
-						ia.ircConn.Privmsg(channel, summary)
-
-						// For execute_typescript, extract and display URLs if present
-						if toolName == "execute_typescript" && part.FunctionResponse.Response != nil {
-							// Display code URL first
-							if codeURL, ok := part.FunctionResponse.Response["code_short_url"].(string); ok && codeURL != "" {
-								codeMessage := fmt.Sprintf("Full code: %s", codeURL)
-								ia.ircConn.Privmsg(channel, codeMessage)
-							}
-							// Display output URL second
-							if shortURL, ok := part.FunctionResponse.Response["short_url"].(string); ok && shortURL != "" {
-								urlMessage := fmt.Sprintf("Full output: %s", shortURL)
-								ia.ircConn.Privmsg(channel, urlMessage)
-							}
-						}
+						ia.outbound.Send(channel, toolResultSummary(toolName, part.FunctionResponse.Response))
 					}
 				}
 			}
@@ -356,44 +1203,104 @@ func (ia *IRCAgent) processMessage(ctx context.Context, sender, message, channel
 			}
 		}
 	}
+	finished = true
+
+	// Don't leave the user hanging if the model stopped without saying anything
+	if msg, ok := tracker.fallback(); ok {
+		log.Printf("Agent produced no response for %s in %s (finish reason: %s)", sender, channel, tracker.finishReason)
+		ia.outbound.Send(channel, msg)
+	}
+
+	// Make a reply cut off by the token limit look cut off
+	if tracker.truncated() {
+		log.Printf("Agent response to %s in %s hit the max token limit", sender, channel)
+		ia.outbound.Send(channel, truncatedNotice)
+	}
 
 	log.Printf("Agent finished processing message from %s in %s", sender, channel)
 }
 
-// handleCommaCommand processes comma-prefixed commands sent to the agent
-func (ia *IRCAgent) handleCommaCommand(sender, message, sourceChannel string) {
-	// Parse the command and arguments
+// handleCommaCommand processes comma-prefixed commands sent to the agent,
+// looking them up in the command registry. Admin commands are checked here so
+// handlers don't have to. received is when the message arrived.
+func (ia *IRCAgent) handleCommaCommand(sender, message, sourceChannel string, received time.Time) {
 	parts := strings.Fields(message)
 	if len(parts) == 0 {
 		return
 	}
 
 	command := strings.ToLower(parts[0])
-
 	log.Printf("User %s sent comma command: %s", sender, command)
 
-	switch command {
-	case ",die":
-		log.Printf("Die command received from %s - triggering panic to restart process", sender)
-		ia.ircConn.Privmsg(sourceChannel, fmt.Sprintf("%s: Restarting agent...", sender))
-		panic("message died")
+	h, ok := ia.commands.Lookup(command)
+	if !ok {
+		ia.outbound.Send(sourceChannel, fmt.Sprintf("%s: Unknown command: %s. %s", sender, command, ia.commands.Help("")))
+		return
+	}
+	if h.Admin && !ia.isAdmin(sender) {
+		log.Printf("Refusing %s from %s: not a verified admin account", command, sender)
+		ia.outbound.Send(sourceChannel, fmt.Sprintf("%s: Permission denied", sender))
+		return
+	}
+	h.Run(ia, CommandRequest{Sender: sender, Channel: sourceChannel, Args: parts[1:], Received: received})
+}
 
-	default:
-		ia.ircConn.Privmsg(sourceChannel, fmt.Sprintf("%s: Unknown command: %s. Available commands: ,die", sender, command))
+// isAdmin reports whether sender may run admin commands. With ADMIN_ACCOUNTS
+// set, the sender's NickServ account is checked via WHOIS.
+func (ia *IRCAgent) isAdmin(sender string) bool {
+	if len(ia.adminAccounts) == 0 {
+		return true
+	}
+
+	account := ia.accounts.Account(sender, 5*time.Second)
+	if account == "" {
+		return false
+	}
+	for _, admin := range ia.adminAccounts {
+		if strings.EqualFold(admin, account) {
+			return true
+		}
 	}
+	return false
 }
 
 // sendToIRC sends a message to IRC, splitting if necessary for length limits
 func (ia *IRCAgent) sendToIRC(message, channel string) {
-	// IRC message limit is typically around 512 bytes, but we'll use 400 to be safe
-	const maxLen = 400
+	for _, line := range ia.ircLines(message, channel) {
+		ia.outbound.Send(channel, line)
+	}
+}
 
-	if len(message) <= maxLen {
-		ia.ircConn.Privmsg(channel, message)
-		return
+// sendReply sends a model reply to channel like sendToIRC, cutting replies
+// over the response cap short with a link to the full text
+func (ia *IRCAgent) sendReply(ctx context.Context, message, channel string) {
+	for _, line := range ia.replyCap.Apply(ctx, message, ia.ircLines(message, channel)) {
+		ia.outbound.Send(channel, line)
 	}
+}
+
+// ircLines splits message into lines that fit in a message to channel
+func (ia *IRCAgent) ircLines(message, channel string) []string {
+	// Leave room for the prefix the server adds when relaying, within the
+	// line length the server advertised (512 bytes unless it said otherwise)
+	maxLen := ia.isupport.MessageLen(ia.ircConn.GetNick(), ia.ircUser, "PRIVMSG", channel)
+
+	// A newline would end the IRC line early, so each line is sent on its own
+	var lines []string
+	for _, line := range strings.Split(message, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		lines = append(lines, splitLine(line, maxLen)...)
+	}
+	return lines
+}
 
-	// Split long messages into chunks
+// splitLine splits line into chunks of at most maxLen bytes, breaking at a
+// space near the limit when there is one
+func splitLine(message string, maxLen int) []string {
+	var chunks []string
 	for len(message) > 0 {
 		end := maxLen
 		if end > len(message) {
@@ -414,10 +1321,11 @@ func (ia *IRCAgent) sendToIRC(message, channel string) {
 			}
 		}
 
-		ia.ircConn.Privmsg(channel, message[:end])
+		chunks = append(chunks, message[:end])
 		message = message[end:]
 		if len(message) > 0 && message[0] == ' ' {
 			message = message[1:] // Skip leading space
 		}
 	}
+	return chunks
 }
@@ -0,0 +1,501 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	irc "github.com/thoj/go-ircevent"
+	"google.golang.org/adk/agent"
+	"google.golang.org/adk/memory"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// fakeRunner yields the events produced by events instead of running a model
+type fakeRunner struct {
+	events func(yield func(*session.Event, error) bool)
+}
+
+func (r fakeRunner) Run(ctx context.Context, userID, sessionID string, msg *genai.Content, cfg agent.RunConfig) iter.Seq2[*session.Event, error] {
+	return r.events
+}
+
+// runnerFunc adapts a function to an agentRunner, for tests that need the
+// run's context
+type runnerFunc func(ctx context.Context, userID, sessionID string) iter.Seq2[*session.Event, error]
+
+func (f runnerFunc) Run(ctx context.Context, userID, sessionID string, msg *genai.Content, cfg agent.RunConfig) iter.Seq2[*session.Event, error] {
+	return f(ctx, userID, sessionID)
+}
+
+// newTestAgent returns an IRCAgent in #agent driven by events, whose IRC
+// output is collected by the returned sink instead of being sent
+func newTestAgent(events func(yield func(*session.Event, error) bool)) (*IRCAgent, *fakeSink) {
+	sink := &fakeSink{}
+	ia := &IRCAgent{
+		runner:         fakeRunner{events: events},
+		sessionService: session.InMemoryService(),
+		sessions:       NewSessionLocks(),
+		breaker:        NewCircuitBreaker(defaultBreakerThreshold, defaultBreakerCooldown),
+		queue:          NewMessageQueue(defaultMessageWorkers, defaultMessageQueueSize),
+		pending:        NewInflightRequests(),
+		replyCap:       NewResponseCap(0, nil, nil),
+		joined:         NewJoinedChannels(),
+		commands:       builtinCommands(),
+		ircConn:        irc.IRC("agent", "agent"),
+		ircUser:        "agent",
+		isupport:       NewISupport(),
+		dedup:          NewMessageDeduper(0),
+		stats:          NewAgentStats(time.Now()),
+		outbound:       NewOutboundBuffer(10, sink),
+	}
+	ia.outbound.Connected()
+	ia.outbound.Joined("#agent")
+	return ia, sink
+}
+
+// textEvent is a model event carrying a text reply
+func textEvent(text string) *session.Event {
+	return &session.Event{
+		Author:      "irc_agent",
+		LLMResponse: model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleModel)},
+	}
+}
+
+func TestProcessMessageRecoversFromPanickingEvents(t *testing.T) {
+	ia, sink := newTestAgent(func(yield func(*session.Event, error) bool) {
+		if !yield(textEvent("partial answer"), nil) {
+			return
+		}
+		panic("malformed event")
+	})
+
+	// Must return normally instead of crashing the test binary
+	ia.processMessage(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	sent := sink.Messages()
+
+	if len(sent) != 2 || sent[0] != "partial answer" {
+		t.Fatalf("Expected the partial answer and an error notice, got %q", sent)
+	}
+	if strings.Contains(sent[1], "malformed event") {
+		t.Errorf("Expected a generic error message, got %q", sent[1])
+	}
+	if ia.stats.errors.Load() != 1 {
+		t.Errorf("Expected the panic to be counted as an error, got %d", ia.stats.errors.Load())
+	}
+}
+
+func TestProcessMessageCircuitBreaker(t *testing.T) {
+	calls := 0
+	ia, sink := newTestAgent(func(yield func(*session.Event, error) bool) {
+		calls++
+		yield(nil, errors.New("provider unavailable"))
+	})
+	ia.breaker = NewCircuitBreaker(2, time.Hour)
+
+	for i := 0; i < 3; i++ {
+		ia.processMessage(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	}
+	if calls != 2 {
+		t.Errorf("Expected the model to be called twice before the circuit opened, got %d", calls)
+	}
+	sent := sink.Messages()
+	if len(sent) != 3 || sent[2] != circuitOpenMessage {
+		t.Errorf("Expected the unavailable notice once the circuit opened, got %q", sent)
+	}
+}
+
+func TestProcessMessageBreakerTrialPanic(t *testing.T) {
+	fail := true
+	ia, _ := newTestAgent(func(yield func(*session.Event, error) bool) {
+		if fail {
+			yield(nil, errors.New("provider unavailable"))
+			return
+		}
+		panic("malformed event")
+	})
+	ia.breaker = NewCircuitBreaker(1, time.Millisecond)
+
+	ia.processMessage(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	time.Sleep(2 * time.Millisecond)
+
+	// A trial that panics is a failure, not a success
+	fail = false
+	ia.processMessage(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	if got := ia.breaker.State(); got != circuitOpen {
+		t.Errorf("Expected a panicking trial to reopen the circuit, got %s", got)
+	}
+}
+
+func TestProcessMessageCancelledRunReleasesBreaker(t *testing.T) {
+	ia, _ := newTestAgent(func(yield func(*session.Event, error) bool) {
+		yield(nil, context.Canceled)
+	})
+	ia.breaker = NewCircuitBreaker(1, time.Millisecond)
+	ia.breaker.Record(errors.New("provider unavailable"), time.Now())
+	time.Sleep(2 * time.Millisecond)
+
+	// The cancelled trial counts neither way, so the next message is the trial
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	ia.processMessage(ctx, "alice", "agent: hi", "#agent", nil, time.Now())
+	if got := ia.breaker.State(); got != circuitOpen {
+		t.Errorf("Expected the circuit to stay open after a cancelled trial, got %s", got)
+	}
+	if !ia.breaker.Allow(time.Now()) {
+		t.Error("Expected the next call to get the trial")
+	}
+}
+
+func TestDispatchSkipsRepeatWhileInFlight(t *testing.T) {
+	release := make(chan struct{})
+	var runs atomic.Int32
+	ia, sink := newTestAgent(func(yield func(*session.Event, error) bool) {
+		runs.Add(1)
+		<-release
+		yield(textEvent("answer"), nil)
+	})
+
+	ia.dispatch(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	ia.dispatch(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	close(release)
+	ia.inflight.Wait()
+
+	if got := runs.Load(); got != 1 {
+		t.Errorf("Expected one run for the repeated message, got %d", got)
+	}
+	sent := sink.Messages()
+	if len(sent) != 2 || sent[0] != "alice: "+alreadyWorkingMessage || sent[1] != "answer" {
+		t.Errorf("Expected a note about the repeat and one answer, got %q", sent)
+	}
+
+	// Asking again after the answer runs it again
+	ia.dispatch(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	ia.inflight.Wait()
+	if got := runs.Load(); got != 2 {
+		t.Errorf("Expected the message to run again once the first finished, got %d runs", got)
+	}
+}
+
+func TestDispatchAnswersCommandsWhileQueueIsFull(t *testing.T) {
+	release := make(chan struct{})
+	ia, sink := newTestAgent(func(yield func(*session.Event, error) bool) {
+		<-release
+		yield(textEvent("answer"), nil)
+	})
+	ia.queue = NewMessageQueue(1, 0)
+
+	ia.dispatch(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	ia.dispatch(context.Background(), "bob", ",ping", "#agent", nil, time.Now())
+	deadline := time.Now().Add(time.Second)
+	for len(sink.Messages()) == 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	ia.inflight.Wait()
+
+	sent := sink.Messages()
+	if len(sent) != 2 || !strings.HasPrefix(sent[0], "bob: pong") || sent[1] != "answer" {
+		t.Errorf("Expected the pong before the answer, got %q", sent)
+	}
+}
+
+func TestProcessMessageDieStillPanics(t *testing.T) {
+	ia, _ := newTestAgent(nil)
+
+	defer func() {
+		if recover() == nil {
+			t.Error("Expected ,die to panic so the process restarts")
+		}
+	}()
+	ia.processMessage(context.Background(), "alice", ",die", "#agent", nil, time.Now())
+}
+
+func TestProcessMessageSerializesRunsPerChannel(t *testing.T) {
+	var active, maxActive atomic.Int32
+	ia, sink := newTestAgent(func(yield func(*session.Event, error) bool) {
+		n := active.Add(1)
+		defer active.Add(-1)
+		for {
+			m := maxActive.Load()
+			if n <= m || maxActive.CompareAndSwap(m, n) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		yield(textEvent("done"), nil)
+	})
+
+	var wg sync.WaitGroup
+	for _, msg := range []string{"agent: one", "agent: two"} {
+		wg.Add(1)
+		go func(msg string) {
+			defer wg.Done()
+			ia.processMessage(context.Background(), "alice", msg, "#agent", nil, time.Now())
+		}(msg)
+	}
+	wg.Wait()
+	sent := sink.Messages()
+
+	if maxActive.Load() != 1 {
+		t.Errorf("Expected one run at a time on #agent, got %d concurrent", maxActive.Load())
+	}
+	if len(sent) != 2 {
+		t.Errorf("Expected both messages to be answered, got %q", sent)
+	}
+	if ia.sessions.Len() != 0 {
+		t.Errorf("Expected session locks to be released, got %d", ia.sessions.Len())
+	}
+}
+
+func TestProcessMessageCountsActiveSessions(t *testing.T) {
+	running := make(chan struct{})
+	release := make(chan struct{})
+	ia, _ := newTestAgent(func(yield func(*session.Event, error) bool) {
+		running <- struct{}{}
+		<-release
+		yield(textEvent("done"), nil)
+	})
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ia.processMessage(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+	}()
+	<-running
+	if got := ia.stats.sessions.Load(); got != 1 {
+		t.Errorf("Expected 1 active session during the run, got %d", got)
+	}
+
+	close(release)
+	<-done
+	if got := ia.stats.sessions.Load(); got != 0 {
+		t.Errorf("Expected no active sessions once the run finished, got %d", got)
+	}
+}
+
+func TestForgetCommandClearsOnlySender(t *testing.T) {
+	ia, sink := newTestAgent(nil)
+	ia.memory = NewMemoryKeeper(memory.InMemoryService(), 0)
+	ctx := context.Background()
+	ia.memory.Remember(ctx, "#agent", "alice", "likes tea")
+	ia.memory.Remember(ctx, "#agent", "bob", "likes coffee")
+
+	ia.processMessage(ctx, "alice", ",forget", "#agent", nil, time.Now())
+	sent := sink.Messages()
+
+	if len(sent) != 1 || !strings.Contains(sent[0], "Forgot 1") {
+		t.Errorf("Expected a confirmation, got %q", sent)
+	}
+	if facts := ia.memory.Facts("#agent", "alice"); len(facts) != 0 {
+		t.Errorf("Expected alice's facts to be cleared, got %q", facts)
+	}
+	if facts := ia.memory.Facts("#agent", "bob"); len(facts) != 1 {
+		t.Errorf("Expected bob's facts to be kept, got %q", facts)
+	}
+}
+
+func TestChannelsCommand(t *testing.T) {
+	ia, sink := newTestAgent(nil)
+
+	ia.processMessage(context.Background(), "alice", ",channels", "#agent", nil, time.Now())
+	if sent := sink.Messages(); len(sent) != 1 || sent[0] != "alice: Not in any channels" {
+		t.Errorf("Expected no channels, got %q", sent)
+	}
+
+	ia.joined.Add("#agent")
+	ia.joined.Add("#go")
+	ia.processMessage(context.Background(), "alice", ",channels", "#agent", nil, time.Now())
+	if sent := sink.Messages(); len(sent) != 2 || sent[1] != "alice: In 2 channels: #agent, #go" {
+		t.Errorf("Expected the joined channels, got %q", sent)
+	}
+}
+
+func TestSendToIRCSplitsLongMessages(t *testing.T) {
+	ia, sink := newTestAgent(nil)
+	message := strings.Repeat("word ", 200)
+
+	ia.sendToIRC(message, "#agent")
+
+	sent := sink.Sent()
+	if len(sent) < 2 {
+		t.Fatalf("Expected the message to be split, got %d parts", len(sent))
+	}
+	maxLen := ia.isupport.MessageLen("agent", "agent", "PRIVMSG", "#agent")
+	var rejoined []string
+	for _, m := range sent {
+		if m.target != "#agent" {
+			t.Errorf("Expected every part to go to #agent, got %s", m.target)
+		}
+		if len(m.message) > maxLen {
+			t.Errorf("Expected parts of at most %d bytes, got %d", maxLen, len(m.message))
+		}
+		rejoined = append(rejoined, strings.Fields(m.message)...)
+	}
+	if len(rejoined) != 200 {
+		t.Errorf("Expected the words to survive splitting intact, got %d", len(rejoined))
+	}
+}
+
+func TestSendToIRCSplitsLines(t *testing.T) {
+	ia, sink := newTestAgent(nil)
+
+	ia.sendToIRC("first\r\n\nsecond\nthird", "#agent")
+
+	sent := sink.Messages()
+	if len(sent) != 3 || sent[0] != "first" || sent[1] != "second" || sent[2] != "third" {
+		t.Errorf("Expected one message per non-empty line, got %q", sent)
+	}
+}
+
+func TestSendReplyCapsLines(t *testing.T) {
+	ia, sink := newTestAgent(nil)
+	ia.replyCap = NewResponseCap(3, nil, nil)
+
+	ia.sendReply(context.Background(), strings.Join(numberedLines(6), "\n"), "#agent")
+
+	sent := sink.Messages()
+	if len(sent) != 3 || sent[2] != "... 4 more lines not shown" {
+		t.Errorf("Expected the reply cut to 3 lines, got %q", sent)
+	}
+}
+
+func TestCommaCommandsUseSink(t *testing.T) {
+	ia, sink := newTestAgent(nil)
+	ia.processMessage(context.Background(), "alice", ",ping", "#agent", nil, time.Now())
+	ia.processMessage(context.Background(), "alice", ",nope", "#agent", nil, time.Now())
+
+	sent := sink.Messages()
+	if len(sent) != 2 {
+		t.Fatalf("Expected two replies, got %q", sent)
+	}
+	if !strings.HasPrefix(sent[0], "alice: pong") {
+		t.Errorf("Expected a pong, got %q", sent[0])
+	}
+	if !strings.Contains(sent[1], "Unknown command: ,nope") {
+		t.Errorf("Expected an unknown command reply, got %q", sent[1])
+	}
+}
+
+func TestCommaCommandDispatch(t *testing.T) {
+	ia, sink := newTestAgent(nil)
+	var got CommandRequest
+	ia.commands = NewCommandRegistry()
+	ia.commands.Register(CommandHandler{Name: ",echo", Usage: "repeat words", Run: func(ia *IRCAgent, req CommandRequest) {
+		got = req
+		ia.reply(req, "%s", strings.Join(req.Args, " "))
+	}})
+	ia.commands.Register(CommandHandler{Name: ",secret", Usage: "admins only", Admin: true, Run: func(ia *IRCAgent, req CommandRequest) {
+		t.Error("Expected the admin command not to run")
+	}})
+	// A WHOIS that finds no account, so the sender isn't an admin
+	ia.adminAccounts = []string{"root"}
+	var accounts *AccountVerifier
+	accounts = NewAccountVerifier(func(nick string) { accounts.HandleEndOfWhois([]string{"agent", nick}) }, time.Minute)
+	ia.accounts = accounts
+
+	ia.processMessage(context.Background(), "alice", ",ECHO hello  there", "#agent", nil, time.Now())
+	ia.processMessage(context.Background(), "alice", ",secret", "#agent", nil, time.Now())
+	ia.processMessage(context.Background(), "alice", ",help echo", "#agent", nil, time.Now())
+
+	sent := sink.Messages()
+	if got.Sender != "alice" || got.Channel != "#agent" || strings.Join(got.Args, "|") != "hello|there" {
+		t.Errorf("Expected the parsed request, got %+v", got)
+	}
+	if len(sent) != 3 {
+		t.Fatalf("Expected three replies, got %q", sent)
+	}
+	if sent[0] != "alice: hello there" {
+		t.Errorf("Expected the echo, got %q", sent[0])
+	}
+	if sent[1] != "alice: Permission denied" {
+		t.Errorf("Expected the admin check to refuse, got %q", sent[1])
+	}
+	if !strings.HasPrefix(sent[2], "alice: Unknown command: ,help") {
+		t.Errorf("Expected ,help to be unknown in a registry without it, got %q", sent[2])
+	}
+}
+
+func TestHelpCommand(t *testing.T) {
+	ia, sink := newTestAgent(nil)
+
+	ia.processMessage(context.Background(), "alice", ",help", "#agent", nil, time.Now())
+	ia.processMessage(context.Background(), "alice", ",help join", "#agent", nil, time.Now())
+
+	sent := sink.Messages()
+	if len(sent) != 2 || !strings.Contains(sent[0], ",ping") || !strings.Contains(sent[0], ",users") {
+		t.Fatalf("Expected a list of commands, got %q", sent)
+	}
+	if sent[1] != "alice: ,join: "+joinUsage+" (admin only)" {
+		t.Errorf("Expected the usage of ,join, got %q", sent[1])
+	}
+}
+
+func TestProcessMessageReportsRunErrors(t *testing.T) {
+	ia, sink := newTestAgent(func(yield func(*session.Event, error) bool) {
+		yield(nil, errors.New("upstream exploded: secret details"))
+	})
+
+	ia.processMessage(context.Background(), "alice", "agent: hi", "#agent", nil, time.Now())
+
+	sent := sink.Messages()
+	if len(sent) != 1 || !strings.HasPrefix(sent[0], "Sorry, something went wrong") {
+		t.Fatalf("Expected a generic error reply, got %q", sent)
+	}
+	if strings.Contains(sent[0], "secret") {
+		t.Errorf("Expected the raw error to stay out of IRC, got %q", sent[0])
+	}
+	if ia.stats.errors.Load() != 1 {
+		t.Errorf("Expected the error to be counted, got %d", ia.stats.errors.Load())
+	}
+}
+
+func TestExecutionNoticesUseTheOriginatingNetwork(t *testing.T) {
+	executor := newFakeExecutor(t, &fakeCommandRunner{stdout: "ok"}, nil)
+	executor.Notices = true
+	// Stands in for the model calling execute_typescript during the run
+	runner := runnerFunc(func(ctx context.Context, userID, sessionID string) iter.Seq2[*session.Event, error] {
+		executor.Execute(fakeToolContext{channel: userID, ctx: ctx}, ExecuteTypeScriptParams{Code: "print()"})
+		return func(yield func(*session.Event, error) bool) {
+			yield(textEvent("done"), nil)
+		}
+	})
+
+	libera, liberaSink := newTestAgent(nil)
+	libera.network, libera.runner = "libera", runner
+	oftc, oftcSink := newTestAgent(nil)
+	oftc.network, oftc.runner = "oftc", runner
+	oftc.outbound.Joined("#invited")
+
+	// A private message and a channel only joined on the second network
+	oftc.processMessage(context.Background(), "alice", "run it", "alice", nil, time.Now())
+	oftc.processMessage(context.Background(), "bob", "agent: run it", "#invited", nil, time.Now())
+
+	if sent := liberaSink.Sent(); len(sent) != 0 {
+		t.Errorf("Expected nothing on the first network, got %v", sent)
+	}
+	want := []sentMessage{
+		{target: "alice", message: "Executing code..."},
+		{target: "alice", message: "Code executed successfully"},
+		{target: "alice", message: "done"},
+		{target: "#invited", message: "Executing code..."},
+		{target: "#invited", message: "Code executed successfully"},
+		{target: "#invited", message: "done"},
+	}
+	sent := oftcSink.Sent()
+	if len(sent) != len(want) {
+		t.Fatalf("Expected %v on the second network, got %v", want, sent)
+	}
+	for i := range want {
+		if sent[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], sent[i])
+		}
+	}
+}
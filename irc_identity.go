@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// defaultNick is the bot's nick, user and real name when none are configured
+const defaultNick = "agent"
+
+// IRCIdentity is the nick, username and real name the bot registers with
+type IRCIdentity struct {
+	Nick     string
+	User     string
+	RealName string
+}
+
+// loadIRCIdentity reads IRC_NICK, IRC_USER and IRC_REALNAME. The user and real
+// name default to the nick, which defaults to "agent".
+func loadIRCIdentity() (IRCIdentity, error) {
+	id, err := resolveIRCIdentity(os.Getenv("IRC_NICK"), os.Getenv("IRC_USER"), os.Getenv("IRC_REALNAME"))
+	if err != nil {
+		return IRCIdentity{}, fmt.Errorf("invalid IRC identity: %w", err)
+	}
+	return id, nil
+}
+
+// resolveIRCIdentity fills in defaults for an identity and validates it
+func resolveIRCIdentity(nick, user, realName string) (IRCIdentity, error) {
+	id := IRCIdentity{
+		Nick:     strings.TrimSpace(nick),
+		User:     strings.TrimSpace(user),
+		RealName: strings.TrimSpace(realName),
+	}
+
+	if id.Nick == "" {
+		id.Nick = defaultNick
+	}
+	// Nicks can't contain spaces or start with a channel prefix or digit
+	if strings.ContainsAny(id.Nick, " ,*?!@") || strings.ContainsAny(id.Nick[:1], "#&:0123456789-") {
+		return IRCIdentity{}, fmt.Errorf("nick %q is not a valid nick", id.Nick)
+	}
+
+	if id.User == "" {
+		id.User = id.Nick
+	}
+	if strings.ContainsAny(id.User, " @") {
+		return IRCIdentity{}, fmt.Errorf("username %q is not a valid username", id.User)
+	}
+
+	if id.RealName == "" {
+		id.RealName = id.Nick
+	}
+	return id, nil
+}
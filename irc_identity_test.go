@@ -0,0 +1,42 @@
+package main
+
+import "testing"
+
+func TestLoadIRCIdentityDefaults(t *testing.T) {
+	t.Setenv("IRC_NICK", "")
+	t.Setenv("IRC_USER", "")
+	t.Setenv("IRC_REALNAME", "")
+
+	id, err := loadIRCIdentity()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := IRCIdentity{Nick: "agent", User: "agent", RealName: "agent"}
+	if id != expected {
+		t.Errorf("Expected %+v, got %+v", expected, id)
+	}
+}
+
+func TestLoadIRCIdentityFromEnv(t *testing.T) {
+	t.Setenv("IRC_NICK", "helper")
+	t.Setenv("IRC_USER", "")
+	t.Setenv("IRC_REALNAME", "Helpful Bot")
+
+	id, err := loadIRCIdentity()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	expected := IRCIdentity{Nick: "helper", User: "helper", RealName: "Helpful Bot"}
+	if id != expected {
+		t.Errorf("Expected %+v, got %+v", expected, id)
+	}
+}
+
+func TestLoadIRCIdentityInvalid(t *testing.T) {
+	for _, nick := range []string{"two words", "#channel", "1bot"} {
+		t.Setenv("IRC_NICK", nick)
+		if _, err := loadIRCIdentity(); err == nil {
+			t.Errorf("Expected error for nick %q", nick)
+		}
+	}
+}
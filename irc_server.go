@@ -0,0 +1,76 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Default IRC ports for plaintext and TLS connections
+const (
+	defaultIRCPort    = "6667"
+	defaultIRCTLSPort = "6697"
+)
+
+// parseIRCServer normalizes the SERVER setting into a host:port address for
+// irc.Connection.Connect. It accepts host, host:port, IPv6 literals with or
+// without brackets ([::1]:6667, ::1), and irc:// or ircs:// URLs. The ircs
+// scheme enables TLS, and a missing port defaults based on whether TLS is used.
+func parseIRCServer(raw string) (addr string, useTLS bool, err error) {
+	hostport := strings.TrimSpace(raw)
+
+	if strings.Contains(hostport, "://") {
+		u, err := url.Parse(hostport)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid IRC server URL %q: %w", raw, err)
+		}
+		switch u.Scheme {
+		case "irc":
+		case "ircs":
+			useTLS = true
+		default:
+			return "", false, fmt.Errorf("unsupported IRC server scheme %q (use irc:// or ircs://)", u.Scheme)
+		}
+		if (u.Path != "" && u.Path != "/") || u.RawQuery != "" || u.Fragment != "" || u.User != nil {
+			return "", false, fmt.Errorf("IRC server URL %q must only contain a scheme, host and port", raw)
+		}
+		hostport = u.Host
+	}
+
+	host, port := hostport, ""
+	switch {
+	case strings.HasPrefix(hostport, "[") && strings.HasSuffix(hostport, "]"):
+		// Bracketed IPv6 literal without a port
+		host = strings.Trim(hostport, "[]")
+	case strings.Count(hostport, ":") > 1 && !strings.HasPrefix(hostport, "["):
+		// Bare IPv6 literal; it cannot carry a port without brackets
+	case strings.Contains(hostport, ":"):
+		host, port, err = net.SplitHostPort(hostport)
+		if err != nil {
+			return "", false, fmt.Errorf("invalid IRC server address %q: %w", raw, err)
+		}
+	}
+
+	if host == "" {
+		return "", false, fmt.Errorf("IRC server address %q is missing a host", raw)
+	}
+	if strings.ContainsAny(host, " \t/") {
+		return "", false, fmt.Errorf("IRC server address %q has an invalid host %q", raw, host)
+	}
+
+	if port != "" {
+		n, err := strconv.Atoi(port)
+		if err != nil || n < 1 || n > 65535 {
+			return "", false, fmt.Errorf("IRC server address %q has an invalid port %q (must be 1-65535)", raw, port)
+		}
+	} else {
+		port = defaultIRCPort
+		if useTLS {
+			port = defaultIRCTLSPort
+		}
+	}
+
+	return net.JoinHostPort(host, port), useTLS, nil
+}
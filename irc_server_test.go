@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func TestParseIRCServer(t *testing.T) {
+	tests := []struct {
+		raw     string
+		addr    string
+		useTLS  bool
+		wantErr bool
+	}{
+		{raw: "irc.example.com", addr: "irc.example.com:6667"},
+		{raw: "irc.example.com:7000", addr: "irc.example.com:7000"},
+		{raw: "  irc.example.com:6667 ", addr: "irc.example.com:6667"},
+		{raw: "[::1]:6667", addr: "[::1]:6667"},
+		{raw: "[::1]", addr: "[::1]:6667"},
+		{raw: "::1", addr: "[::1]:6667"},
+		{raw: "irc://irc.example.com", addr: "irc.example.com:6667"},
+		{raw: "ircs://irc.example.com", addr: "irc.example.com:6697", useTLS: true},
+		{raw: "ircs://irc.example.com:7070", addr: "irc.example.com:7070", useTLS: true},
+		{raw: "ircs://[2001:db8::1]", addr: "[2001:db8::1]:6697", useTLS: true},
+		{raw: "http://irc.example.com", wantErr: true},
+		{raw: "", wantErr: true},
+		{raw: ":6667", wantErr: true},
+		{raw: "irc.example.com:abc", wantErr: true},
+		{raw: "irc.example.com:70000", wantErr: true},
+		{raw: "irc.example.com:0", wantErr: true},
+		{raw: "ircs://irc.example.com/#channel", wantErr: true},
+		{raw: "irc.example.com/path", wantErr: true},
+		{raw: "irc example.com", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		addr, useTLS, err := parseIRCServer(tt.raw)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parseIRCServer(%q): expected error, got %s", tt.raw, addr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parseIRCServer(%q): unexpected error: %v", tt.raw, err)
+			continue
+		}
+		if addr != tt.addr || useTLS != tt.useTLS {
+			t.Errorf("parseIRCServer(%q) = (%s, %v), expected (%s, %v)", tt.raw, addr, useTLS, tt.addr, tt.useTLS)
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// defaultLineLen is the IRC line limit from RFC 1459, including the trailing CRLF
+const defaultLineLen = 512
+
+// maxHostLen is reserved for our own hostname in the prefix the server adds to
+// relayed messages, since we can't reliably know it
+const maxHostLen = 63
+
+// minMessageLen keeps split chunks usable even with odd server limits
+const minMessageLen = 100
+
+// ISupport holds the limits a server advertises in its 005 RPL_ISUPPORT
+// replies. Only the line length is kept: the bot sends one target per
+// PRIVMSG and never sets modes, so MODES and TARGMAX don't affect it.
+type ISupport struct {
+	mu      sync.RWMutex
+	lineLen int
+}
+
+// NewISupport creates an empty set of server limits
+func NewISupport() *ISupport {
+	s := &ISupport{}
+	s.Reset()
+	return s
+}
+
+// Reset forgets all advertised limits, e.g. when a new connection starts
+func (s *ISupport) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lineLen = defaultLineLen
+}
+
+// Update applies the tokens from a 005 reply: <me> TOKEN[=value]... :are supported
+func (s *ISupport) Update(args []string) {
+	tokens := parseISupport(args)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if value, ok := tokens["LINELEN"]; ok {
+		// "-LINELEN" withdraws it, restoring the default
+		s.lineLen = defaultLineLen
+		if value != nil {
+			if n, err := strconv.Atoi(*value); err == nil && n > 0 {
+				s.lineLen = n
+			}
+		}
+	}
+}
+
+// LineLen returns the maximum line length, including CRLF
+func (s *ISupport) LineLen() int {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.lineLen
+}
+
+// MessageLen returns how many bytes of text fit in one PRIVMSG or NOTICE to
+// target, after the prefix the server adds when relaying it
+func (s *ISupport) MessageLen(nick, user, command, target string) int {
+	// :nick!user@host COMMAND target :text\r\n
+	overhead := len(":"+nick+"!"+user+"@") + maxHostLen + len(" "+command+" "+target+" :") + len("\r\n")
+	return max(s.LineLen()-overhead, minMessageLen)
+}
+
+// parseISupport returns the tokens in a 005 reply. Withdrawn tokens ("-KEY")
+// map to nil and flags without a value to "".
+func parseISupport(args []string) map[string]*string {
+	tokens := make(map[string]*string)
+	// The first argument is our nick and the last the human-readable text
+	if len(args) < 3 {
+		return tokens
+	}
+
+	for _, token := range args[1 : len(args)-1] {
+		if token == "" {
+			continue
+		}
+		if key, ok := strings.CutPrefix(token, "-"); ok {
+			tokens[strings.ToUpper(key)] = nil
+			continue
+		}
+		key, value, _ := strings.Cut(token, "=")
+		value = unescapeISupport(value)
+		tokens[strings.ToUpper(key)] = &value
+	}
+	return tokens
+}
+
+// unescapeISupport decodes the \xHH escapes allowed in ISUPPORT values
+func unescapeISupport(value string) string {
+	if !strings.Contains(value, `\x`) {
+		return value
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && i+3 < len(value) && value[i+1] == 'x' {
+			if n, err := strconv.ParseUint(value[i+2:i+4], 16, 8); err == nil {
+				b.WriteByte(byte(n))
+				i += 3
+				continue
+			}
+		}
+		b.WriteByte(value[i])
+	}
+	return b.String()
+}
@@ -0,0 +1,82 @@
+package main
+
+import "testing"
+
+func TestParseISupport(t *testing.T) {
+	args := []string{"agent", "MODES=4", "TARGMAX=PRIVMSG:4,NOTICE:3,JOIN:", "LINELEN=1024", "EXCEPTS", "NETWORK=Example\\x20Net", "-KNOCK", "are supported by this server"}
+	tokens := parseISupport(args)
+
+	expected := map[string]string{
+		"MODES":   "4",
+		"TARGMAX": "PRIVMSG:4,NOTICE:3,JOIN:",
+		"LINELEN": "1024",
+		"EXCEPTS": "",
+		"NETWORK": "Example Net",
+	}
+	for key, want := range expected {
+		got, ok := tokens[key]
+		if !ok || got == nil {
+			t.Errorf("Expected token %s, got none", key)
+			continue
+		}
+		if *got != want {
+			t.Errorf("%s: Expected %q, got %q", key, want, *got)
+		}
+	}
+	if value, ok := tokens["KNOCK"]; !ok || value != nil {
+		t.Errorf("Expected KNOCK to be withdrawn, got %v", value)
+	}
+	if _, ok := tokens["ARE SUPPORTED BY THIS SERVER"]; ok {
+		t.Error("Expected the trailing text to be skipped")
+	}
+}
+
+func TestISupportUpdate(t *testing.T) {
+	s := NewISupport()
+	if s.LineLen() != defaultLineLen {
+		t.Fatalf("Expected the RFC default line length, got %d", s.LineLen())
+	}
+
+	// Servers usually split their tokens across several 005 replies
+	s.Update([]string{"agent", "MODES=6", "CHANTYPES=#&", "are supported by this server"})
+	s.Update([]string{"agent", "TARGMAX=PRIVMSG:4,JOIN:", "LINELEN=1024", "are supported by this server"})
+	if s.LineLen() != 1024 {
+		t.Errorf("Expected line length 1024, got %d", s.LineLen())
+	}
+
+	s.Update([]string{"agent", "LINELEN=bogus", "are supported by this server"})
+	if s.LineLen() != defaultLineLen {
+		t.Errorf("Expected an invalid line length to be ignored, got %d", s.LineLen())
+	}
+
+	s.Update([]string{"agent", "LINELEN=2048", "are supported by this server"})
+	s.Update([]string{"agent", "-LINELEN", "are supported by this server"})
+	if s.LineLen() != defaultLineLen {
+		t.Errorf("Expected a withdrawn line length to restore the default, got %d", s.LineLen())
+	}
+
+	s.Update([]string{"agent", "LINELEN=2048", "are supported by this server"})
+	s.Reset()
+	if s.LineLen() != defaultLineLen {
+		t.Error("Expected Reset to restore the default")
+	}
+}
+
+func TestISupportMessageLen(t *testing.T) {
+	s := NewISupport()
+
+	// 512 - len(":agent!agent@") - 63 - len(" PRIVMSG #agent :") - len("\r\n")
+	if got := s.MessageLen("agent", "agent", "PRIVMSG", "#agent"); got != 512-13-63-17-2 {
+		t.Errorf("Expected %d, got %d", 512-13-63-17-2, got)
+	}
+
+	s.Update([]string{"agent", "LINELEN=2048", "are supported by this server"})
+	if got := s.MessageLen("agent", "agent", "PRIVMSG", "#agent"); got != 2048-13-63-17-2 {
+		t.Errorf("Expected the advertised line length to be used, got %d", got)
+	}
+
+	s.Update([]string{"agent", "LINELEN=50", "are supported by this server"})
+	if got := s.MessageLen("agent", "agent", "PRIVMSG", "#agent"); got != minMessageLen {
+		t.Errorf("Expected the minimum message length, got %d", got)
+	}
+}
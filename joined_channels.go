@@ -0,0 +1,56 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// JoinedChannels is the set of channels the bot is currently in. It follows
+// the server's JOIN, PART and KICK messages for the bot rather than the
+// commands it sends, since a join can be refused.
+type JoinedChannels struct {
+	mu       sync.RWMutex
+	channels map[string]string // maps lowercased channel -> channel as joined
+}
+
+// NewJoinedChannels creates an empty set
+func NewJoinedChannels() *JoinedChannels {
+	return &JoinedChannels{
+		channels: make(map[string]string),
+	}
+}
+
+// Add records that the bot joined channel
+func (j *JoinedChannels) Add(channel string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.channels[strings.ToLower(channel)] = channel
+}
+
+// Remove records that the bot left or was kicked from channel
+func (j *JoinedChannels) Remove(channel string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	delete(j.channels, strings.ToLower(channel))
+}
+
+// Reset forgets every channel, as on a new connection
+func (j *JoinedChannels) Reset() {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.channels = make(map[string]string)
+}
+
+// List returns the joined channels, sorted
+func (j *JoinedChannels) List() []string {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	channels := make([]string, 0, len(j.channels))
+	for _, channel := range j.channels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	return channels
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJoinedChannels(t *testing.T) {
+	j := NewJoinedChannels()
+	j.Add("#Go")
+	j.Add("#agent")
+	j.Add("#go") // rejoining under another case doesn't duplicate
+
+	if got := j.List(); !reflect.DeepEqual(got, []string{"#agent", "#go"}) {
+		t.Errorf("Expected [#agent #go], got %v", got)
+	}
+
+	j.Remove("#AGENT")
+	if got := j.List(); !reflect.DeepEqual(got, []string{"#go"}) {
+		t.Errorf("Expected [#go] after parting #agent, got %v", got)
+	}
+
+	j.Reset()
+	if got := j.List(); len(got) != 0 {
+		t.Errorf("Expected no channels after a reconnect, got %v", got)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// kickRejoinWindow is how long kicks from a channel count towards the cap
+const kickRejoinWindow = time.Hour
+
+// defaultKickRejoinMax is how many rejoins are attempted per window by default
+const defaultKickRejoinMax = 3
+
+// KickRejoiner decides whether the bot rejoins a channel it was kicked from.
+// Rejoins are capped per channel so an op who keeps kicking the bot wins
+// rather than starting a kick/rejoin war.
+type KickRejoiner struct {
+	mu     sync.Mutex
+	delay  time.Duration          // wait before rejoining; zero or less disables rejoining
+	max    int                    // rejoins allowed per channel within kickRejoinWindow
+	kicks  map[string][]time.Time // maps lowercased channel -> recent rejoins
+	timers map[string]*time.Timer // maps lowercased channel -> pending rejoin
+}
+
+// NewKickRejoiner creates a rejoiner that waits delay before rejoining, at
+// most max times an hour per channel
+func NewKickRejoiner(delay time.Duration, max int) *KickRejoiner {
+	if max <= 0 {
+		max = defaultKickRejoinMax
+	}
+	return &KickRejoiner{
+		delay:  delay,
+		max:    max,
+		kicks:  make(map[string][]time.Time),
+		timers: make(map[string]*time.Timer),
+	}
+}
+
+// Delay returns how long to wait before rejoining
+func (k *KickRejoiner) Delay() time.Duration {
+	return k.delay
+}
+
+// Kicked records a kick from channel at now and reports whether to rejoin.
+// allowlisted says whether channel is one the bot is meant to be in.
+func (k *KickRejoiner) Kicked(channel string, allowlisted bool, now time.Time) bool {
+	if k.delay <= 0 || !allowlisted {
+		return false
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := strings.ToLower(channel)
+	recent := k.kicks[key][:0]
+	for _, at := range k.kicks[key] {
+		if now.Sub(at) < kickRejoinWindow {
+			recent = append(recent, at)
+		}
+	}
+	if len(recent) >= k.max {
+		k.kicks[key] = recent
+		return false
+	}
+	k.kicks[key] = append(recent, now)
+	return true
+}
+
+// Schedule calls rejoin for channel once the delay has passed, replacing any
+// rejoin already pending for it
+func (k *KickRejoiner) Schedule(channel string, rejoin func()) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	key := strings.ToLower(channel)
+	if pending := k.timers[key]; pending != nil {
+		pending.Stop()
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(k.delay, func() {
+		k.mu.Lock()
+		// Cancelled or replaced after the timer had already fired
+		if k.timers[key] != timer {
+			k.mu.Unlock()
+			return
+		}
+		delete(k.timers, key)
+		k.mu.Unlock()
+		rejoin()
+	})
+	k.timers[key] = timer
+}
+
+// Cancel stops every pending rejoin, for when the connection drops, is
+// re-established or shuts down
+func (k *KickRejoiner) Cancel() {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	for key, timer := range k.timers {
+		timer.Stop()
+		delete(k.timers, key)
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestKickRejoinerCapsRejoins(t *testing.T) {
+	k := NewKickRejoiner(10*time.Second, 2)
+	now := time.Now()
+
+	if !k.Kicked("#agent", true, now) || !k.Kicked("#Agent", true, now.Add(time.Minute)) {
+		t.Fatal("Expected the first two kicks to be rejoined")
+	}
+	if k.Kicked("#agent", true, now.Add(2*time.Minute)) {
+		t.Error("Expected the third kick within an hour not to be rejoined")
+	}
+	// Other channels have their own count
+	if !k.Kicked("#go", true, now.Add(2*time.Minute)) {
+		t.Error("Expected a kick from another channel to be rejoined")
+	}
+	// Once the earlier kicks age out, rejoining resumes
+	if !k.Kicked("#agent", true, now.Add(time.Hour+time.Second)) {
+		t.Error("Expected rejoining after the window")
+	}
+}
+
+func TestKickRejoinerDecision(t *testing.T) {
+	now := time.Now()
+	if NewKickRejoiner(time.Second, 0).Kicked("#random", false, now) {
+		t.Error("Expected no rejoin for a channel that isn't allowlisted")
+	}
+	if NewKickRejoiner(0, 0).Kicked("#agent", true, now) {
+		t.Error("Expected no rejoin when rejoining is disabled")
+	}
+	if k := NewKickRejoiner(time.Second, 0); k.max != defaultKickRejoinMax {
+		t.Errorf("Expected the default cap of %d, got %d", defaultKickRejoinMax, k.max)
+	}
+}
+
+func TestKickRejoinerSchedule(t *testing.T) {
+	k := NewKickRejoiner(10*time.Millisecond, 0)
+	rejoined := make(chan string, 2)
+	k.Schedule("#agent", func() { rejoined <- "first" })
+	// A second kick replaces the pending rejoin rather than adding one
+	k.Schedule("#Agent", func() { rejoined <- "second" })
+
+	select {
+	case got := <-rejoined:
+		if got != "second" {
+			t.Errorf("Expected the replacement rejoin, got %s", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected a rejoin")
+	}
+	select {
+	case got := <-rejoined:
+		t.Errorf("Expected only one rejoin, also got %s", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestKickRejoinerCancel(t *testing.T) {
+	k := NewKickRejoiner(20*time.Millisecond, 0)
+	rejoined := make(chan struct{}, 1)
+	k.Schedule("#agent", func() { rejoined <- struct{}{} })
+	k.Cancel()
+
+	select {
+	case <-rejoined:
+		t.Error("Expected a cancelled rejoin not to run")
+	case <-time.After(60 * time.Millisecond):
+	}
+	if len(k.timers) != 0 {
+		t.Errorf("Expected no pending rejoins, got %d", len(k.timers))
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"time"
+
+	"google.golang.org/adk/tool"
+)
+
+// Page sizes for list_results
+const (
+	defaultListResultsLimit = 20
+	maxListResultsLimit     = 100
+)
+
+// ListResultsParams defines the input parameters for the list_results tool
+type ListResultsParams struct {
+	Prefix string `json:"prefix,omitempty" jsonschema:"Only list results whose keys continue with this, e.g. uploads/"`
+	Cursor string `json:"cursor,omitempty" jsonschema:"next_cursor from a previous call, to get the next page"`
+	Limit  int    `json:"limit,omitempty" jsonschema:"How many results to return, 20 by default and at most 100"`
+}
+
+// ListedResult is one stored result returned by the list_results tool
+type ListedResult struct {
+	Key          string `json:"key"`
+	Size         int64  `json:"size"`
+	LastModified string `json:"last_modified"`
+	URL          string `json:"url,omitempty"`
+}
+
+// ListResultsResults defines the output of the list_results tool
+type ListResultsResults struct {
+	Status       string         `json:"status"`
+	Results      []ListedResult `json:"results,omitempty"`
+	NextCursor   string         `json:"next_cursor,omitempty"`
+	ErrorMessage string         `json:"error_message,omitempty"`
+}
+
+// ResultLister lists uploaded results so the model doesn't have to write
+// code against the S3 API to find them. Links are left unshortened: they are
+// presigned and expire, so listing the same result again mints a new URL and
+// a short link for each would pile up in the shortener.
+type ResultLister struct {
+	lister ArtifactLister
+}
+
+// NewResultLister creates a lister over lister
+func NewResultLister(lister ArtifactLister) *ResultLister {
+	return &ResultLister{lister: lister}
+}
+
+// List returns a page of stored results with their sizes, times and links
+func (l *ResultLister) List(ctx tool.Context, params ListResultsParams) ListResultsResults {
+	limit := params.Limit
+	if limit <= 0 {
+		limit = defaultListResultsLimit
+	}
+	limit = min(limit, maxListResultsLimit)
+
+	objects, next, err := l.lister.List(toolContext(ctx), params.Prefix, params.Cursor, limit)
+	if err != nil {
+		return ListResultsResults{Status: "error", ErrorMessage: err.Error()}
+	}
+
+	results := ListResultsResults{Status: "success", NextCursor: next}
+	for _, obj := range objects {
+		results.Results = append(results.Results, ListedResult{
+			Key:          obj.Key,
+			Size:         obj.Size,
+			LastModified: obj.LastModified.UTC().Format(time.RFC3339),
+			URL:          obj.URL,
+		})
+	}
+	return results
+}
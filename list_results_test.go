@@ -0,0 +1,76 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// fakeArtifactLister serves a fixed set of objects two at a time
+type fakeArtifactLister struct {
+	objects []ArtifactObject
+	prefix  string // last prefix asked for
+	limit   int    // last limit asked for
+	err     error
+}
+
+func (f *fakeArtifactLister) List(ctx context.Context, prefix, cursor string, limit int) ([]ArtifactObject, string, error) {
+	f.prefix, f.limit = prefix, limit
+	if f.err != nil {
+		return nil, "", f.err
+	}
+	start := 0
+	if cursor != "" {
+		start = 2
+	}
+	end := min(start+2, len(f.objects))
+	next := ""
+	if end < len(f.objects) {
+		next = "page-2"
+	}
+	return f.objects[start:end], next, nil
+}
+
+func TestListResults(t *testing.T) {
+	modified := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	lister := &fakeArtifactLister{objects: []ArtifactObject{
+		{Key: "code-results/1-a.txt", Size: 10, LastModified: modified, URL: "https://bucket.s3.amazonaws.com/a?X-Amz-Signature=1"},
+		{Key: "code-results/2-b.json", Size: 20, LastModified: modified, URL: "https://bucket.s3.amazonaws.com/b?X-Amz-Signature=2"},
+		{Key: "code-results/3-c.png", Size: 30, LastModified: modified, URL: "https://bucket.s3.amazonaws.com/c?X-Amz-Signature=3"},
+	}}
+	resultLister := NewResultLister(lister)
+
+	page := resultLister.List(nil, ListResultsParams{Prefix: "uploads/"})
+	if page.Status != "success" {
+		t.Fatalf("Expected success, got %+v", page)
+	}
+	if lister.prefix != "uploads/" || lister.limit != defaultListResultsLimit {
+		t.Errorf("Expected prefix uploads/ and the default limit, got %q and %d", lister.prefix, lister.limit)
+	}
+	if len(page.Results) != 2 || page.NextCursor != "page-2" {
+		t.Fatalf("Expected 2 results and a next cursor, got %+v", page)
+	}
+	first := page.Results[0]
+	if first.Key != "code-results/1-a.txt" || first.Size != 10 || first.LastModified != "2024-05-01T12:00:00Z" {
+		t.Errorf("Expected the first object's details, got %+v", first)
+	}
+	if first.URL != lister.objects[0].URL {
+		t.Errorf("Expected the presigned URL as is, got %s", first.URL)
+	}
+
+	page = resultLister.List(nil, ListResultsParams{Cursor: page.NextCursor, Limit: 1000})
+	if len(page.Results) != 1 || page.NextCursor != "" {
+		t.Errorf("Expected the last result and no cursor, got %+v", page)
+	}
+	if lister.limit != maxListResultsLimit {
+		t.Errorf("Expected the limit capped at %d, got %d", maxListResultsLimit, lister.limit)
+	}
+}
+
+func TestListResultsError(t *testing.T) {
+	resultLister := NewResultLister(&fakeArtifactLister{err: errors.New("access denied")})
+	if result := resultLister.List(nil, ListResultsParams{}); result.Status != "error" || result.ErrorMessage != "access denied" {
+		t.Errorf("Expected the lister error, got %+v", result)
+	}
+}
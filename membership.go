@@ -0,0 +1,118 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// ChannelMembership tracks which nicks are present in each channel the bot is in
+type ChannelMembership struct {
+	mu       sync.RWMutex
+	channels map[string]map[string]string // maps lowercased channel -> lowercased nick -> nick as seen
+}
+
+// NewChannelMembership creates an empty membership tracker
+func NewChannelMembership() *ChannelMembership {
+	return &ChannelMembership{
+		channels: make(map[string]map[string]string),
+	}
+}
+
+// AddNames records the nicks from a 353 (RPL_NAMREPLY) line for a channel.
+// Servers may split a large channel across several 353 replies, so names are
+// added to the existing set rather than replacing it.
+func (m *ChannelMembership) AddNames(channel string, names []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	members := m.channelLocked(channel)
+	for _, name := range names {
+		// Strip channel status prefixes (op, voice, etc.)
+		nick := strings.TrimLeft(name, "~&@%+")
+		if nick == "" {
+			continue
+		}
+		members[strings.ToLower(nick)] = nick
+	}
+}
+
+// Join records that nick has joined channel
+func (m *ChannelMembership) Join(channel, nick string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.channelLocked(channel)[strings.ToLower(nick)] = nick
+}
+
+// Part records that nick has left channel
+func (m *ChannelMembership) Part(channel, nick string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if members, ok := m.channels[strings.ToLower(channel)]; ok {
+		delete(members, strings.ToLower(nick))
+	}
+}
+
+// Quit records that nick has disconnected, removing it from every channel
+func (m *ChannelMembership) Quit(nick string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, members := range m.channels {
+		delete(members, strings.ToLower(nick))
+	}
+}
+
+// Rename records a NICK change, carrying the new nick into every channel the
+// old nick was in
+func (m *ChannelMembership) Rename(oldNick, newNick string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	oldKey := strings.ToLower(oldNick)
+	for _, members := range m.channels {
+		if _, ok := members[oldKey]; ok {
+			delete(members, oldKey)
+			members[strings.ToLower(newNick)] = newNick
+		}
+	}
+}
+
+// Reset forgets everything known about a channel. It is used when the bot
+// itself joins or leaves a channel so that a fresh NAMES reply starts clean.
+func (m *ChannelMembership) Reset(channel string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.channels, strings.ToLower(channel))
+}
+
+// Members returns the nicks currently in channel, sorted case-insensitively
+func (m *ChannelMembership) Members(channel string) []string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	members := m.channels[strings.ToLower(channel)]
+	nicks := make([]string, 0, len(members))
+	for _, nick := range members {
+		nicks = append(nicks, nick)
+	}
+	sort.Slice(nicks, func(i, j int) bool {
+		return strings.ToLower(nicks[i]) < strings.ToLower(nicks[j])
+	})
+	return nicks
+}
+
+// channelLocked returns the member set for channel, creating it if needed.
+// The caller must hold m.mu for writing.
+func (m *ChannelMembership) channelLocked(channel string) map[string]string {
+	key := strings.ToLower(channel)
+	members, ok := m.channels[key]
+	if !ok {
+		members = make(map[string]string)
+		m.channels[key] = members
+	}
+	return members
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestChannelMembershipNamesAndPrefixes(t *testing.T) {
+	members := NewChannelMembership()
+
+	// A large channel may be split across several 353 replies
+	members.AddNames("#agent", []string{"@alice", "+bob"})
+	members.AddNames("#agent", []string{"~Carol", "dave"})
+
+	got := members.Members("#agent")
+	expected := []string{"alice", "bob", "Carol", "dave"}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected members %v, got %v", expected, got)
+	}
+}
+
+func TestChannelMembershipJoinPartQuit(t *testing.T) {
+	members := NewChannelMembership()
+
+	members.Join("#agent", "alice")
+	members.Join("#agent", "bob")
+	members.Join("#other", "alice")
+
+	// Channel names are case-insensitive
+	members.Part("#AGENT", "bob")
+	if got := members.Members("#agent"); !reflect.DeepEqual(got, []string{"alice"}) {
+		t.Errorf("Expected only alice after part, got %v", got)
+	}
+
+	// Quit removes the nick from every channel
+	members.Quit("alice")
+	if got := members.Members("#agent"); len(got) != 0 {
+		t.Errorf("Expected #agent to be empty after quit, got %v", got)
+	}
+	if got := members.Members("#other"); len(got) != 0 {
+		t.Errorf("Expected #other to be empty after quit, got %v", got)
+	}
+}
+
+func TestChannelMembershipRenameAndReset(t *testing.T) {
+	members := NewChannelMembership()
+
+	members.AddNames("#agent", []string{"alice", "bob"})
+	members.Rename("alice", "alice_away")
+
+	expected := []string{"alice_away", "bob"}
+	if got := members.Members("#agent"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected members %v after rename, got %v", expected, got)
+	}
+
+	// Renaming a nick that isn't present must not add it anywhere
+	members.Rename("mallory", "eve")
+	if got := members.Members("#agent"); !reflect.DeepEqual(got, expected) {
+		t.Errorf("Expected members %v after unrelated rename, got %v", expected, got)
+	}
+
+	members.Reset("#agent")
+	if got := members.Members("#agent"); len(got) != 0 {
+		t.Errorf("Expected no members after reset, got %v", got)
+	}
+}
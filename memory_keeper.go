@@ -0,0 +1,280 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/adk/memory"
+	"google.golang.org/adk/session"
+	"google.golang.org/adk/tool"
+	"google.golang.org/genai"
+)
+
+// defaultMemoryFacts is how many facts are kept per user in a channel
+const defaultMemoryFacts = 20
+
+// memoryAppName is the app facts are stored under in the memory service
+const memoryAppName = "irc_agent"
+
+// RememberParams defines the input parameters for the remember tool
+type RememberParams struct {
+	Fact string `json:"fact" jsonschema:"A short fact about the user to remember in later conversations, e.g. a preference"`
+}
+
+// RememberResults defines the output of the remember tool
+type RememberResults struct {
+	Status       string `json:"status"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// RecallParams defines the input parameters for the recall tool
+type RecallParams struct {
+	Query string `json:"query" jsonschema:"Words to look for in what you remember about the user"`
+}
+
+// RecallResults defines the output of the recall tool
+type RecallResults struct {
+	Status       string   `json:"status"`
+	Facts        []string `json:"facts,omitempty"`
+	ErrorMessage string   `json:"error_message,omitempty"`
+}
+
+// memoryScope identifies whose facts these are: one nick in one channel. The
+// channel is the runner's user ID, so it includes the network on named
+// networks and a nick on two networks isn't taken for the same person.
+type memoryScope struct {
+	channel string // lowercased
+	nick    string // lowercased
+}
+
+// memoryFact is a remembered fact
+type memoryFact struct {
+	text string
+	at   time.Time
+}
+
+// MemoryKeeper stores facts about users in the ADK memory service so the bot
+// can recall them across sessions. Facts are scoped to a nick in a channel;
+// each scope is kept as its own memory session, replaced on every change.
+type MemoryKeeper struct {
+	service memory.Service
+	limit   int // facts kept per scope, oldest dropped first
+
+	mu    sync.Mutex
+	facts map[memoryScope][]memoryFact
+}
+
+// NewMemoryKeeper creates a keeper storing up to limit facts per user
+func NewMemoryKeeper(service memory.Service, limit int) *MemoryKeeper {
+	if limit <= 0 {
+		limit = defaultMemoryFacts
+	}
+	return &MemoryKeeper{
+		service: service,
+		limit:   limit,
+		facts:   make(map[memoryScope][]memoryFact),
+	}
+}
+
+// scopeFor returns the memory scope for nick in channel
+func scopeFor(channel, nick string) memoryScope {
+	return memoryScope{channel: strings.ToLower(channel), nick: strings.ToLower(nick)}
+}
+
+// Remember stores fact about nick in channel
+func (k *MemoryKeeper) Remember(ctx context.Context, channel, nick, fact string) error {
+	fact = strings.TrimSpace(fact)
+	if fact == "" {
+		return fmt.Errorf("fact is empty")
+	}
+	scope := scopeFor(channel, nick)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// Copied so a failed sync leaves the remembered facts as they were
+	facts := append(append([]memoryFact(nil), k.facts[scope]...), memoryFact{text: fact, at: time.Now()})
+	if len(facts) > k.limit {
+		facts = facts[len(facts)-k.limit:]
+	}
+	if err := k.sync(ctx, scope, facts); err != nil {
+		return err
+	}
+	k.facts[scope] = facts
+	return nil
+}
+
+// Facts returns everything remembered about nick in channel, oldest first
+func (k *MemoryKeeper) Facts(channel, nick string) []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	var texts []string
+	for _, f := range k.facts[scopeFor(channel, nick)] {
+		texts = append(texts, f.text)
+	}
+	return texts
+}
+
+// Search returns the facts about nick in channel that share a word with query
+func (k *MemoryKeeper) Search(ctx context.Context, channel, nick, query string) ([]string, error) {
+	scope := scopeFor(channel, nick)
+	resp, err := k.service.Search(ctx, &memory.SearchRequest{
+		AppName: memoryAppName,
+		UserID:  scope.channel,
+		Query:   strings.ToLower(query),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search memory: %w", err)
+	}
+
+	var texts []string
+	for _, entry := range resp.Memories {
+		// Other users' facts in the channel share the memory user
+		if entry.Author != scope.nick || entry.Content == nil {
+			continue
+		}
+		for _, part := range entry.Content.Parts {
+			if part.Text != "" {
+				texts = append(texts, part.Text)
+			}
+		}
+	}
+	return texts, nil
+}
+
+// Forget deletes everything remembered about nick in channel, leaving other
+// users' facts alone, and returns how many facts were removed
+func (k *MemoryKeeper) Forget(ctx context.Context, channel, nick string) (int, error) {
+	scope := scopeFor(channel, nick)
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+
+	// The memory service replaces a session's entries, so an empty one clears them
+	if err := k.sync(ctx, scope, nil); err != nil {
+		return 0, err
+	}
+	n := len(k.facts[scope])
+	delete(k.facts, scope)
+	return n, nil
+}
+
+// sync replaces the memory session for scope with facts. Callers update
+// k.facts only once it succeeds, so Facts and Search agree, and must hold k.mu.
+func (k *MemoryKeeper) sync(ctx context.Context, scope memoryScope, facts []memoryFact) error {
+	if err := k.service.AddSession(ctx, newFactSession(scope, facts)); err != nil {
+		return fmt.Errorf("failed to store memory: %w", err)
+	}
+	return nil
+}
+
+// RememberTool is the remember tool: it stores a fact about the user who sent
+// the message being answered
+func (k *MemoryKeeper) RememberTool(ctx tool.Context, params RememberParams) RememberResults {
+	nick := senderFromContext(ctx)
+	if nick == "" {
+		return RememberResults{Status: "error", ErrorMessage: "No user to remember this for"}
+	}
+	if err := k.Remember(toolContext(ctx), ctx.UserID(), nick, params.Fact); err != nil {
+		return RememberResults{Status: "error", ErrorMessage: err.Error()}
+	}
+	return RememberResults{Status: "success"}
+}
+
+// RecallTool is the recall tool: it searches the facts remembered about the
+// user who sent the message being answered
+func (k *MemoryKeeper) RecallTool(ctx tool.Context, params RecallParams) RecallResults {
+	nick := senderFromContext(ctx)
+	if nick == "" {
+		return RecallResults{Status: "error", ErrorMessage: "No user to recall facts for"}
+	}
+	facts, err := k.Search(toolContext(ctx), ctx.UserID(), nick, params.Query)
+	if err != nil {
+		return RecallResults{Status: "error", ErrorMessage: err.Error()}
+	}
+	return RecallResults{Status: "success", Facts: facts}
+}
+
+// memoryPrompt describes what is remembered about sender, to prepend to the
+// prompt, or returns "" when nothing is
+func memoryPrompt(sender string, facts []string) string {
+	if len(facts) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("What you remember about " + sender + ":\n")
+	for _, f := range facts {
+		b.WriteString("- " + f + "\n")
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// senderKey is the context key holding the nick a run is answering
+type senderKey struct{}
+
+// withSender returns ctx carrying the nick of the message being answered, so
+// tools can scope what they do to that user
+func withSender(ctx context.Context, nick string) context.Context {
+	return context.WithValue(ctx, senderKey{}, nick)
+}
+
+// senderFromContext returns the nick stored by withSender, or ""
+func senderFromContext(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	nick, _ := ctx.Value(senderKey{}).(string)
+	return nick
+}
+
+// factSession presents a scope's facts as a session for the memory service,
+// one model event per fact authored by the lowercased nick
+type factSession struct {
+	scope  memoryScope
+	events []*session.Event
+}
+
+// newFactSession builds the memory session for scope holding facts
+func newFactSession(scope memoryScope, facts []memoryFact) *factSession {
+	s := &factSession{scope: scope}
+	for _, f := range facts {
+		event := session.NewEvent("")
+		event.Author = scope.nick
+		event.Timestamp = f.at
+		event.LLMResponse.Content = genai.NewContentFromText(f.text, genai.RoleModel)
+		s.events = append(s.events, event)
+	}
+	return s
+}
+
+func (s *factSession) ID() string                { return "memory-" + s.scope.nick }
+func (s *factSession) AppName() string           { return memoryAppName }
+func (s *factSession) UserID() string            { return s.scope.channel }
+func (s *factSession) State() session.State      { return emptyState{} }
+func (s *factSession) Events() session.Events    { return s }
+func (s *factSession) LastUpdateTime() time.Time { return time.Now() }
+func (s *factSession) Len() int                  { return len(s.events) }
+func (s *factSession) At(i int) *session.Event   { return s.events[i] }
+
+func (s *factSession) All() iter.Seq[*session.Event] {
+	return func(yield func(*session.Event) bool) {
+		for _, e := range s.events {
+			if !yield(e) {
+				return
+			}
+		}
+	}
+}
+
+// emptyState is the state of a factSession, which has none
+type emptyState struct{}
+
+func (emptyState) Get(string) (any, error)     { return nil, session.ErrStateKeyNotExist }
+func (emptyState) Set(string, any) error       { return fmt.Errorf("memory sessions have no state") }
+func (emptyState) All() iter.Seq2[string, any] { return func(func(string, any) bool) {} }
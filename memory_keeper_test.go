@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/memory"
+	"google.golang.org/adk/session"
+)
+
+func TestMemoryKeeperRememberAndSearch(t *testing.T) {
+	keeper := NewMemoryKeeper(memory.InMemoryService(), 0)
+	ctx := context.Background()
+
+	if err := keeper.Remember(ctx, "#Go", "Alice", "prefers metric units"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := keeper.Remember(ctx, "#go", "alice", "works on compilers"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	facts, err := keeper.Search(ctx, "#go", "ALICE", "which units")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(facts) != 1 || facts[0] != "prefers metric units" {
+		t.Errorf("Expected the units fact, got %q", facts)
+	}
+
+	all := keeper.Facts("#go", "alice")
+	if len(all) != 2 || all[1] != "works on compilers" {
+		t.Errorf("Expected both facts in order, got %q", all)
+	}
+}
+
+func TestMemoryKeeperScopes(t *testing.T) {
+	keeper := NewMemoryKeeper(memory.InMemoryService(), 0)
+	ctx := context.Background()
+
+	keeper.Remember(ctx, "#go", "alice", "likes tea")
+	keeper.Remember(ctx, "#go", "bob", "likes coffee")
+	keeper.Remember(ctx, "#rust", "alice", "likes water")
+
+	facts, err := keeper.Search(ctx, "#go", "alice", "likes")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(facts) != 1 || facts[0] != "likes tea" {
+		t.Errorf("Expected only alice's #go fact, got %q", facts)
+	}
+	if got := keeper.Facts("#rust", "bob"); len(got) != 0 {
+		t.Errorf("Expected nothing for bob in #rust, got %q", got)
+	}
+}
+
+func TestMemoryKeeperLimit(t *testing.T) {
+	keeper := NewMemoryKeeper(memory.InMemoryService(), 2)
+	ctx := context.Background()
+	for _, fact := range []string{"one", "two", "three"} {
+		keeper.Remember(ctx, "#go", "alice", fact)
+	}
+
+	facts := keeper.Facts("#go", "alice")
+	if len(facts) != 2 || facts[0] != "two" {
+		t.Errorf("Expected the oldest fact to be dropped, got %q", facts)
+	}
+	if found, _ := keeper.Search(ctx, "#go", "alice", "one"); len(found) != 0 {
+		t.Errorf("Expected the dropped fact to be gone from memory, got %q", found)
+	}
+}
+
+func TestMemoryKeeperRejectsEmptyFact(t *testing.T) {
+	keeper := NewMemoryKeeper(memory.InMemoryService(), 0)
+	if err := keeper.Remember(context.Background(), "#go", "alice", "  "); err == nil {
+		t.Error("Expected an error for an empty fact")
+	}
+}
+
+func TestMemoryTools(t *testing.T) {
+	keeper := NewMemoryKeeper(memory.InMemoryService(), 0)
+	ctx := fakeToolContext{channel: "#go", ctx: withSender(context.Background(), "alice")}
+
+	if result := keeper.RememberTool(ctx, RememberParams{Fact: "prefers vim"}); result.Status != "success" {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+	result := keeper.RecallTool(ctx, RecallParams{Query: "vim"})
+	if result.Status != "success" || len(result.Facts) != 1 || result.Facts[0] != "prefers vim" {
+		t.Errorf("Expected the stored fact, got %+v", result)
+	}
+
+	// Without a sender there is nobody to scope facts to
+	anonymous := fakeToolContext{channel: "#go"}
+	if result := keeper.RememberTool(anonymous, RememberParams{Fact: "x"}); result.Status != "error" {
+		t.Errorf("Expected an error without a sender, got %+v", result)
+	}
+}
+
+func TestMemoryPrompt(t *testing.T) {
+	if got := memoryPrompt("alice", nil); got != "" {
+		t.Errorf("Expected no prompt without facts, got %q", got)
+	}
+	got := memoryPrompt("alice", []string{"prefers vim"})
+	if !strings.Contains(got, "alice") || !strings.Contains(got, "- prefers vim") {
+		t.Errorf("Expected the facts in the prompt, got %q", got)
+	}
+}
+
+func TestMemoryKeeperForgetIsScoped(t *testing.T) {
+	keeper := NewMemoryKeeper(memory.InMemoryService(), 0)
+	ctx := context.Background()
+	keeper.Remember(ctx, "#go", "alice", "likes tea")
+	keeper.Remember(ctx, "#go", "alice", "likes cake")
+	keeper.Remember(ctx, "#go", "bob", "likes coffee")
+	keeper.Remember(ctx, "#rust", "alice", "likes water")
+
+	n, err := keeper.Forget(ctx, "#go", "Alice")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != 2 {
+		t.Errorf("Expected 2 facts forgotten, got %d", n)
+	}
+
+	if found, _ := keeper.Search(ctx, "#go", "alice", "likes"); len(found) != 0 {
+		t.Errorf("Expected alice's #go facts to be gone, got %q", found)
+	}
+	if found, _ := keeper.Search(ctx, "#go", "bob", "likes"); len(found) != 1 {
+		t.Errorf("Expected bob's facts to be kept, got %q", found)
+	}
+	if found, _ := keeper.Search(ctx, "#rust", "alice", "likes"); len(found) != 1 {
+		t.Errorf("Expected alice's #rust facts to be kept, got %q", found)
+	}
+}
+
+// failingMemoryService is a memory service whose writes fail
+type failingMemoryService struct {
+	memory.Service
+	fail bool
+}
+
+func (s *failingMemoryService) AddSession(ctx context.Context, sess session.Session) error {
+	if s.fail {
+		return errors.New("store unavailable")
+	}
+	return s.Service.AddSession(ctx, sess)
+}
+
+func TestMemoryKeeperKeepsFactsWhenStoreFails(t *testing.T) {
+	service := &failingMemoryService{Service: memory.InMemoryService()}
+	keeper := NewMemoryKeeper(service, 0)
+	ctx := context.Background()
+	keeper.Remember(ctx, "#go", "alice", "likes tea")
+
+	service.fail = true
+	if err := keeper.Remember(ctx, "#go", "alice", "likes coffee"); err == nil {
+		t.Fatal("Expected an error when the memory service fails")
+	}
+	if got := keeper.Facts("#go", "alice"); len(got) != 1 || got[0] != "likes tea" {
+		t.Errorf("Expected the unstored fact to be left out, got %q", got)
+	}
+	if _, err := keeper.Forget(ctx, "#go", "alice"); err == nil {
+		t.Fatal("Expected an error when the memory service fails")
+	}
+	if got := keeper.Facts("#go", "alice"); len(got) != 1 {
+		t.Errorf("Expected the facts to stay while memory still has them, got %q", got)
+	}
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// MessageDeduper suppresses sending a message identical to one recently sent to
+// the same channel. It guards against feedback loops between bots.
+type MessageDeduper struct {
+	mu     sync.Mutex
+	window time.Duration                   // how long a sent message blocks repeats
+	sent   map[string]map[string]time.Time // maps lowercased channel -> message -> last sent time
+}
+
+// NewMessageDeduper creates a deduper with the given window. A window of zero
+// or less disables suppression.
+func NewMessageDeduper(window time.Duration) *MessageDeduper {
+	return &MessageDeduper{
+		window: window,
+		sent:   make(map[string]map[string]time.Time),
+	}
+}
+
+// Allow reports whether message may be sent to channel at now, and records it
+// as sent if so
+func (d *MessageDeduper) Allow(channel, message string, now time.Time) bool {
+	if d.window <= 0 {
+		return true
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	key := strings.ToLower(channel)
+	history, ok := d.sent[key]
+	if !ok {
+		history = make(map[string]time.Time)
+		d.sent[key] = history
+	}
+
+	// Forget messages that have aged out of the window
+	for msg, sentAt := range history {
+		if now.Sub(sentAt) >= d.window {
+			delete(history, msg)
+		}
+	}
+
+	if _, recent := history[message]; recent {
+		return false
+	}
+
+	history[message] = now
+	return true
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMessageDeduperWindow(t *testing.T) {
+	d := NewMessageDeduper(30 * time.Second)
+	start := time.Now()
+
+	if !d.Allow("#agent", "hello", start) {
+		t.Fatalf("Expected first message to be allowed")
+	}
+	if d.Allow("#agent", "hello", start.Add(10*time.Second)) {
+		t.Errorf("Expected repeat within window to be suppressed")
+	}
+	if !d.Allow("#agent", "something else", start.Add(10*time.Second)) {
+		t.Errorf("Expected a different message to be allowed")
+	}
+	if !d.Allow("#other", "hello", start.Add(10*time.Second)) {
+		t.Errorf("Expected the same message in another channel to be allowed")
+	}
+	if !d.Allow("#agent", "hello", start.Add(31*time.Second)) {
+		t.Errorf("Expected repeat after the window to be allowed")
+	}
+}
+
+func TestMessageDeduperDisabled(t *testing.T) {
+	d := NewMessageDeduper(0)
+	now := time.Now()
+
+	if !d.Allow("#agent", "hello", now) || !d.Allow("#agent", "hello", now) {
+		t.Errorf("Expected a zero window to disable suppression")
+	}
+}
@@ -0,0 +1,95 @@
+package main
+
+import "sync"
+
+// Defaults for the incoming message queue
+const (
+	defaultMessageWorkers   = 4
+	defaultMessageQueueSize = 32
+)
+
+// busyMessage is sent when a message is dropped because the queue is full
+const busyMessage = "I'm busy with other requests right now, please try again in a moment"
+
+// MessageQueue runs incoming messages on a fixed number of workers so a burst
+// can't start an unbounded number of model calls and executions at once.
+// Jobs with the same key run one at a time in arrival order, and a key whose
+// job is running doesn't hold up a worker, so one busy channel can't idle the
+// pool. Up to depth jobs wait beyond those running; once full, Submit refuses
+// more without blocking.
+type MessageQueue struct {
+	mu      sync.Mutex
+	cond    *sync.Cond          // signalled when a key becomes ready
+	waiting map[string][]func() // jobs not yet started, by key
+	running map[string]bool     // keys with a job on a worker
+	ready   []string            // keys with waiting jobs and none running, oldest first
+	queued  int                 // jobs accepted and not yet finished
+	limit   int                 // most jobs accepted at once: workers plus depth
+}
+
+// NewMessageQueue starts workers goroutines serving a queue of depth jobs
+func NewMessageQueue(workers, depth int) *MessageQueue {
+	if workers <= 0 {
+		workers = defaultMessageWorkers
+	}
+	if depth < 0 {
+		depth = 0
+	}
+	q := &MessageQueue{
+		waiting: make(map[string][]func()),
+		running: make(map[string]bool),
+		limit:   workers + depth,
+	}
+	q.cond = sync.NewCond(&q.mu)
+	for i := 0; i < workers; i++ {
+		go q.work()
+	}
+	return q
+}
+
+// Submit queues job behind any others for key and reports whether it was
+// accepted. It never blocks, so it is safe to call from the IRC read loop.
+func (q *MessageQueue) Submit(key string, job func()) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.queued >= q.limit {
+		return false
+	}
+	q.queued++
+	if len(q.waiting[key]) == 0 && !q.running[key] {
+		q.ready = append(q.ready, key)
+		q.cond.Signal()
+	}
+	q.waiting[key] = append(q.waiting[key], job)
+	return true
+}
+
+// work runs jobs from ready keys until the process exits
+func (q *MessageQueue) work() {
+	q.mu.Lock()
+	for {
+		for len(q.ready) == 0 {
+			q.cond.Wait()
+		}
+		key := q.ready[0]
+		q.ready = q.ready[1:]
+		job := q.waiting[key][0]
+		if rest := q.waiting[key][1:]; len(rest) > 0 {
+			q.waiting[key] = rest
+		} else {
+			delete(q.waiting, key)
+		}
+		q.running[key] = true
+		q.mu.Unlock()
+
+		job()
+
+		q.mu.Lock()
+		delete(q.running, key)
+		q.queued--
+		if len(q.waiting[key]) > 0 {
+			q.ready = append(q.ready, key)
+			q.cond.Signal()
+		}
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMessageQueueBoundsConcurrency(t *testing.T) {
+	q := NewMessageQueue(2, 10)
+
+	var active, maxActive atomic.Int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		accepted := q.Submit(fmt.Sprintf("#c%d", i), func() {
+			defer wg.Done()
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				m := maxActive.Load()
+				if n <= m || maxActive.CompareAndSwap(m, n) {
+					break
+				}
+			}
+			time.Sleep(5 * time.Millisecond)
+		})
+		if !accepted {
+			t.Fatalf("Expected job %d to fit in the queue", i)
+		}
+	}
+	wg.Wait()
+
+	if got := maxActive.Load(); got != 2 {
+		t.Errorf("Expected at most 2 jobs at once, got %d", got)
+	}
+}
+
+func TestMessageQueueRefusesWhenFull(t *testing.T) {
+	q := NewMessageQueue(1, 1)
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	// One job running and one waiting fill the queue
+	q.Submit("#a", func() { close(started); <-release })
+	<-started
+	if !q.Submit("#a", func() {}) {
+		t.Fatal("Expected the second job to be queued")
+	}
+	if q.Submit("#a", func() {}) {
+		t.Error("Expected the third job to be refused")
+	}
+	close(release)
+}
+
+func TestMessageQueueRunsOneJobPerKey(t *testing.T) {
+	q := NewMessageQueue(2, 10)
+	release := make(chan struct{})
+	var order []int
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// Three jobs for #busy run in order, one at a time
+	for i := 0; i < 3; i++ {
+		wg.Add(1)
+		q.Submit("#busy", func() {
+			defer wg.Done()
+			<-release
+			mu.Lock()
+			order = append(order, i)
+			mu.Unlock()
+		})
+	}
+
+	// The second worker isn't tied up waiting on #busy
+	done := make(chan struct{})
+	q.Submit("#other", func() { close(done) })
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected #other to run while #busy was running")
+	}
+
+	close(release)
+	wg.Wait()
+	if len(order) != 3 || order[0] != 0 || order[1] != 1 || order[2] != 2 {
+		t.Errorf("Expected #busy jobs in order, got %v", order)
+	}
+}
+
+func TestMessageQueueSubmitNeverBlocks(t *testing.T) {
+	q := NewMessageQueue(1, 0)
+	release := make(chan struct{})
+	defer close(release)
+	q.Submit("#a", func() { <-release })
+
+	refused := make(chan bool)
+	go func() { refused <- !q.Submit("#b", func() {}) }()
+	select {
+	case ok := <-refused:
+		if !ok {
+			t.Error("Expected the job to be refused while the only worker is busy")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected Submit to return without waiting for room")
+	}
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+
+	irc "github.com/thoj/go-ircevent"
+)
+
+// MessageSink delivers the messages the bot sends to a channel or nick
+type MessageSink interface {
+	Send(target, message string)
+}
+
+// MessageSinkFunc adapts a function to a MessageSink
+type MessageSinkFunc func(target, message string)
+
+// Send calls f(target, message)
+func (f MessageSinkFunc) Send(target, message string) {
+	f(target, message)
+}
+
+// IRCSink sends messages over an IRC connection as PRIVMSG or, with the
+// notice reply type, NOTICE. Messages written as "/me ..." go out as actions.
+type IRCSink struct {
+	conn      *irc.Connection
+	replyType string
+}
+
+// NewIRCSink creates a sink sending on conn with replyType from REPLY_TYPE
+func NewIRCSink(conn *irc.Connection, replyType string) *IRCSink {
+	return &IRCSink{conn: conn, replyType: replyType}
+}
+
+// Send writes message to target on the connection
+func (s *IRCSink) Send(target, message string) {
+	if action, ok := ownAction(message); ok {
+		s.conn.Action(target, action)
+		return
+	}
+	replySender(s.replyType, s.conn.Privmsg, s.conn.Notice)(target, message)
+}
+
+// WriterSink writes messages to w, one "[target] message" line each, instead
+// of sending them to IRC
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewWriterSink creates a sink writing to w
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{w: w}
+}
+
+// Send writes message to the sink's writer
+func (s *WriterSink) Send(target, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fmt.Fprintf(s.w, "[%s] %s\n", target, message)
+}
+
+// replyRoute is the way back to where a message came from: the sink for the
+// connection it arrived on and the channel or nick to answer
+type replyRoute struct {
+	sink   MessageSink
+	target string
+}
+
+// replyRouteKey is the context key holding a run's replyRoute
+type replyRouteKey struct{}
+
+// withReplyRoute returns ctx carrying the route back to the message being
+// answered, so tools post to the network it came from
+func withReplyRoute(ctx context.Context, sink MessageSink, target string) context.Context {
+	return context.WithValue(ctx, replyRouteKey{}, replyRoute{sink: sink, target: target})
+}
+
+// replyRouteFromContext returns the route stored by withReplyRoute
+func replyRouteFromContext(ctx context.Context) (replyRoute, bool) {
+	if ctx == nil {
+		return replyRoute{}, false
+	}
+	route, ok := ctx.Value(replyRouteKey{}).(replyRoute)
+	return route, ok
+}
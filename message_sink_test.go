@@ -0,0 +1,66 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+)
+
+// sentMessage is a message a fakeSink received
+type sentMessage struct {
+	target  string
+	message string
+}
+
+// fakeSink records what the bot sends instead of writing it to IRC
+type fakeSink struct {
+	mu   sync.Mutex
+	sent []sentMessage
+}
+
+func (s *fakeSink) Send(target, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, sentMessage{target: target, message: message})
+}
+
+// Messages returns the text of every message sent, in order
+func (s *fakeSink) Messages() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var messages []string
+	for _, m := range s.sent {
+		messages = append(messages, m.message)
+	}
+	return messages
+}
+
+// Sent returns every message sent with its target, in order
+func (s *fakeSink) Sent() []sentMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]sentMessage(nil), s.sent...)
+}
+
+func TestWriterSink(t *testing.T) {
+	var out bytes.Buffer
+	sink := NewWriterSink(&out)
+	sink.Send("#agent", "hello")
+	sink.Send("alice", "hi")
+
+	expected := "[#agent] hello\n[alice] hi\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+}
+
+func TestMessageSinkFunc(t *testing.T) {
+	var got sentMessage
+	var sink MessageSink = MessageSinkFunc(func(target, message string) {
+		got = sentMessage{target: target, message: message}
+	})
+	sink.Send("#agent", "hello")
+	if got != (sentMessage{target: "#agent", message: "hello"}) {
+		t.Errorf("Expected the message to reach the function, got %+v", got)
+	}
+}
@@ -0,0 +1,38 @@
+package main
+
+import "time"
+
+// MessageTags holds the IRCv3 message tags the bot uses
+type MessageTags struct {
+	Account    string    // sender's services account, from the account tag
+	ServerTime time.Time // when the server received the message, from server-time
+	MsgID      string    // server-assigned message ID
+}
+
+// ircv3Caps are requested after registration so the server attaches the tags above
+const ircv3Caps = "server-time account-tag message-tags"
+
+// parseMessageTags extracts the tags the bot uses from an event's tag map.
+// Missing or malformed tags are left zero.
+func parseMessageTags(tags map[string]string) MessageTags {
+	var parsed MessageTags
+	if tags == nil {
+		return parsed
+	}
+
+	// "*" is used by some servers to mean no account
+	if account := tags["account"]; account != "*" {
+		parsed.Account = account
+	}
+	if raw := tags["time"]; raw != "" {
+		// server-time is ISO 8601 in UTC with millisecond precision
+		if t, err := time.Parse("2006-01-02T15:04:05.000Z", raw); err == nil {
+			parsed.ServerTime = t
+		} else if t, err := time.Parse(time.RFC3339Nano, raw); err == nil {
+			parsed.ServerTime = t
+		}
+	}
+	parsed.MsgID = tags["msgid"]
+
+	return parsed
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseMessageTags(t *testing.T) {
+	tags := parseMessageTags(map[string]string{
+		"account":      "alice_account",
+		"time":         "2025-03-04T05:06:07.089Z",
+		"msgid":        "abc123",
+		"+draft/reply": "x",
+	})
+
+	if tags.Account != "alice_account" {
+		t.Errorf("Expected account alice_account, got %q", tags.Account)
+	}
+	expected := time.Date(2025, 3, 4, 5, 6, 7, 89_000_000, time.UTC)
+	if !tags.ServerTime.Equal(expected) {
+		t.Errorf("Expected server time %v, got %v", expected, tags.ServerTime)
+	}
+	if tags.MsgID != "abc123" {
+		t.Errorf("Expected msgid abc123, got %q", tags.MsgID)
+	}
+}
+
+func TestParseMessageTagsMissingOrInvalid(t *testing.T) {
+	tags := parseMessageTags(nil)
+	if tags != (MessageTags{}) {
+		t.Errorf("Expected zero tags for nil map, got %+v", tags)
+	}
+
+	tags = parseMessageTags(map[string]string{"account": "*", "time": "yesterday"})
+	if tags.Account != "" {
+		t.Errorf("Expected * account to mean logged out, got %q", tags.Account)
+	}
+	if !tags.ServerTime.IsZero() {
+		t.Errorf("Expected invalid server time to be ignored, got %v", tags.ServerTime)
+	}
+}
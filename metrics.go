@@ -0,0 +1,31 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics exposed on the shortener's /metrics endpoint, alongside
+// the shortener's own
+var (
+	executionsRun = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "irc_agent_executions_total",
+		Help: "Number of TypeScript executions run.",
+	})
+
+	executionFailures = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "irc_agent_execution_failures_total",
+		Help: "Number of TypeScript executions that failed or exited non-zero.",
+	})
+
+	executionDuration = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "irc_agent_execution_duration_seconds",
+		Help:    "Time spent running TypeScript executions.",
+		Buckets: []float64{0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	})
+
+	llmTokens = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "irc_agent_llm_tokens_total",
+		Help: "Number of LLM tokens used, by provider and direction (prompt or completion).",
+	}, []string{"provider", "type"})
+)
@@ -2,35 +2,74 @@ package anthropic
 
 import (
 	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
+	"net/http"
 	"strings"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
 	"google.golang.org/adk/model"
 	"google.golang.org/genai"
 )
 
+// Sentinel errors wrapped into API failures so callers can categorize them
+// without depending on the SDK's error types
+var (
+	// ErrAuthentication means Anthropic rejected the API key or the key lacks
+	// permission for the requested model
+	ErrAuthentication = errors.New("anthropic authentication failed")
+	// ErrRateLimited means the request was throttled
+	ErrRateLimited = errors.New("anthropic rate limit exceeded")
+	// ErrOverloaded means Anthropic is overloaded or returned a server error
+	ErrOverloaded = errors.New("anthropic service unavailable")
+)
+
+// messageClient is the part of the Anthropic SDK the model calls, so tests
+// can substitute a fake for the API
+type messageClient interface {
+	New(ctx context.Context, body anthropic.MessageNewParams, opts ...option.RequestOption) (*anthropic.Message, error)
+	NewStreaming(ctx context.Context, body anthropic.MessageNewParams, opts ...option.RequestOption) *ssestream.Stream[anthropic.MessageStreamEventUnion]
+}
+
 type anthropicModel struct {
-	client anthropic.Client
-	name   anthropic.Model
+	messages messageClient
+	name     anthropic.Model
+	vision   bool // send image parts to the model instead of dropping them
+}
+
+// Option customizes a model created by NewModel
+type Option func(*anthropicModel)
+
+// WithVision enables image input. Leave it off for models without vision
+// support; image parts are then dropped from requests.
+func WithVision(enabled bool) Option {
+	return func(m *anthropicModel) {
+		m.vision = enabled
+	}
 }
 
 // NewModel creates a new Anthropic model that implements the model.LLM interface.
 // modelName should be something like "claude-3-5-haiku-20241022" for Haiku 3.5
-func NewModel(ctx context.Context, modelName string, apiKey string) (model.LLM, error) {
+func NewModel(ctx context.Context, modelName string, apiKey string, opts ...Option) (model.LLM, error) {
 	if apiKey == "" {
 		return nil, fmt.Errorf("ANTHROPIC_API_KEY is required")
 	}
 
 	client := anthropic.NewClient(option.WithAPIKey(apiKey))
 
-	return &anthropicModel{
-		name:   anthropic.Model(modelName),
-		client: client,
-	}, nil
+	m := &anthropicModel{
+		name:     anthropic.Model(modelName),
+		messages: &client.Messages,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m, nil
 }
 
 func (m *anthropicModel) Name() string {
@@ -39,12 +78,32 @@ func (m *anthropicModel) Name() string {
 
 // GenerateContent implements the model.LLM interface for Anthropic
 func (m *anthropicModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	params := m.buildParams(req)
+
+	if stream {
+		return m.generateStream(ctx, params)
+	}
+
+	return func(yield func(*model.LLMResponse, error) bool) {
+		resp, err := m.generate(ctx, params)
+		yield(resp, err)
+	}
+}
+
+// buildParams converts an ADK request into Anthropic message parameters
+func (m *anthropicModel) buildParams(req *model.LLMRequest) anthropic.MessageNewParams {
 	// Convert genai.Content to Anthropic messages
-	messages, systemPrompt := convertToAnthropicMessages(req.Contents)
+	messages, systemPrompt := convertToAnthropicMessages(req.Contents, m.vision)
+
+	// Use the model requested for this call if set, e.g. by a per-channel override
+	modelName := m.name
+	if req.Model != "" {
+		modelName = anthropic.Model(req.Model)
+	}
 
 	// Build the Anthropic request
 	params := anthropic.MessageNewParams{
-		Model:     m.name,
+		Model:     modelName,
 		Messages:  messages,
 		MaxTokens: 4096,
 	}
@@ -82,21 +141,20 @@ func (m *anthropicModel) GenerateContent(ctx context.Context, req *model.LLMRequ
 		params.Temperature = anthropic.Float(temp)
 	}
 
-	if stream {
-		return m.generateStream(ctx, params)
+	// Set top_p if specified
+	if req.Config != nil && req.Config.TopP != nil {
+		topP := float64(*req.Config.TopP)
+		params.TopP = anthropic.Float(topP)
 	}
 
-	return func(yield func(*model.LLMResponse, error) bool) {
-		resp, err := m.generate(ctx, params)
-		yield(resp, err)
-	}
+	return params
 }
 
 // generate calls the Anthropic API synchronously
 func (m *anthropicModel) generate(ctx context.Context, params anthropic.MessageNewParams) (*model.LLMResponse, error) {
-	resp, err := m.client.Messages.New(ctx, params)
+	resp, err := m.messages.New(ctx, params)
 	if err != nil {
-		return nil, fmt.Errorf("failed to call Anthropic API: %w", err)
+		return nil, fmt.Errorf("failed to call Anthropic API: %w", wrapAPIError(err))
 	}
 
 	return convertToLLMResponse(resp), nil
@@ -105,7 +163,7 @@ func (m *anthropicModel) generate(ctx context.Context, params anthropic.MessageN
 // generateStream returns a stream of responses from Anthropic
 func (m *anthropicModel) generateStream(ctx context.Context, params anthropic.MessageNewParams) iter.Seq2[*model.LLMResponse, error] {
 	return func(yield func(*model.LLMResponse, error) bool) {
-		stream := m.client.Messages.NewStreaming(ctx, params)
+		stream := m.messages.NewStreaming(ctx, params)
 
 		var aggregatedText strings.Builder
 		accumulated := &anthropic.Message{}
@@ -142,7 +200,7 @@ func (m *anthropicModel) generateStream(ctx context.Context, params anthropic.Me
 		}
 
 		if err := stream.Err(); err != nil {
-			yield(nil, fmt.Errorf("stream error: %w", err))
+			yield(nil, fmt.Errorf("stream error: %w", wrapAPIError(err)))
 			return
 		}
 
@@ -153,9 +211,30 @@ func (m *anthropicModel) generateStream(ctx context.Context, params anthropic.Me
 	}
 }
 
+// wrapAPIError tags API failures with one of the sentinel errors above so
+// callers can tell a bad API key or an outage apart from other errors
+func wrapAPIError(err error) error {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+
+	switch {
+	case apiErr.StatusCode == http.StatusUnauthorized || apiErr.StatusCode == http.StatusForbidden:
+		return fmt.Errorf("%w: %w", ErrAuthentication, err)
+	case apiErr.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %w", ErrRateLimited, err)
+	case apiErr.StatusCode >= http.StatusInternalServerError:
+		// Includes Anthropic's non-standard 529 "overloaded" status
+		return fmt.Errorf("%w: %w", ErrOverloaded, err)
+	}
+	return err
+}
+
 // convertToAnthropicMessages converts genai.Content to Anthropic messages
-// Returns messages and system prompt separately
-func convertToAnthropicMessages(contents []*genai.Content) ([]anthropic.MessageParam, string) {
+// Returns messages and system prompt separately. Image parts are only
+// included when images is true.
+func convertToAnthropicMessages(contents []*genai.Content, images bool) ([]anthropic.MessageParam, string) {
 	var messages []anthropic.MessageParam
 	var systemPrompt string
 
@@ -184,6 +263,13 @@ func convertToAnthropicMessages(contents []*genai.Content) ([]anthropic.MessageP
 				contentBlocks = append(contentBlocks, anthropic.NewTextBlock(part.Text))
 			}
 
+			// Handle images, inline or by URL
+			if images {
+				if block, ok := convertImagePart(part); ok {
+					contentBlocks = append(contentBlocks, block)
+				}
+			}
+
 			// Handle function calls (tool uses)
 			if part.FunctionCall != nil {
 				toolUse := anthropic.NewToolUseBlock(
@@ -237,6 +323,22 @@ func convertToAnthropicMessages(contents []*genai.Content) ([]anthropic.MessageP
 	return messages, systemPrompt
 }
 
+// convertImagePart converts an inline image or image URL part into an
+// Anthropic image block. Parts that aren't images are reported as not ok.
+func convertImagePart(part *genai.Part) (anthropic.ContentBlockParamUnion, bool) {
+	if part.InlineData != nil && strings.HasPrefix(part.InlineData.MIMEType, "image/") {
+		data := base64.StdEncoding.EncodeToString(part.InlineData.Data)
+		return anthropic.NewImageBlockBase64(part.InlineData.MIMEType, data), true
+	}
+
+	if part.FileData != nil && strings.HasPrefix(part.FileData.MIMEType, "image/") &&
+		(strings.HasPrefix(part.FileData.FileURI, "https://") || strings.HasPrefix(part.FileData.FileURI, "http://")) {
+		return anthropic.NewImageBlock(anthropic.URLImageSourceParam{URL: part.FileData.FileURI}), true
+	}
+
+	return anthropic.ContentBlockParamUnion{}, false
+}
+
 // convertToAnthropicTools converts genai tools to Anthropic tools
 func convertToAnthropicTools(genaiTools []*genai.Tool) []anthropic.ToolUnionParam {
 	var tools []anthropic.ToolUnionParam
@@ -0,0 +1,459 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// fakeMessages stands in for the Anthropic API. New returns message or err;
+// NewStreaming replays stream, a text/event-stream body.
+type fakeMessages struct {
+	message *anthropic.Message
+	err     error
+	stream  string
+	params  anthropic.MessageNewParams // last request
+}
+
+func (f *fakeMessages) New(ctx context.Context, body anthropic.MessageNewParams, opts ...option.RequestOption) (*anthropic.Message, error) {
+	f.params = body
+	return f.message, f.err
+}
+
+func (f *fakeMessages) NewStreaming(ctx context.Context, body anthropic.MessageNewParams, opts ...option.RequestOption) *ssestream.Stream[anthropic.MessageStreamEventUnion] {
+	f.params = body
+	decoder := ssestream.NewDecoder(&http.Response{Body: io.NopCloser(strings.NewReader(f.stream))})
+	return ssestream.NewStream[anthropic.MessageStreamEventUnion](decoder, f.err)
+}
+
+// parseMessage builds an Anthropic message from its JSON form, as the SDK
+// would from an API response
+func parseMessage(t *testing.T, raw string) *anthropic.Message {
+	t.Helper()
+	var msg anthropic.Message
+	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		t.Fatalf("Failed to parse message: %v", err)
+	}
+	return &msg
+}
+
+// apiError is an SDK error for an HTTP status
+func apiError(status int) error {
+	req, _ := http.NewRequest(http.MethodPost, "https://api.anthropic.com/v1/messages", nil)
+	return &anthropic.Error{StatusCode: status, Request: req, Response: &http.Response{StatusCode: status}}
+}
+
+func TestBuildParamsPassesGenerationConfig(t *testing.T) {
+	m := &anthropicModel{name: anthropic.Model("claude-haiku-4-5")}
+
+	temperature := float32(0.25)
+	topP := float32(0.9)
+	req := &model.LLMRequest{
+		Contents: []*genai.Content{genai.NewContentFromText("hello", genai.RoleUser)},
+		Config: &genai.GenerateContentConfig{
+			Temperature: &temperature,
+			TopP:        &topP,
+		},
+	}
+
+	params := m.buildParams(req)
+
+	if !params.Temperature.Valid() || params.Temperature.Value != 0.25 {
+		t.Errorf("Expected temperature 0.25, got %+v", params.Temperature)
+	}
+	if !params.TopP.Valid() || float32(params.TopP.Value) != topP {
+		t.Errorf("Expected top_p 0.9, got %+v", params.TopP)
+	}
+	if params.Model != "claude-haiku-4-5" {
+		t.Errorf("Expected default model, got %s", params.Model)
+	}
+}
+
+func TestBuildParamsOmitsUnsetGenerationConfig(t *testing.T) {
+	m := &anthropicModel{name: anthropic.Model("claude-haiku-4-5")}
+
+	params := m.buildParams(&model.LLMRequest{Model: "claude-sonnet-4-5"})
+
+	if params.Temperature.Valid() || params.TopP.Valid() {
+		t.Errorf("Expected temperature and top_p to be unset, got %+v / %+v", params.Temperature, params.TopP)
+	}
+	if params.Model != "claude-sonnet-4-5" {
+		t.Errorf("Expected per-request model override, got %s", params.Model)
+	}
+}
+
+func TestConvertToLLMResponseEmptyContent(t *testing.T) {
+	resp := convertToLLMResponse(&anthropic.Message{StopReason: "end_turn"})
+
+	if resp.Content == nil {
+		t.Fatalf("Expected non-nil content for an empty message")
+	}
+	if len(resp.Content.Parts) != 0 {
+		t.Errorf("Expected no parts, got %d", len(resp.Content.Parts))
+	}
+	if resp.FinishReason != genai.FinishReasonStop {
+		t.Errorf("Expected finish reason STOP, got %s", resp.FinishReason)
+	}
+}
+
+func TestConvertImageParts(t *testing.T) {
+	contents := []*genai.Content{
+		{
+			Role: genai.RoleUser,
+			Parts: []*genai.Part{
+				genai.NewPartFromText("What is in these pictures?"),
+				genai.NewPartFromBytes([]byte{0x89, 'P', 'N', 'G'}, "image/png"),
+				genai.NewPartFromURI("https://example.com/cat.jpg", "image/jpeg"),
+			},
+		},
+	}
+
+	messages, _ := convertToAnthropicMessages(contents, true)
+	if len(messages) != 1 {
+		t.Fatalf("Expected 1 message, got %d", len(messages))
+	}
+	blocks := messages[0].Content
+	if len(blocks) != 3 {
+		t.Fatalf("Expected 3 content blocks, got %d", len(blocks))
+	}
+
+	inline := blocks[1].OfImage
+	if inline == nil || inline.Source.OfBase64 == nil {
+		t.Fatalf("Expected a base64 image block, got %+v", blocks[1])
+	}
+	if inline.Source.OfBase64.MediaType != "image/png" || inline.Source.OfBase64.Data != "iVBORw==" {
+		t.Errorf("Unexpected base64 image source: %+v", inline.Source.OfBase64)
+	}
+
+	byURL := blocks[2].OfImage
+	if byURL == nil || byURL.Source.OfURL == nil {
+		t.Fatalf("Expected a URL image block, got %+v", blocks[2])
+	}
+	if byURL.Source.OfURL.URL != "https://example.com/cat.jpg" {
+		t.Errorf("Expected image URL to be passed through, got %s", byURL.Source.OfURL.URL)
+	}
+
+	// Without vision the images are dropped and only the text remains
+	messages, _ = convertToAnthropicMessages(contents, false)
+	if len(messages[0].Content) != 1 || messages[0].Content[0].OfText == nil {
+		t.Errorf("Expected only the text block without vision, got %+v", messages[0].Content)
+	}
+}
+
+func TestConvertToAnthropicMessages(t *testing.T) {
+	tests := []struct {
+		name     string
+		contents []*genai.Content
+		system   string
+		check    func(t *testing.T, messages []anthropic.MessageParam)
+	}{
+		{
+			name:     "user text",
+			contents: []*genai.Content{genai.NewContentFromText("hello", genai.RoleUser)},
+			check: func(t *testing.T, messages []anthropic.MessageParam) {
+				if len(messages) != 1 || messages[0].Role != anthropic.MessageParamRoleUser {
+					t.Fatalf("Expected one user message, got %+v", messages)
+				}
+				if text := messages[0].Content[0].OfText; text == nil || text.Text != "hello" {
+					t.Errorf("Expected the text block, got %+v", messages[0].Content[0])
+				}
+			},
+		},
+		{
+			name:     "model text becomes assistant",
+			contents: []*genai.Content{genai.NewContentFromText("hi there", genai.RoleModel)},
+			check: func(t *testing.T, messages []anthropic.MessageParam) {
+				if len(messages) != 1 || messages[0].Role != anthropic.MessageParamRoleAssistant {
+					t.Errorf("Expected one assistant message, got %+v", messages)
+				}
+			},
+		},
+		{
+			name: "system messages are pulled out",
+			contents: []*genai.Content{
+				{Role: "system", Parts: []*genai.Part{{Text: "be brief"}, {Text: "be kind"}}},
+				genai.NewContentFromText("hello", genai.RoleUser),
+			},
+			system: "be brief\n\nbe kind",
+			check: func(t *testing.T, messages []anthropic.MessageParam) {
+				if len(messages) != 1 {
+					t.Errorf("Expected only the user message, got %d", len(messages))
+				}
+			},
+		},
+		{
+			name: "tool use",
+			contents: []*genai.Content{{
+				Role: genai.RoleModel,
+				Parts: []*genai.Part{{FunctionCall: &genai.FunctionCall{
+					ID: "call_1", Name: "calculate", Args: map[string]any{"expression": "1+1"},
+				}}},
+			}},
+			check: func(t *testing.T, messages []anthropic.MessageParam) {
+				use := messages[0].Content[0].OfToolUse
+				if use == nil || use.ID != "call_1" || use.Name != "calculate" {
+					t.Fatalf("Expected a tool_use block, got %+v", messages[0].Content[0])
+				}
+				if args, ok := use.Input.(map[string]any); !ok || args["expression"] != "1+1" {
+					t.Errorf("Expected the call arguments, got %+v", use.Input)
+				}
+			},
+		},
+		{
+			name: "tool result",
+			contents: []*genai.Content{{
+				Role: genai.RoleUser,
+				Parts: []*genai.Part{{FunctionResponse: &genai.FunctionResponse{
+					ID: "call_1", Name: "calculate", Response: map[string]any{"result": 2},
+				}}},
+			}},
+			check: func(t *testing.T, messages []anthropic.MessageParam) {
+				result := messages[0].Content[0].OfToolResult
+				if result == nil || result.ToolUseID != "call_1" {
+					t.Fatalf("Expected a tool_result block, got %+v", messages[0].Content[0])
+				}
+				if len(result.Content) != 1 || result.Content[0].OfText == nil || result.Content[0].OfText.Text != `{"result":2}` {
+					t.Errorf("Expected the response as JSON, got %+v", result.Content)
+				}
+			},
+		},
+		{
+			name: "nil and empty contents are skipped",
+			contents: []*genai.Content{
+				nil,
+				{Role: genai.RoleUser, Parts: []*genai.Part{{Text: ""}}},
+			},
+			check: func(t *testing.T, messages []anthropic.MessageParam) {
+				if len(messages) != 0 {
+					t.Errorf("Expected no messages, got %+v", messages)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			messages, system := convertToAnthropicMessages(tt.contents, false)
+			if system != tt.system {
+				t.Errorf("Expected system prompt %q, got %q", tt.system, system)
+			}
+			tt.check(t, messages)
+		})
+	}
+}
+
+func TestConvertToAnthropicTools(t *testing.T) {
+	tools := convertToAnthropicTools([]*genai.Tool{
+		nil,
+		{FunctionDeclarations: []*genai.FunctionDeclaration{
+			nil,
+			{
+				Name:        "calculate",
+				Description: "Evaluates arithmetic",
+				Parameters: &genai.Schema{
+					Type:       genai.TypeObject,
+					Properties: map[string]*genai.Schema{"expression": {Type: genai.TypeString}},
+					Required:   []string{"expression"},
+				},
+			},
+			{Name: "ping"},
+		}},
+	})
+
+	if len(tools) != 2 {
+		t.Fatalf("Expected 2 tools, got %d", len(tools))
+	}
+
+	calc := tools[0].OfTool
+	if calc == nil || calc.Name != "calculate" || calc.Description.Value != "Evaluates arithmetic" {
+		t.Fatalf("Unexpected calculate tool %+v", tools[0])
+	}
+	if calc.InputSchema.Type != "object" || len(calc.InputSchema.Required) != 1 || calc.InputSchema.Required[0] != "expression" {
+		t.Errorf("Unexpected input schema %+v", calc.InputSchema)
+	}
+	if props, ok := calc.InputSchema.Properties.(map[string]*genai.Schema); !ok || props["expression"] == nil {
+		t.Errorf("Expected the expression property, got %+v", calc.InputSchema.Properties)
+	}
+
+	ping := tools[1].OfTool
+	if ping == nil || ping.Description.Valid() {
+		t.Errorf("Expected a tool without description, got %+v", tools[1])
+	}
+	if props, ok := ping.InputSchema.Properties.(map[string]interface{}); !ok || len(props) != 0 {
+		t.Errorf("Expected empty properties, got %+v", ping.InputSchema.Properties)
+	}
+}
+
+func TestConvertToLLMResponse(t *testing.T) {
+	msg := parseMessage(t, `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-haiku-4-5",
+		"content": [
+			{"type": "text", "text": "Let me check"},
+			{"type": "tool_use", "id": "call_1", "name": "calculate", "input": {"expression": "2*3"}}
+		],
+		"stop_reason": "tool_use",
+		"usage": {"input_tokens": 10, "output_tokens": 5}
+	}`)
+
+	resp := convertToLLMResponse(msg)
+
+	if len(resp.Content.Parts) != 2 || resp.Content.Parts[0].Text != "Let me check" {
+		t.Fatalf("Expected text and a function call, got %+v", resp.Content.Parts)
+	}
+	call := resp.Content.Parts[1].FunctionCall
+	if call == nil || call.ID != "call_1" || call.Name != "calculate" || call.Args["expression"] != "2*3" {
+		t.Errorf("Unexpected function call %+v", call)
+	}
+	usage := resp.UsageMetadata
+	if usage.PromptTokenCount != 10 || usage.CandidatesTokenCount != 5 || usage.TotalTokenCount != 15 {
+		t.Errorf("Unexpected usage %+v", usage)
+	}
+	if !resp.TurnComplete {
+		t.Error("Expected the turn to be complete")
+	}
+}
+
+func TestConvertToLLMResponseFinishReasons(t *testing.T) {
+	tests := []struct {
+		stopReason anthropic.StopReason
+		expected   genai.FinishReason
+	}{
+		{"end_turn", genai.FinishReasonStop},
+		{"tool_use", genai.FinishReasonStop},
+		{"max_tokens", genai.FinishReasonMaxTokens},
+		{"stop_sequence", genai.FinishReasonOther},
+		{"", genai.FinishReasonOther},
+	}
+	for _, tt := range tests {
+		resp := convertToLLMResponse(&anthropic.Message{StopReason: tt.stopReason})
+		if resp.FinishReason != tt.expected {
+			t.Errorf("Stop reason %q: expected %s, got %s", tt.stopReason, tt.expected, resp.FinishReason)
+		}
+	}
+}
+
+func TestGenerateContentWithFakeClient(t *testing.T) {
+	fake := &fakeMessages{message: parseMessage(t, `{
+		"id": "msg_1", "type": "message", "role": "assistant", "model": "claude-haiku-4-5",
+		"content": [{"type": "text", "text": "pong"}],
+		"stop_reason": "end_turn",
+		"usage": {"input_tokens": 3, "output_tokens": 1}
+	}`)}
+	m := &anthropicModel{messages: fake, name: "claude-haiku-4-5"}
+
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("ping", genai.RoleUser)}}
+	var responses []*model.LLMResponse
+	for resp, err := range m.GenerateContent(context.Background(), req, false) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+
+	if len(responses) != 1 || responses[0].Content.Parts[0].Text != "pong" {
+		t.Fatalf("Expected one pong response, got %+v", responses)
+	}
+	if len(fake.params.Messages) != 1 || fake.params.Model != "claude-haiku-4-5" {
+		t.Errorf("Expected the request to reach the client, got %+v", fake.params)
+	}
+}
+
+func TestGenerateWrapsAPIErrors(t *testing.T) {
+	tests := []struct {
+		status   int
+		expected error
+	}{
+		{http.StatusUnauthorized, ErrAuthentication},
+		{http.StatusForbidden, ErrAuthentication},
+		{http.StatusTooManyRequests, ErrRateLimited},
+		{529, ErrOverloaded},
+		{http.StatusInternalServerError, ErrOverloaded},
+	}
+	for _, tt := range tests {
+		m := &anthropicModel{messages: &fakeMessages{err: apiError(tt.status)}}
+		_, err := m.generate(context.Background(), anthropic.MessageNewParams{})
+		if !errors.Is(err, tt.expected) {
+			t.Errorf("Status %d: expected %v, got %v", tt.status, tt.expected, err)
+		}
+	}
+
+	m := &anthropicModel{messages: &fakeMessages{err: apiError(http.StatusBadRequest)}}
+	_, err := m.generate(context.Background(), anthropic.MessageNewParams{})
+	if err == nil || errors.Is(err, ErrAuthentication) || errors.Is(err, ErrRateLimited) || errors.Is(err, ErrOverloaded) {
+		t.Errorf("Expected an uncategorized error for 400, got %v", err)
+	}
+}
+
+func TestGenerateStreamWithFakeClient(t *testing.T) {
+	fake := &fakeMessages{stream: strings.Join([]string{
+		"event: message_start",
+		`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-haiku-4-5","content":[],"stop_reason":null,"usage":{"input_tokens":4,"output_tokens":0}}}`,
+		"",
+		"event: content_block_start",
+		`data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		"",
+		"event: ping",
+		`data: {"type":"ping"}`,
+		"",
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}`,
+		"",
+		"event: content_block_delta",
+		`data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}`,
+		"",
+		"event: content_block_stop",
+		`data: {"type":"content_block_stop","index":0}`,
+		"",
+		"event: message_delta",
+		`data: {"type":"message_delta","delta":{"stop_reason":"end_turn"},"usage":{"output_tokens":2}}`,
+		"",
+		"event: message_stop",
+		`data: {"type":"message_stop"}`,
+		"",
+	}, "\n")}
+	m := &anthropicModel{messages: fake, name: "claude-haiku-4-5"}
+
+	var responses []*model.LLMResponse
+	for resp, err := range m.generateStream(context.Background(), anthropic.MessageNewParams{}) {
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		responses = append(responses, resp)
+	}
+
+	if len(responses) != 3 {
+		t.Fatalf("Expected two partial responses and a final one, got %d", len(responses))
+	}
+	if !responses[0].Partial || responses[1].Content.Parts[0].Text != "Hello" {
+		t.Errorf("Expected partials to aggregate text, got %+v / %+v", responses[0], responses[1])
+	}
+	final := responses[2]
+	if final.Partial || !final.TurnComplete || final.Content.Parts[0].Text != "Hello" {
+		t.Errorf("Unexpected final response %+v", final)
+	}
+	if final.FinishReason != genai.FinishReasonStop {
+		t.Errorf("Expected finish reason STOP, got %s", final.FinishReason)
+	}
+}
+
+func TestGenerateStreamReportsErrors(t *testing.T) {
+	m := &anthropicModel{messages: &fakeMessages{err: apiError(http.StatusTooManyRequests)}}
+
+	var gotErr error
+	for _, err := range m.generateStream(context.Background(), anthropic.MessageNewParams{}) {
+		gotErr = err
+	}
+	if !errors.Is(gotErr, ErrRateLimited) {
+		t.Errorf("Expected a rate limit error, got %v", gotErr)
+	}
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+)
+
+// NetworkConfig describes one IRC network the bot connects to
+type NetworkConfig struct {
+	Name     string   `json:"name,omitempty"` // label for logs and session IDs, defaults to the server host
+	Server   string   `json:"server"`         // same forms as SERVER
+	Channels []string `json:"channels"`
+	Nick     string   `json:"nick,omitempty"`     // defaults to IRC_NICK
+	User     string   `json:"user,omitempty"`     // defaults to IRC_USER, then the nick
+	RealName string   `json:"realname,omitempty"` // defaults to IRC_REALNAME, then the nick
+	TLS      bool     `json:"tls,omitempty"`      // use TLS even without an ircs:// server
+	Password string   `json:"password,omitempty"` // NickServ password, empty to skip identifying
+	// SASLLogin and SASLPassword authenticate with SASL PLAIN while
+	// registering, set together or not at all
+	SASLLogin    string `json:"sasl_login,omitempty"`
+	SASLPassword string `json:"sasl_password,omitempty"`
+}
+
+// ircNetwork is a validated NetworkConfig
+type ircNetwork struct {
+	name       string
+	serverAddr string
+	useTLS     bool
+	channels   []string
+	identity   IRCIdentity
+	password   string
+	sasl       saslCredentials // empty skips SASL
+}
+
+// saslCredentials are the account and password for SASL PLAIN
+type saslCredentials struct {
+	login    string
+	password string
+}
+
+// newSASLCredentials checks login and password are set together
+func newSASLCredentials(login, password string) (saslCredentials, error) {
+	if (login == "") != (password == "") {
+		return saslCredentials{}, fmt.Errorf("SASL login and password must be set together")
+	}
+	return saslCredentials{login: login, password: password}, nil
+}
+
+// loadNetworks returns the networks to connect to: the JSON list in
+// NETWORKS_FILE or NETWORKS when set, otherwise the single network described
+// by SERVER, CHANNEL, IRC_NICK and PASS.
+func loadNetworks() ([]ircNetwork, error) {
+	var data []byte
+	if path := os.Getenv("NETWORKS_FILE"); path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read NETWORKS_FILE: %w", err)
+		}
+		data = b
+	} else if inline := os.Getenv("NETWORKS"); inline != "" {
+		data = []byte(inline)
+	} else {
+		return networkFromEnv()
+	}
+
+	// Unknown fields are refused so a misspelt setting isn't silently dropped
+	var configs []NetworkConfig
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&configs); err != nil {
+		return nil, fmt.Errorf("failed to parse networks: %w", err)
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no networks configured")
+	}
+
+	seen := make(map[string]bool)
+	networks := make([]ircNetwork, 0, len(configs))
+	for i, cfg := range configs {
+		network, err := resolveNetwork(cfg)
+		if err != nil {
+			return nil, fmt.Errorf("network %d: %w", i+1, err)
+		}
+		// Names keep each network's sessions apart, so they must be unique
+		key := strings.ToLower(network.name)
+		if seen[key] {
+			return nil, fmt.Errorf("network %d: duplicate name %q", i+1, network.name)
+		}
+		seen[key] = true
+		networks = append(networks, network)
+	}
+	return networks, nil
+}
+
+// networkFromEnv builds the single network configured with SERVER and CHANNEL.
+// Its name is empty, which keeps session IDs the same as before networks existed.
+func networkFromEnv() ([]ircNetwork, error) {
+	server := os.Getenv("SERVER")
+	channel := os.Getenv("CHANNEL")
+	if server == "" || channel == "" {
+		return nil, fmt.Errorf("SERVER and CHANNEL environment variables are required")
+	}
+
+	// CHANNEL may list several channels, separated by commas
+	channels, err := parseChannels(channel)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CHANNEL: %w", err)
+	}
+
+	// Validate the server address up front rather than failing on connect
+	serverAddr, useTLS, err := parseIRCServer(server)
+	if err != nil {
+		return nil, fmt.Errorf("invalid SERVER: %w", err)
+	}
+
+	// Nick, username and real name the bot registers with
+	identity, err := loadIRCIdentity()
+	if err != nil {
+		return nil, err
+	}
+
+	sasl, err := newSASLCredentials(os.Getenv("SASL_LOGIN"), os.Getenv("SASL_PASSWORD"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SASL_LOGIN and SASL_PASSWORD: %w", err)
+	}
+
+	return []ircNetwork{{
+		serverAddr: serverAddr,
+		useTLS:     useTLS,
+		channels:   channels,
+		identity:   identity,
+		password:   os.Getenv("PASS"),
+		sasl:       sasl,
+	}}, nil
+}
+
+// resolveNetwork validates cfg and fills in defaults from the environment
+func resolveNetwork(cfg NetworkConfig) (ircNetwork, error) {
+	if cfg.Server == "" {
+		return ircNetwork{}, fmt.Errorf("server is required")
+	}
+	serverAddr, useTLS, err := parseIRCServer(cfg.Server)
+	if err != nil {
+		return ircNetwork{}, fmt.Errorf("invalid server: %w", err)
+	}
+
+	channels, err := parseChannels(strings.Join(cfg.Channels, ","))
+	if err != nil {
+		return ircNetwork{}, fmt.Errorf("invalid channels: %w", err)
+	}
+
+	identity, err := resolveIRCIdentity(
+		firstNonEmpty(cfg.Nick, os.Getenv("IRC_NICK")),
+		firstNonEmpty(cfg.User, os.Getenv("IRC_USER")),
+		firstNonEmpty(cfg.RealName, os.Getenv("IRC_REALNAME")),
+	)
+	if err != nil {
+		return ircNetwork{}, err
+	}
+
+	name := cfg.Name
+	if name == "" {
+		name, _, _ = net.SplitHostPort(serverAddr)
+	}
+	// Names prefix channels in user IDs, as in "libera/#go"
+	if strings.ContainsAny(name, " #/") {
+		return ircNetwork{}, fmt.Errorf("invalid name %q", name)
+	}
+
+	sasl, err := newSASLCredentials(cfg.SASLLogin, cfg.SASLPassword)
+	if err != nil {
+		return ircNetwork{}, err
+	}
+
+	return ircNetwork{
+		name:       name,
+		serverAddr: serverAddr,
+		useTLS:     useTLS || cfg.TLS,
+		channels:   channels,
+		identity:   identity,
+		password:   cfg.Password,
+		sasl:       sasl,
+	}, nil
+}
+
+// firstNonEmpty returns the first of values that isn't empty
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadNetworksFromEnv(t *testing.T) {
+	t.Setenv("NETWORKS", "")
+	t.Setenv("NETWORKS_FILE", "")
+	t.Setenv("SERVER", "ircs://irc.example.com")
+	t.Setenv("CHANNEL", "#a,#b")
+	t.Setenv("IRC_NICK", "helper")
+	t.Setenv("PASS", "secret")
+	t.Setenv("SASL_LOGIN", "helper")
+	t.Setenv("SASL_PASSWORD", "hunter2")
+
+	networks, err := loadNetworks()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(networks) != 1 {
+		t.Fatalf("Expected 1 network, got %d", len(networks))
+	}
+	n := networks[0]
+	if n.name != "" {
+		t.Errorf("Expected empty name, got %q", n.name)
+	}
+	if n.serverAddr != "irc.example.com:6697" || !n.useTLS {
+		t.Errorf("Expected irc.example.com:6697 with TLS, got %s (tls %v)", n.serverAddr, n.useTLS)
+	}
+	if len(n.channels) != 2 || n.identity.Nick != "helper" || n.password != "secret" {
+		t.Errorf("Unexpected network %+v", n)
+	}
+	if n.sasl != (saslCredentials{login: "helper", password: "hunter2"}) {
+		t.Errorf("Expected the SASL credentials, got %+v", n.sasl)
+	}
+
+	// A login without a password is refused
+	t.Setenv("SASL_PASSWORD", "")
+	if _, err := loadNetworks(); err == nil {
+		t.Error("Expected an error for a SASL login without a password")
+	}
+}
+
+func TestLoadNetworksJSON(t *testing.T) {
+	t.Setenv("NETWORKS_FILE", "")
+	t.Setenv("IRC_NICK", "helper")
+	t.Setenv("IRC_USER", "")
+	t.Setenv("IRC_REALNAME", "")
+	t.Setenv("NETWORKS", `[
+		{"name": "libera", "server": "ircs://irc.libera.chat", "channels": ["#a"], "password": "pw", "sasl_login": "helper", "sasl_password": "pw"},
+		{"server": "irc.oftc.net:6697", "tls": true, "channels": ["#b", "#c"], "nick": "other"}
+	]`)
+
+	networks, err := loadNetworks()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(networks) != 2 {
+		t.Fatalf("Expected 2 networks, got %d", len(networks))
+	}
+	if networks[0].name != "libera" || networks[0].identity.Nick != "helper" || networks[0].password != "pw" || networks[0].sasl.login != "helper" {
+		t.Errorf("Unexpected first network %+v", networks[0])
+	}
+	second := networks[1]
+	if second.name != "irc.oftc.net" {
+		t.Errorf("Expected name to default to the host, got %q", second.name)
+	}
+	if !second.useTLS || second.identity.Nick != "other" || second.identity.User != "other" || second.sasl.login != "" {
+		t.Errorf("Unexpected second network %+v", second)
+	}
+	if len(second.channels) != 2 {
+		t.Errorf("Expected 2 channels, got %v", second.channels)
+	}
+}
+
+func TestLoadNetworksFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "networks.json")
+	if err := os.WriteFile(path, []byte(`[{"name": "home", "server": "irc.example.com", "channels": ["#a"]}]`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("NETWORKS_FILE", path)
+	t.Setenv("NETWORKS", "not json")
+	t.Setenv("IRC_NICK", "")
+
+	networks, err := loadNetworks()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(networks) != 1 || networks[0].name != "home" {
+		t.Errorf("Expected the network from the file, got %+v", networks)
+	}
+}
+
+func TestLoadNetworksInvalid(t *testing.T) {
+	t.Setenv("NETWORKS_FILE", "")
+	t.Setenv("IRC_NICK", "")
+	cases := map[string]string{
+		"not json":          `{"server": "irc.example.com"}`,
+		"empty list":        `[]`,
+		"missing server":    `[{"channels": ["#a"]}]`,
+		"missing channels":  `[{"server": "irc.example.com"}]`,
+		"bad channel":       `[{"server": "irc.example.com", "channels": ["a"]}]`,
+		"bad nick":          `[{"server": "irc.example.com", "channels": ["#a"], "nick": "two words"}]`,
+		"bad name":          `[{"name": "my net", "server": "irc.example.com", "channels": ["#a"]}]`,
+		"slash in name":     `[{"name": "my/net", "server": "irc.example.com", "channels": ["#a"]}]`,
+		"duplicate name":    `[{"name": "a", "server": "one.example.com", "channels": ["#a"]}, {"name": "A", "server": "two.example.com", "channels": ["#b"]}]`,
+		"duplicate servers": `[{"server": "irc.example.com", "channels": ["#a"]}, {"server": "irc.example.com:6697", "channels": ["#b"]}]`,
+		"unknown field":     `[{"server": "irc.example.com", "channels": ["#a"], "pasword": "pw"}]`,
+		"half of SASL":      `[{"server": "irc.example.com", "channels": ["#a"], "sasl_login": "helper"}]`,
+	}
+	for name, value := range cases {
+		t.Setenv("NETWORKS", value)
+		if _, err := loadNetworks(); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestSessionIDPerNetwork(t *testing.T) {
+	single := &IRCAgent{}
+	if got := single.sessionID("#go"); got != "irc-session-#go" {
+		t.Errorf("Expected irc-session-#go, got %s", got)
+	}
+	named := &IRCAgent{network: "libera"}
+	if got := named.sessionID("#go"); got != "irc-session-libera-#go" {
+		t.Errorf("Expected irc-session-libera-#go, got %s", got)
+	}
+}
+
+func TestUserIDPerNetwork(t *testing.T) {
+	single := &IRCAgent{}
+	if got := single.userID("#go"); got != "#go" {
+		t.Errorf("Expected #go, got %s", got)
+	}
+	named := &IRCAgent{network: "libera"}
+	if got := named.userID("#go"); got != "libera/#go" {
+		t.Errorf("Expected libera/#go, got %s", got)
+	}
+
+	tests := map[string][2]string{
+		"libera/#go":   {"libera", "#go"},
+		"libera/alice": {"libera", "alice"},
+		"#go":          {"", "#go"},
+		"#a/b":         {"", "#a/b"},
+		"alice":        {"", "alice"},
+	}
+	for userID, want := range tests {
+		network, channel := splitUserID(userID)
+		if network != want[0] || channel != want[1] {
+			t.Errorf("splitUserID(%q) = %q, %q, want %q, %q", userID, network, channel, want[0], want[1])
+		}
+	}
+}
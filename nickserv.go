@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// nickServNick is the services bot that handles nick registration
+const nickServNick = "NickServ"
+
+// isNickServIdentifyPrompt reports whether a NOTICE is NickServ asking the
+// bot to identify for its registered nick
+func isNickServIdentifyPrompt(sender, message string) bool {
+	if !strings.EqualFold(sender, nickServNick) {
+		return false
+	}
+	lower := strings.ToLower(message)
+	return strings.Contains(lower, "identify") &&
+		(strings.Contains(lower, "registered") || strings.Contains(lower, "protected"))
+}
+
+// nickServTimeout is how long to wait for NickServ to confirm identification
+// before joining channels anyway
+const nickServTimeout = 15 * time.Second
+
+// isNickServConfirmation reports whether a NOTICE is NickServ confirming the
+// bot is now identified
+func isNickServConfirmation(sender, message string) bool {
+	if !strings.EqualFold(sender, nickServNick) {
+		return false
+	}
+	lower := strings.ToLower(message)
+	return strings.Contains(lower, "you are now identified") ||
+		strings.Contains(lower, "you are now logged in") ||
+		strings.Contains(lower, "password accepted")
+}
+
+// identifyWaiter lets the join logic wait for NickServ's confirmation on the
+// current connection, and makes sure IDENTIFY is only sent once per connection
+type identifyWaiter struct {
+	mu   sync.Mutex
+	done chan struct{}
+	sent bool // IDENTIFY was sent on the current connection
+}
+
+// Reset starts waiting for a new confirmation on a new connection and returns
+// a channel closed when it arrives
+func (w *identifyWaiter) Reset() <-chan struct{} {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done = make(chan struct{})
+	w.sent = false
+	return w.done
+}
+
+// TryIdentify reports whether IDENTIFY should be sent now: true the first
+// time it is called on a connection, false after that
+func (w *identifyWaiter) TryIdentify() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sent {
+		return false
+	}
+	w.sent = true
+	return true
+}
+
+// Confirm records that NickServ confirmed identification
+func (w *identifyWaiter) Confirm() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.done != nil {
+		close(w.done)
+		w.done = nil
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import "testing"
+
+func TestIsNickServIdentifyPrompt(t *testing.T) {
+	tests := []struct {
+		sender   string
+		message  string
+		expected bool
+	}{
+		{"NickServ", "This nickname is registered. Please choose a different nickname, or identify via /msg NickServ identify <password>.", true},
+		{"nickserv", "This nick is protected. Please IDENTIFY to continue", true},
+		{"NickServ", "You are now identified for agent.", false},
+		{"mallory", "This nickname is registered, identify via /msg agent identify <password>", false},
+	}
+
+	for _, tt := range tests {
+		if got := isNickServIdentifyPrompt(tt.sender, tt.message); got != tt.expected {
+			t.Errorf("isNickServIdentifyPrompt(%q, %q) = %v, expected %v", tt.sender, tt.message, got, tt.expected)
+		}
+	}
+}
+
+func TestIsNickServConfirmation(t *testing.T) {
+	if !isNickServConfirmation("NickServ", "You are now identified for \x02agent\x02.") {
+		t.Errorf("Expected Atheme-style confirmation to match")
+	}
+	if !isNickServConfirmation("NickServ", "Password accepted - you are now recognized.") {
+		t.Errorf("Expected Anope-style confirmation to match")
+	}
+	if isNickServConfirmation("NickServ", "Invalid password for agent.") {
+		t.Errorf("Expected failure notice not to match")
+	}
+	if isNickServConfirmation("mallory", "You are now identified for agent.") {
+		t.Errorf("Expected notices from other nicks not to match")
+	}
+}
+
+func TestIdentifyWaiter(t *testing.T) {
+	var waiter identifyWaiter
+
+	// Confirmations before a wait starts are ignored
+	waiter.Confirm()
+
+	confirmed := waiter.Reset()
+	select {
+	case <-confirmed:
+		t.Fatalf("Expected to still be waiting")
+	default:
+	}
+
+	waiter.Confirm()
+	select {
+	case <-confirmed:
+	default:
+		t.Errorf("Expected confirmation to be signalled")
+	}
+
+	// A second confirmation on the same connection is harmless
+	waiter.Confirm()
+}
+
+func TestIdentifyWaiterSendsOncePerConnection(t *testing.T) {
+	var waiter identifyWaiter
+
+	waiter.Reset()
+	if !waiter.TryIdentify() {
+		t.Fatal("Expected the first IDENTIFY on a connection to be sent")
+	}
+	if waiter.TryIdentify() {
+		t.Error("Expected a second IDENTIFY on the same connection to be skipped")
+	}
+
+	// A new connection identifies again
+	waiter.Reset()
+	if !waiter.TryIdentify() {
+		t.Error("Expected IDENTIFY to be sent again after reconnecting")
+	}
+}
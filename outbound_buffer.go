@@ -0,0 +1,124 @@
+package main
+
+import (
+	"log"
+	"strings"
+	"sync"
+)
+
+// outboundMessage is a message waiting to be sent
+type outboundMessage struct {
+	target  string
+	message string
+}
+
+// OutboundBuffer sends messages to IRC, holding them while the connection is
+// down and flushing them once the bot is back, so replies aren't lost across
+// a reconnect. Channel messages are held until the channel is rejoined.
+//
+// The sink is always called without the lock held, so a send that blocks on
+// a dead connection can't hold up other senders or the connection callbacks
+// that would mark it offline.
+type OutboundBuffer struct {
+	mu     sync.Mutex
+	size   int             // maximum held messages, oldest dropped first
+	online bool            // registered on a connection that hasn't been lost
+	sink   MessageSink     // writes a message to the connection
+	joined map[string]bool // lowercased channels joined on the current connection
+	queue  []outboundMessage
+}
+
+// NewOutboundBuffer creates a buffer holding up to size messages. It starts
+// offline, holding messages until Connected is called.
+func NewOutboundBuffer(size int, sink MessageSink) *OutboundBuffer {
+	return &OutboundBuffer{
+		size:   size,
+		sink:   sink,
+		joined: make(map[string]bool),
+	}
+}
+
+// isChannel reports whether target is a channel rather than a nick
+func isChannel(target string) bool {
+	return target != "" && strings.ContainsRune("#&+!", rune(target[0]))
+}
+
+// Send delivers message to target now if possible, otherwise holds it
+func (b *OutboundBuffer) Send(target, message string) {
+	b.mu.Lock()
+	if b.online && (!isChannel(target) || b.joined[strings.ToLower(target)]) {
+		b.mu.Unlock()
+		b.sink.Send(target, message)
+		return
+	}
+	defer b.mu.Unlock()
+
+	b.queue = append(b.queue, outboundMessage{target: target, message: message})
+	if len(b.queue) > b.size {
+		dropped := b.queue[0]
+		b.queue = b.queue[1:]
+		log.Printf("Outbound buffer full, dropping message to %s: %s", dropped.target, dropped.message)
+	}
+}
+
+// Connected is called once the bot has registered on a new connection. It
+// forgets joined channels and flushes messages to nicks.
+func (b *OutboundBuffer) Connected() {
+	b.mu.Lock()
+	b.online = true
+	b.joined = make(map[string]bool)
+	ready := b.take(func(target string) bool { return !isChannel(target) })
+	b.mu.Unlock()
+	b.send(ready)
+}
+
+// Disconnected is called when the connection is lost. Messages are held from
+// now on, and channels have to be rejoined on the next connection.
+func (b *OutboundBuffer) Disconnected() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.online = false
+	b.joined = make(map[string]bool)
+}
+
+// Joined is called when the bot joins channel. It flushes messages held for it.
+func (b *OutboundBuffer) Joined(channel string) {
+	b.mu.Lock()
+	b.joined[strings.ToLower(channel)] = true
+	var ready []outboundMessage
+	if b.online {
+		ready = b.take(func(target string) bool { return strings.EqualFold(target, channel) })
+	}
+	b.mu.Unlock()
+	b.send(ready)
+}
+
+// Parted is called when the bot leaves or is kicked from channel
+func (b *OutboundBuffer) Parted(channel string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	delete(b.joined, strings.ToLower(channel))
+}
+
+// take removes and returns held messages whose target matches, in order,
+// keeping the rest. The caller holds b.mu.
+func (b *OutboundBuffer) take(match func(target string) bool) []outboundMessage {
+	var taken []outboundMessage
+	kept := b.queue[:0]
+	for _, m := range b.queue {
+		if match(m.target) {
+			taken = append(taken, m)
+		} else {
+			kept = append(kept, m)
+		}
+	}
+	b.queue = kept
+	return taken
+}
+
+// send writes messages to the sink in order
+func (b *OutboundBuffer) send(messages []outboundMessage) {
+	for _, m := range messages {
+		b.sink.Send(m.target, m.message)
+	}
+}
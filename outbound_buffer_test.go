@@ -0,0 +1,120 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeConnection records what an OutboundBuffer sends
+type fakeConnection struct {
+	sent []string
+}
+
+func (c *fakeConnection) send(target, message string) {
+	c.sent = append(c.sent, target+" "+message)
+}
+
+func newTestBuffer(size int) (*OutboundBuffer, *fakeConnection) {
+	conn := &fakeConnection{}
+	return NewOutboundBuffer(size, MessageSinkFunc(conn.send)), conn
+}
+
+func TestOutboundBufferReconnect(t *testing.T) {
+	buffer, conn := newTestBuffer(10)
+	buffer.Connected()
+	buffer.Joined("#agent")
+
+	buffer.Send("#agent", "before")
+
+	// Connection drops mid-response
+	buffer.Disconnected()
+	buffer.Send("#agent", "during 1")
+	buffer.Send("alice", "private")
+	buffer.Send("#agent", "during 2")
+
+	if len(conn.sent) != 1 {
+		t.Fatalf("Expected only the message before the disconnect to be sent, got %v", conn.sent)
+	}
+
+	// Reconnected: messages to nicks go out, channel messages wait for the rejoin
+	buffer.Connected()
+	expected := []string{"#agent before", "alice private"}
+	if !reflect.DeepEqual(conn.sent, expected) {
+		t.Errorf("Expected %v after reconnect, got %v", expected, conn.sent)
+	}
+
+	buffer.Joined("#AGENT")
+	expected = append(expected, "#agent during 1", "#agent during 2")
+	if !reflect.DeepEqual(conn.sent, expected) {
+		t.Errorf("Expected %v after rejoin, got %v", expected, conn.sent)
+	}
+
+	// Once rejoined, messages go straight out
+	buffer.Send("#agent", "after")
+	if conn.sent[len(conn.sent)-1] != "#agent after" {
+		t.Errorf("Expected message to be sent immediately, got %v", conn.sent)
+	}
+}
+
+func TestOutboundBufferDropsOldest(t *testing.T) {
+	// Offline until the first connection
+	buffer, conn := newTestBuffer(2)
+
+	for i := 1; i <= 3; i++ {
+		buffer.Send("alice", fmt.Sprintf("message %d", i))
+	}
+
+	buffer.Connected()
+
+	expected := []string{"alice message 2", "alice message 3"}
+	if !reflect.DeepEqual(conn.sent, expected) {
+		t.Errorf("Expected %v, got %v", expected, conn.sent)
+	}
+}
+
+func TestOutboundBufferBlockedSink(t *testing.T) {
+	var mu sync.Mutex
+	var sent []string
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	buffer := NewOutboundBuffer(10, MessageSinkFunc(func(target, message string) {
+		// The first write hangs, as one into a dead connection does
+		if message == "stuck" {
+			close(entered)
+			<-release
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		sent = append(sent, target+" "+message)
+	}))
+	buffer.Connected()
+	buffer.Joined("#agent")
+
+	go buffer.Send("#agent", "stuck")
+	<-entered
+
+	// Nothing else waits on the blocked write
+	done := make(chan struct{})
+	go func() {
+		buffer.Disconnected()
+		buffer.Send("#agent", "held")
+		buffer.Connected()
+		buffer.Joined("#agent")
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the buffer to keep working while a write is blocked")
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(sent, []string{"#agent held"}) {
+		t.Errorf("Expected the held message to be flushed on rejoin, got %v", sent)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/r33drichards/irc-agent/shortener"
+	"google.golang.org/adk/tool"
+)
+
+// pasteMaxBytes is the largest paste create_paste accepts
+const pasteMaxBytes = 1 << 20
+
+// pasteLanguagePattern limits language hints to names that are safe in a CSS class
+var pasteLanguagePattern = regexp.MustCompile(`^[a-z0-9+#-]{1,32}$`)
+
+// CreatePasteParams defines the input parameters for the create_paste tool
+type CreatePasteParams struct {
+	Content  string `json:"content" jsonschema:"The text or code to share"`
+	Language string `json:"language,omitempty" jsonschema:"Optional syntax hint such as go, python or typescript"`
+	Title    string `json:"title,omitempty" jsonschema:"Optional title shown above the paste"`
+}
+
+// CreatePasteResults defines the output of the create_paste tool
+type CreatePasteResults struct {
+	Status       string `json:"status"`
+	ShortURL     string `json:"short_url,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// Paster shares long text as a paste page so it doesn't have to be dumped
+// into the channel
+type Paster struct {
+	store     ArtifactStore
+	shortener *shortener.URLShortener
+}
+
+// NewPaster creates a paster uploading to store and shortening links with shortener
+func NewPaster(store ArtifactStore, shortener *shortener.URLShortener) *Paster {
+	return &Paster{store: store, shortener: shortener}
+}
+
+// CreatePaste uploads the content as an HTML page and returns a short link to it
+func (p *Paster) CreatePaste(ctx tool.Context, params CreatePasteParams) CreatePasteResults {
+	if params.Content == "" {
+		return CreatePasteResults{Status: "error", ErrorMessage: "content is required"}
+	}
+	if len(params.Content) > pasteMaxBytes {
+		return CreatePasteResults{Status: "error", ErrorMessage: fmt.Sprintf("Paste is too large: %d bytes (max %d)", len(params.Content), pasteMaxBytes)}
+	}
+
+	language := strings.ToLower(strings.TrimSpace(params.Language))
+	if language != "" && !pasteLanguagePattern.MatchString(language) {
+		return CreatePasteResults{Status: "error", ErrorMessage: fmt.Sprintf("Invalid language hint %q", params.Language)}
+	}
+
+	signedURL, err := p.store.Upload(toolContext(ctx), renderPaste(params.Title, language, params.Content), "text/html; charset=utf-8")
+	if err != nil {
+		return CreatePasteResults{Status: "error", ErrorMessage: fmt.Sprintf("Failed to upload paste: %v", err)}
+	}
+
+	link := signedURL
+	if p.shortener != nil {
+		// The uploaded URL still works if it can't be shortened
+		if short, err := p.shortener.GetShortURL(signedURL); err != nil {
+			log.Printf("Warning: Failed to shorten paste URL: %v", err)
+		} else {
+			link = short
+		}
+	}
+	return CreatePasteResults{Status: "success", ShortURL: link}
+}
+
+// renderPaste builds a self-contained HTML page for a paste. The language is
+// kept as a meta tag and a language-* class, the convention syntax
+// highlighters look for.
+func renderPaste(title, language, content string) string {
+	if title == "" {
+		title = "Paste"
+	}
+
+	var b strings.Builder
+	b.WriteString("<!DOCTYPE html>\n<html>\n<head>\n<meta charset=\"utf-8\">\n")
+	b.WriteString("<meta name=\"viewport\" content=\"width=device-width, initial-scale=1\">\n")
+	fmt.Fprintf(&b, "<title>%s</title>\n", html.EscapeString(title))
+	if language != "" {
+		fmt.Fprintf(&b, "<meta name=\"language\" content=\"%s\">\n", language)
+	}
+	b.WriteString("<style>body{margin:0;font-family:sans-serif}h1{font-size:1rem;padding:.5rem 1rem}pre{margin:0;padding:1rem;background:#f6f8fa;overflow-x:auto;tab-size:4}</style>\n")
+	b.WriteString("</head>\n<body>\n")
+	fmt.Fprintf(&b, "<h1>%s</h1>\n", html.EscapeString(title))
+	if language != "" {
+		fmt.Fprintf(&b, "<pre><code class=\"language-%s\">", language)
+	} else {
+		b.WriteString("<pre><code>")
+	}
+	b.WriteString(html.EscapeString(content))
+	b.WriteString("</code></pre>\n</body>\n</html>\n")
+	return b.String()
+}
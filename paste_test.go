@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+func TestCreatePaste(t *testing.T) {
+	dir := t.TempDir()
+	store := &FileArtifactStore{Dir: dir, BaseURL: "http://short.test"}
+	shortener := shortener.NewURLShortener("http://short.test", shortener.NewInMemoryStorage())
+	paster := NewPaster(store, shortener)
+
+	result := paster.CreatePaste(nil, CreatePasteParams{
+		Content:  "if a < b && b > c {\n\treturn\n}",
+		Language: "Go",
+		Title:    "<example>",
+	})
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+
+	signedURL, err := shortener.Resolve(t.Context(), result.ShortURL)
+	if err != nil {
+		t.Fatalf("Expected the short URL to resolve, got %v", err)
+	}
+	if !strings.HasSuffix(signedURL, ".html") {
+		t.Errorf("Expected the paste to be stored as HTML, got %s", signedURL)
+	}
+
+	page, err := os.ReadFile(filepath.Join(dir, filepath.Base(signedURL)))
+	if err != nil {
+		t.Fatalf("Failed to read paste: %v", err)
+	}
+	for _, want := range []string{
+		`<meta name="language" content="go">`,
+		`<code class="language-go">if a &lt; b &amp;&amp; b &gt; c {`,
+		`<title>&lt;example&gt;</title>`,
+	} {
+		if !strings.Contains(string(page), want) {
+			t.Errorf("Expected paste page to contain %q, got:\n%s", want, page)
+		}
+	}
+}
+
+// unwritableStorage is shortener storage that fails every write
+type unwritableStorage struct {
+	*shortener.InMemoryStorage
+}
+
+func (unwritableStorage) Set(ctx context.Context, id, url string) error {
+	return errors.New("connection refused")
+}
+
+func (unwritableStorage) SetIfAbsent(ctx context.Context, id, url string) (bool, error) {
+	return false, errors.New("connection refused")
+}
+
+func TestCreatePasteShortenerFailure(t *testing.T) {
+	store := &fakeArtifactStore{}
+	us := shortener.NewURLShortener("http://short.test", unwritableStorage{shortener.NewInMemoryStorage()})
+	paster := NewPaster(store, us)
+
+	// The paste is uploaded, so its unshortened link is still worth returning
+	result := paster.CreatePaste(nil, CreatePasteParams{Content: "hello"})
+	if result.Status != "success" || result.ShortURL != "https://artifacts.example.com/upload" {
+		t.Errorf("Expected the uploaded URL, got %+v", result)
+	}
+}
+
+func TestCreatePasteRejectsBadInput(t *testing.T) {
+	paster := NewPaster(&FileArtifactStore{Dir: t.TempDir()}, nil)
+
+	for _, params := range []CreatePasteParams{
+		{},
+		{Content: "x", Language: `go"><script>`},
+		{Content: strings.Repeat("x", pasteMaxBytes+1)},
+	} {
+		if result := paster.CreatePaste(nil, params); result.Status != "error" {
+			t.Errorf("Expected an error, got %+v", result)
+		}
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"log"
+	"math"
+	"strings"
+
+	"github.com/r33drichards/irc-agent/shortener"
+	chart "github.com/wcharczuk/go-chart/v2"
+	"google.golang.org/adk/tool"
+)
+
+// Limits on render_chart specs
+const (
+	chartMaxSeries = 10
+	chartMaxPoints = 1000
+)
+
+// ChartSeries is one named series of values in a chart spec
+type ChartSeries struct {
+	Name   string    `json:"name,omitempty" jsonschema:"Series name shown in the legend"`
+	Values []float64 `json:"values" jsonschema:"The data points, in order"`
+}
+
+// RenderChartParams defines the input parameters for the render_chart tool
+type RenderChartParams struct {
+	Type   string        `json:"type" jsonschema:"Chart type: line, bar or pie"`
+	Title  string        `json:"title,omitempty" jsonschema:"Optional title shown above the chart"`
+	Labels []string      `json:"labels,omitempty" jsonschema:"Optional label for each data point: x axis ticks for line charts, bar or slice names otherwise"`
+	Series []ChartSeries `json:"series" jsonschema:"The data. Line charts take up to 10 series, bar and pie charts exactly one"`
+}
+
+// RenderChartResults defines the output of the render_chart tool
+type RenderChartResults struct {
+	Status       string `json:"status"`
+	ShortURL     string `json:"short_url,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// ChartRenderer draws charts from simple data specs as PNGs, so the model
+// doesn't have to write canvas code to visualize data
+type ChartRenderer struct {
+	store     ArtifactStore
+	shortener *shortener.URLShortener
+}
+
+// NewChartRenderer creates a renderer uploading to store and shortening links with shortener
+func NewChartRenderer(store ArtifactStore, shortener *shortener.URLShortener) *ChartRenderer {
+	return &ChartRenderer{store: store, shortener: shortener}
+}
+
+// RenderChart renders the chart, uploads it as a PNG and returns a short link to it
+func (r *ChartRenderer) RenderChart(ctx tool.Context, params RenderChartParams) RenderChartResults {
+	png, err := renderChartPNG(params)
+	if err != nil {
+		return RenderChartResults{Status: "error", ErrorMessage: err.Error()}
+	}
+
+	link, err := r.store.Upload(toolContext(ctx), string(png), "image/png")
+	if err != nil {
+		return RenderChartResults{Status: "error", ErrorMessage: fmt.Sprintf("Failed to upload chart: %v", err)}
+	}
+	if r.shortener != nil {
+		// The uploaded URL still works if it can't be shortened
+		if short, err := r.shortener.GetShortURL(link); err != nil {
+			log.Printf("Warning: Failed to shorten chart URL: %v", err)
+		} else {
+			link = short
+		}
+	}
+	return RenderChartResults{Status: "success", ShortURL: link}
+}
+
+// validateChart checks a spec against the limits and what its type needs
+func validateChart(params RenderChartParams) error {
+	if len(params.Series) == 0 {
+		return fmt.Errorf("at least one series is required")
+	}
+	if len(params.Series) > chartMaxSeries {
+		return fmt.Errorf("at most %d series are allowed, got %d", chartMaxSeries, len(params.Series))
+	}
+	for i, s := range params.Series {
+		if len(s.Values) == 0 {
+			return fmt.Errorf("series %d has no values", i+1)
+		}
+		if len(s.Values) > chartMaxPoints {
+			return fmt.Errorf("series %d has %d values, at most %d are allowed", i+1, len(s.Values), chartMaxPoints)
+		}
+		for _, v := range s.Values {
+			if math.IsNaN(v) || math.IsInf(v, 0) {
+				return fmt.Errorf("series %d has a value that isn't a finite number", i+1)
+			}
+		}
+	}
+	return nil
+}
+
+// renderChartPNG draws the chart described by params as a PNG
+func renderChartPNG(params RenderChartParams) ([]byte, error) {
+	if err := validateChart(params); err != nil {
+		return nil, err
+	}
+
+	var renderable interface {
+		Render(rp chart.RendererProvider, w io.Writer) error
+	}
+	switch kind := strings.ToLower(params.Type); kind {
+	case "line":
+		renderable = lineChart(params)
+	case "bar", "pie":
+		if len(params.Series) != 1 {
+			return nil, fmt.Errorf("%s charts take exactly one series, got %d", kind, len(params.Series))
+		}
+		values := chartValues(params.Labels, params.Series[0].Values)
+		if kind == "bar" {
+			renderable = barChart(params.Title, values)
+		} else {
+			for _, v := range values {
+				if v.Value <= 0 {
+					return nil, fmt.Errorf("pie chart values must be positive, got %g", v.Value)
+				}
+			}
+			renderable = &chart.PieChart{Title: params.Title, Width: 512, Height: 512, Values: values}
+		}
+	default:
+		return nil, fmt.Errorf("chart type must be line, bar or pie, got %q", params.Type)
+	}
+
+	var buf bytes.Buffer
+	if err := renderable.Render(chart.PNG, &buf); err != nil {
+		return nil, fmt.Errorf("failed to render chart: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// lineChart builds a line chart with one line per series, plotted against the
+// point index and labelled with labels when given
+func lineChart(params RenderChartParams) *chart.Chart {
+	graph := &chart.Chart{Title: params.Title}
+	for _, s := range params.Series {
+		xs := make([]float64, len(s.Values))
+		for i := range xs {
+			xs[i] = float64(i)
+		}
+		// A single point has no range to plot, so it is drawn as a flat line
+		ys := s.Values
+		if len(xs) == 1 {
+			xs, ys = []float64{0, 1}, []float64{ys[0], ys[0]}
+		}
+		graph.Series = append(graph.Series, chart.ContinuousSeries{Name: s.Name, XValues: xs, YValues: ys})
+	}
+	for i, label := range params.Labels {
+		graph.XAxis.Ticks = append(graph.XAxis.Ticks, chart.Tick{Value: float64(i), Label: label})
+	}
+	if len(params.Series) > 1 {
+		graph.Elements = []chart.Renderable{chart.Legend(graph)}
+	}
+	return graph
+}
+
+// barChart builds a bar chart of values. The chart's Y range comes from the
+// values and can't be empty, so when they are all equal, as with a single
+// bar, the axis is set to run from zero to them (or to 1 when all are zero).
+func barChart(title string, values []chart.Value) *chart.BarChart {
+	graph := &chart.BarChart{Title: title, Height: 512, BarWidth: 40, Bars: values}
+	lo, hi := values[0].Value, values[0].Value
+	for _, v := range values[1:] {
+		lo, hi = min(lo, v.Value), max(hi, v.Value)
+	}
+	if lo == hi {
+		lo, hi = min(lo, 0), max(hi, 0)
+		if lo == hi {
+			hi = 1
+		}
+		graph.YAxis.Range = &chart.ContinuousRange{Min: lo, Max: hi}
+	}
+	return graph
+}
+
+// chartValues pairs values with their labels for bar and pie charts
+func chartValues(labels []string, values []float64) []chart.Value {
+	out := make([]chart.Value, len(values))
+	for i, v := range values {
+		label := fmt.Sprintf("%d", i+1)
+		if i < len(labels) && labels[i] != "" {
+			label = labels[i]
+		}
+		out[i] = chart.Value{Value: v, Label: label}
+	}
+	return out
+}
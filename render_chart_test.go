@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+func TestRenderChartUploadsPNG(t *testing.T) {
+	store := &fakeArtifactStore{}
+	us := shortener.NewURLShortener("http://short.test", shortener.NewInMemoryStorage())
+	renderer := NewChartRenderer(store, us)
+
+	specs := []RenderChartParams{
+		{Type: "line", Title: "Requests", Labels: []string{"mon", "tue", "wed"}, Series: []ChartSeries{
+			{Name: "api", Values: []float64{3, 5, 4}},
+			{Name: "web", Values: []float64{1, 2, 6}},
+		}},
+		{Type: "bar", Labels: []string{"go", "rust"}, Series: []ChartSeries{{Values: []float64{10, 7}}}},
+		{Type: "Pie", Series: []ChartSeries{{Values: []float64{1, 2, 3}}}},
+		{Type: "line", Series: []ChartSeries{{Values: []float64{42}}}},
+		{Type: "bar", Series: []ChartSeries{{Values: []float64{5}}}},
+		{Type: "bar", Labels: []string{"a", "b", "c"}, Series: []ChartSeries{{Values: []float64{3, 3, 3}}}},
+		{Type: "bar", Series: []ChartSeries{{Values: []float64{0, 0}}}},
+		{Type: "line", Series: []ChartSeries{{Values: []float64{2, 2, 2}}}},
+	}
+	for _, spec := range specs {
+		result := renderer.RenderChart(nil, spec)
+		if result.Status != "success" {
+			t.Errorf("%s chart: Expected success, got %+v", spec.Type, result)
+			continue
+		}
+		if !strings.HasPrefix(result.ShortURL, "http://short.test/") {
+			t.Errorf("%s chart: Expected a short link, got %s", spec.Type, result.ShortURL)
+		}
+	}
+
+	if len(store.uploads) != len(specs) {
+		t.Fatalf("Expected %d uploads, got %d", len(specs), len(store.uploads))
+	}
+	for i, upload := range store.uploads {
+		if store.contentTypes[i] != "image/png" {
+			t.Errorf("Expected image/png uploads, got %s", store.contentTypes[i])
+		}
+		if _, err := png.Decode(bytes.NewReader([]byte(upload))); err != nil {
+			t.Errorf("Expected upload %d to be a valid PNG: %v", i, err)
+		}
+	}
+}
+
+func TestRenderChartRejectsBadSpecs(t *testing.T) {
+	store := &fakeArtifactStore{}
+	renderer := NewChartRenderer(store, nil)
+
+	for _, spec := range []RenderChartParams{
+		{Type: "line"},
+		{Type: "scatter", Series: []ChartSeries{{Values: []float64{1, 2}}}},
+		{Type: "bar", Series: []ChartSeries{{Values: []float64{1}}, {Values: []float64{2}}}},
+		{Type: "pie", Series: []ChartSeries{{Values: []float64{1, -2}}}},
+		{Type: "line", Series: []ChartSeries{{Values: make([]float64, chartMaxPoints+1)}}},
+		{Type: "line", Series: make([]ChartSeries, chartMaxSeries+1)},
+	} {
+		if result := renderer.RenderChart(nil, spec); result.Status != "error" {
+			t.Errorf("Expected an error for %+v, got %+v", spec, result)
+		}
+	}
+	if len(store.uploads) != 0 {
+		t.Errorf("Expected nothing to be uploaded for bad specs, got %d uploads", len(store.uploads))
+	}
+}
+
+func TestRenderChartUploadFailure(t *testing.T) {
+	renderer := NewChartRenderer(failingArtifactStore{}, nil)
+	result := renderer.RenderChart(nil, RenderChartParams{Type: "bar", Series: []ChartSeries{{Values: []float64{1, 2}}}})
+	if result.Status != "error" || !strings.Contains(result.ErrorMessage, "upload") {
+		t.Errorf("Expected an upload error, got %+v", result)
+	}
+}
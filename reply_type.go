@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Reply types selectable with REPLY_TYPE
+const (
+	replyPrivmsg = "privmsg"
+	replyNotice  = "notice"
+)
+
+// parseReplyType validates REPLY_TYPE, defaulting to privmsg
+func parseReplyType(raw string) (string, error) {
+	switch replyType := strings.ToLower(strings.TrimSpace(raw)); replyType {
+	case "", replyPrivmsg:
+		return replyPrivmsg, nil
+	case replyNotice:
+		return replyNotice, nil
+	default:
+		return "", fmt.Errorf("REPLY_TYPE must be privmsg or notice, got %q", raw)
+	}
+}
+
+// replySender picks the function used to send replies for replyType
+func replySender(replyType string, privmsg, notice func(target, message string)) func(target, message string) {
+	if replyType == replyNotice {
+		return notice
+	}
+	return privmsg
+}
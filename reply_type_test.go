@@ -0,0 +1,43 @@
+package main
+
+import "testing"
+
+func TestParseReplyType(t *testing.T) {
+	tests := []struct {
+		raw      string
+		expected string
+	}{
+		{"", replyPrivmsg},
+		{"privmsg", replyPrivmsg},
+		{" NOTICE ", replyNotice},
+	}
+	for _, tt := range tests {
+		got, err := parseReplyType(tt.raw)
+		if err != nil {
+			t.Errorf("parseReplyType(%q) returned error: %v", tt.raw, err)
+		}
+		if got != tt.expected {
+			t.Errorf("parseReplyType(%q) = %q, expected %q", tt.raw, got, tt.expected)
+		}
+	}
+
+	if _, err := parseReplyType("action"); err == nil {
+		t.Errorf("Expected error for unknown reply type")
+	}
+}
+
+func TestReplySender(t *testing.T) {
+	var used string
+	privmsg := func(target, message string) { used = "privmsg" }
+	notice := func(target, message string) { used = "notice" }
+
+	replySender(replyNotice, privmsg, notice)("#agent", "hi")
+	if used != "notice" {
+		t.Errorf("Expected notice sender, got %s", used)
+	}
+
+	replySender(replyPrivmsg, privmsg, notice)("#agent", "hi")
+	if used != "privmsg" {
+		t.Errorf("Expected privmsg sender, got %s", used)
+	}
+}
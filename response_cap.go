@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+// defaultMaxResponseLines is how many IRC lines a reply may take by default
+const defaultMaxResponseLines = 10
+
+// ResponseCap keeps verbose replies from flooding a channel. A reply longer
+// than the cap is cut to its first lines plus a link to the full text.
+type ResponseCap struct {
+	maxLines  int           // lines a reply may take, including the link; zero or less for no cap
+	store     ArtifactStore // where full replies are uploaded; nil to only truncate
+	shortener *shortener.URLShortener
+}
+
+// NewResponseCap creates a cap of maxLines, uploading full replies to store
+func NewResponseCap(maxLines int, store ArtifactStore, shortener *shortener.URLShortener) *ResponseCap {
+	return &ResponseCap{maxLines: maxLines, store: store, shortener: shortener}
+}
+
+// Apply returns the lines to send for a reply of text split into lines. Over
+// the cap, the last line sent points to the full text instead.
+func (c *ResponseCap) Apply(ctx context.Context, text string, lines []string) []string {
+	if c.maxLines <= 0 || len(lines) <= c.maxLines {
+		return lines
+	}
+
+	kept := append([]string{}, lines[:c.maxLines-1]...)
+	more := len(lines) - len(kept)
+	if link := c.upload(ctx, text); link != "" {
+		return append(kept, fmt.Sprintf("... %d more lines: %s", more, link))
+	}
+	return append(kept, fmt.Sprintf("... %d more lines not shown", more))
+}
+
+// upload stores the full reply and returns a link to it, or "" if it can't
+func (c *ResponseCap) upload(ctx context.Context, text string) string {
+	if c.store == nil {
+		return ""
+	}
+	link, err := c.store.Upload(ctx, text, "text/plain; charset=utf-8")
+	if err != nil {
+		log.Printf("Warning: Failed to upload long reply: %v", err)
+		return ""
+	}
+	if c.shortener == nil {
+		return link
+	}
+	// The uploaded URL still works if it can't be shortened
+	short, err := c.shortener.GetShortURL(link)
+	if err != nil {
+		log.Printf("Warning: Failed to shorten long reply URL: %v", err)
+		return link
+	}
+	return short
+}
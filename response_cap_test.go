@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+// numberedLines returns n lines "line 1" to "line n"
+func numberedLines(n int) []string {
+	lines := make([]string, n)
+	for i := range lines {
+		lines[i] = fmt.Sprintf("line %d", i+1)
+	}
+	return lines
+}
+
+func TestResponseCapLinksFullText(t *testing.T) {
+	dir := t.TempDir()
+	store := &FileArtifactStore{Dir: dir, BaseURL: "http://short.test"}
+	us := shortener.NewURLShortener("http://short.test", shortener.NewInMemoryStorage())
+	c := NewResponseCap(4, store, us)
+
+	lines := numberedLines(10)
+	text := strings.Join(lines, "\n")
+	sent := c.Apply(context.Background(), text, lines)
+
+	if len(sent) != 4 || sent[0] != "line 1" || sent[2] != "line 3" {
+		t.Fatalf("Expected the first 3 lines and a link, got %q", sent)
+	}
+	if !strings.HasPrefix(sent[3], "... 7 more lines: http://short.test/") {
+		t.Errorf("Expected a short link to the rest, got %q", sent[3])
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"))
+	if len(files) != 1 {
+		t.Fatalf("Expected the full reply to be uploaded once, got %v", files)
+	}
+	if data, _ := os.ReadFile(files[0]); string(data) != text {
+		t.Errorf("Expected the upload to hold the full reply, got %q", data)
+	}
+}
+
+func TestResponseCapWithinLimit(t *testing.T) {
+	c := NewResponseCap(4, nil, nil)
+	lines := numberedLines(4)
+	if sent := c.Apply(context.Background(), strings.Join(lines, "\n"), lines); len(sent) != 4 || sent[3] != "line 4" {
+		t.Errorf("Expected a reply within the cap to be sent whole, got %q", sent)
+	}
+
+	lines = numberedLines(50)
+	if sent := NewResponseCap(0, nil, nil).Apply(context.Background(), "", lines); len(sent) != 50 {
+		t.Errorf("Expected no cap when disabled, got %d lines", len(sent))
+	}
+}
+
+func TestResponseCapWithoutStore(t *testing.T) {
+	c := NewResponseCap(3, nil, nil)
+	sent := c.Apply(context.Background(), "", numberedLines(5))
+	if len(sent) != 3 || sent[2] != "... 3 more lines not shown" {
+		t.Errorf("Expected the reply truncated without a link, got %q", sent)
+	}
+}
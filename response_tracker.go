@@ -0,0 +1,55 @@
+package main
+
+import (
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// emptyResponseFallback is sent when the model finishes without replying
+const emptyResponseFallback = "(no response)"
+
+// truncatedNotice follows a reply that hit the model's output token limit
+const truncatedNotice = "(response truncated — ask me to continue)"
+
+// responseTracker watches the events of a single agent run to tell whether the
+// user got a reply and why the model stopped
+type responseTracker struct {
+	replied      bool               // the model produced text or used send_irc_message
+	finishReason genai.FinishReason // finish reason of the last model event that reported one
+}
+
+// observe records a run event
+func (t *responseTracker) observe(event *session.Event) {
+	if event == nil || event.Author == genai.RoleUser {
+		return
+	}
+
+	if event.FinishReason != "" && event.FinishReason != genai.FinishReasonUnspecified {
+		t.finishReason = event.FinishReason
+	}
+
+	if event.Content == nil {
+		return
+	}
+	for _, part := range event.Content.Parts {
+		if part.Text != "" {
+			t.replied = true
+		}
+		if part.FunctionCall != nil && part.FunctionCall.Name == "send_irc_message" {
+			t.replied = true
+		}
+	}
+}
+
+// fallback returns the message to send when the run ended without a reply
+func (t *responseTracker) fallback() (string, bool) {
+	if t.replied {
+		return "", false
+	}
+	return emptyResponseFallback, true
+}
+
+// truncated reports whether the model stopped because it ran out of output tokens
+func (t *responseTracker) truncated() bool {
+	return t.finishReason == genai.FinishReasonMaxTokens
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"testing"
+
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// modelEvent builds an agent event as produced from a model response
+func modelEvent(resp model.LLMResponse) *session.Event {
+	return &session.Event{LLMResponse: resp, Author: "irc_agent"}
+}
+
+func TestResponseTrackerEmptyResponse(t *testing.T) {
+	var tracker responseTracker
+
+	// A stop-only response from the model: no content blocks at all
+	tracker.observe(modelEvent(model.LLMResponse{
+		Content:      &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{}},
+		FinishReason: genai.FinishReasonStop,
+		TurnComplete: true,
+	}))
+
+	msg, ok := tracker.fallback()
+	if !ok {
+		t.Fatalf("Expected a fallback for an empty response")
+	}
+	if msg != emptyResponseFallback {
+		t.Errorf("Expected %q, got %q", emptyResponseFallback, msg)
+	}
+	if tracker.finishReason != genai.FinishReasonStop {
+		t.Errorf("Expected finish reason STOP, got %s", tracker.finishReason)
+	}
+}
+
+func TestResponseTrackerReplied(t *testing.T) {
+	tests := []struct {
+		name string
+		part *genai.Part
+	}{
+		{"text", genai.NewPartFromText("hello")},
+		{"send_irc_message", genai.NewPartFromFunctionCall("send_irc_message", map[string]any{"message": "hi"})},
+	}
+
+	for _, tt := range tests {
+		var tracker responseTracker
+		tracker.observe(modelEvent(model.LLMResponse{
+			Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{tt.part}},
+		}))
+		if _, ok := tracker.fallback(); ok {
+			t.Errorf("%s: expected no fallback after a reply", tt.name)
+		}
+	}
+}
+
+func TestResponseTrackerIgnoresUserEvents(t *testing.T) {
+	var tracker responseTracker
+	tracker.observe(&session.Event{
+		LLMResponse: model.LLMResponse{Content: genai.NewContentFromText("question", genai.RoleUser)},
+		Author:      genai.RoleUser,
+	})
+
+	if _, ok := tracker.fallback(); !ok {
+		t.Errorf("Expected user text not to count as a reply")
+	}
+}
+
+func TestResponseTrackerTruncated(t *testing.T) {
+	var tracker responseTracker
+	tracker.observe(modelEvent(model.LLMResponse{
+		Content:      genai.NewContentFromText("The answer is", genai.RoleModel),
+		FinishReason: genai.FinishReasonMaxTokens,
+	}))
+
+	if !tracker.truncated() {
+		t.Errorf("Expected MAX_TOKENS finish reason to be reported as truncated")
+	}
+
+	// A later complete turn (e.g. after a tool call) clears it
+	tracker.observe(modelEvent(model.LLMResponse{
+		Content:      genai.NewContentFromText("Done.", genai.RoleModel),
+		FinishReason: genai.FinishReasonStop,
+	}))
+	if tracker.truncated() {
+		t.Errorf("Expected truncation to reflect the last finish reason")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import "fmt"
+
+// s3Instruction is the part of the built-in system instruction about the
+// result bucket, naming the store's bucket, region and endpoint so the
+// model's SDK calls go where the executor lets scripts connect
+func s3Instruction(store *S3ArtifactStore) string {
+	clientOptions := fmt.Sprintf("region: %q", store.region())
+	if store.Endpoint != "" {
+		clientOptions += fmt.Sprintf(", endpoint: %q", store.Endpoint)
+	}
+	if store.ForcePathStyle {
+		clientOptions += ", forcePathStyle: true"
+	}
+
+	return fmt.Sprintf(`
+S3 Result Bucket:
+- Full access to S3 bucket: s3://%[1]s
+- AWS credentials are available via environment variables
+- To upload a file without them, call presign_upload and PUT the file to its upload_url, then share its download_url
+- AWS SDK is available for Deno ("npm:@aws-sdk/client-s3@3")
+
+Example: Use AWS SDK in Deno to interact with S3:
+import { S3Client, GetObjectCommand } from "npm:@aws-sdk/client-s3@3";
+const client = new S3Client({ %[2]s });
+const command = new GetObjectCommand({
+  Bucket: %[1]q,
+  Key: "%[3]s1234567890-abcdef.txt"
+});
+const response = await client.send(command);
+const body = await response.Body.transformToString();
+console.log(body);
+
+Example: List all objects in an S3 bucket (to find uploaded results, call list_results instead):
+import { S3Client, ListObjectsV2Command } from "npm:@aws-sdk/client-s3@3";
+const client = new S3Client({ %[2]s });
+const command = new ListObjectsV2Command({
+  Bucket: %[1]q
+});
+const response = await client.send(command);
+console.log(JSON.stringify(response.Contents, null, 2));
+
+Example: Rename an S3 object (copy then delete):
+import { S3Client, CopyObjectCommand, DeleteObjectCommand } from "npm:@aws-sdk/client-s3@3";
+const client = new S3Client({ %[2]s });
+const oldKey = "1719040270770.jpeg";
+const newKey = "hdsht.jpeg";
+// Copy to new name
+await client.send(new CopyObjectCommand({
+  Bucket: %[1]q,
+  CopySource: "%[1]s/" + oldKey,
+  Key: newKey
+}));
+// Delete old object
+await client.send(new DeleteObjectCommand({
+  Bucket: %[1]q,
+  Key: oldKey
+}));
+console.log("Renamed " + oldKey + " to " + newKey);
+`, store.bucket(), clientOptions, store.keyPrefix())
+}
@@ -0,0 +1,23 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestS3InstructionNamesConfiguredStore(t *testing.T) {
+	text := s3Instruction(&S3ArtifactStore{Bucket: "results", Region: "eu-west-1", KeyPrefix: "runs/"})
+	for _, want := range []string{"s3://results", `Bucket: "results"`, `CopySource: "results/"`, `region: "eu-west-1"`, `"runs/1234567890`} {
+		if !strings.Contains(text, want) {
+			t.Errorf("Expected the instruction to contain %s, got:\n%s", want, text)
+		}
+	}
+	if strings.Contains(text, "robust-cicada") || strings.Contains(text, "us-west-2") || strings.Contains(text, "endpoint") {
+		t.Errorf("Expected only the configured store, got:\n%s", text)
+	}
+
+	text = s3Instruction(&S3ArtifactStore{Endpoint: "http://minio:9000", ForcePathStyle: true})
+	if !strings.Contains(text, `endpoint: "http://minio:9000", forcePathStyle: true`) {
+		t.Errorf("Expected the custom endpoint in the client options, got:\n%s", text)
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import "sync"
+
+// sessionLock is a mutex shared by the runs waiting on one session
+type sessionLock struct {
+	mu      sync.Mutex
+	waiters int // runs holding or waiting for mu
+}
+
+// SessionLocks serializes agent runs per session so two messages arriving
+// close together in a channel don't interleave appends to its history. Runs
+// for different sessions don't block each other.
+type SessionLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sessionLock
+}
+
+// NewSessionLocks creates an empty set of session locks
+func NewSessionLocks() *SessionLocks {
+	return &SessionLocks{locks: make(map[string]*sessionLock)}
+}
+
+// Lock blocks until no other run holds sessionID and returns the function
+// that releases it. Locks are dropped once nobody is waiting on them.
+func (l *SessionLocks) Lock(sessionID string) (unlock func()) {
+	l.mu.Lock()
+	lock, ok := l.locks[sessionID]
+	if !ok {
+		lock = &sessionLock{}
+		l.locks[sessionID] = lock
+	}
+	lock.waiters++
+	l.mu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		lock.waiters--
+		if lock.waiters == 0 {
+			delete(l.locks, sessionID)
+		}
+	}
+}
+
+// Len returns the number of sessions currently held or waited on
+func (l *SessionLocks) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.locks)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSessionLocksSerializeSameSession(t *testing.T) {
+	locks := NewSessionLocks()
+	unlock := locks.Lock("irc-session-#a")
+
+	acquired := make(chan struct{})
+	go func() {
+		release := locks.Lock("irc-session-#a")
+		close(acquired)
+		release()
+	}()
+
+	select {
+	case <-acquired:
+		t.Fatal("Expected the second lock to wait for the first")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	unlock()
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the second lock once the first was released")
+	}
+}
+
+func TestSessionLocksIndependentSessions(t *testing.T) {
+	locks := NewSessionLocks()
+	unlockA := locks.Lock("irc-session-#a")
+	defer unlockA()
+
+	acquired := make(chan struct{})
+	go func() {
+		locks.Lock("irc-session-#b")()
+		close(acquired)
+	}()
+
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("Expected a different session not to wait")
+	}
+}
+
+func TestSessionLocksDropsReleasedLocks(t *testing.T) {
+	locks := NewSessionLocks()
+	locks.Lock("irc-session-#a")()
+	if locks.Len() != 0 {
+		t.Errorf("Expected no locks after release, got %d", locks.Len())
+	}
+}
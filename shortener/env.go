@@ -0,0 +1,138 @@
+package shortener
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"log/slog"
+	"os"
+
+	"github.com/r33drichards/irc-agent/internal/envconf"
+)
+
+// defaultHost is used when SHORTENER_HOST is not set (the Railway production URL)
+const defaultHost = "https://irc-agent-production-09eb.up.railway.app"
+
+// defaultPort is used when SHORTENER_PORT is not set
+const defaultPort = "3000"
+
+// Host returns the base URL of short links, from SHORTENER_HOST
+func Host() string {
+	if host := os.Getenv("SHORTENER_HOST"); host != "" {
+		return host
+	}
+	return defaultHost
+}
+
+// Port returns the port the URL shortener listens on, from SHORTENER_PORT
+func Port() string {
+	if port := os.Getenv("SHORTENER_PORT"); port != "" {
+		return port
+	}
+	return defaultPort
+}
+
+// FromEnv creates a shortener for host configured from the environment:
+// Redis storage from REDIS_* when set, in-memory otherwise, and
+// SHORTENER_MAX_ENTRIES, SHORTENER_REDIRECT_STATUS, SHORTENER_LOG_FILE,
+// SHORTENER_ADMIN_TOKEN, SHORTENER_ID_LENGTH, SHORTENER_CORS_ORIGINS and
+// SHORTENER_MAX_BODY_BYTES. opts are applied after the environment's. The
+// returned func releases the storage and log file once the shortener has
+// shut down.
+func FromEnv(host string, opts ...ShortenerOption) (*URLShortener, func() error, error) {
+	var closers []io.Closer
+	closeAll := func() error {
+		var firstErr error
+		for _, c := range closers {
+			if err := c.Close(); err != nil && firstErr == nil {
+				firstErr = err
+			}
+		}
+		return firstErr
+	}
+	fail := func(err error) (*URLShortener, func() error, error) {
+		closeAll()
+		return nil, nil, err
+	}
+
+	maxEntries, envOpts, err := envOptions()
+	if err != nil {
+		return fail(err)
+	}
+
+	// Use Redis for short URL storage when configured so links survive restarts
+	var storage URLStorage = NewInMemoryStorage(WithMaxEntries(maxEntries))
+	if redisConfigured() {
+		redisCfg, err := redisConfigFromEnv()
+		if err != nil {
+			return fail(fmt.Errorf("invalid Redis configuration: %w", err))
+		}
+		redisStorage := NewRedisStorage(redisCfg)
+		closers = append(closers, redisStorage)
+		storage = redisStorage
+		log.Printf("Using Redis storage at %s", redisMode(redisCfg))
+	}
+
+	// The shortener can log to its own file, apart from the bot's logs
+	if path := os.Getenv("SHORTENER_LOG_FILE"); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return fail(fmt.Errorf("failed to open SHORTENER_LOG_FILE: %w", err))
+		}
+		closers = append(closers, f)
+		envOpts = append(envOpts, WithLogger(slog.New(slog.NewTextHandler(f, nil))))
+	}
+
+	return NewURLShortener(host, storage, append(envOpts, opts...)...), closeAll, nil
+}
+
+// Settings FromEnv reads, also checked by CheckEnv
+var (
+	maxEntriesSetting   = envconf.Int{Name: "SHORTENER_MAX_ENTRIES", Default: defaultInMemoryMaxEntries}
+	idLengthSetting     = envconf.Int{Name: "SHORTENER_ID_LENGTH", Min: MinIDLength} // unset keeps the default
+	maxBodyBytesSetting = envconf.Int{Name: "SHORTENER_MAX_BODY_BYTES", Min: 1}
+)
+
+// envOptions reads the settings FromEnv applies without opening anything:
+// the in-memory size limit and the shortener options. It reports every
+// invalid setting, not just the first.
+func envOptions() (maxEntries int, opts []ShortenerOption, err error) {
+	var problems []error
+	maxEntries, err = maxEntriesSetting.Get()
+	problems = append(problems, err)
+
+	if raw := os.Getenv("SHORTENER_REDIRECT_STATUS"); raw != "" {
+		status, err := ParseRedirectStatus(raw)
+		if err != nil {
+			problems = append(problems, fmt.Errorf("invalid SHORTENER_REDIRECT_STATUS: %w", err))
+		}
+		opts = append(opts, WithRedirectStatus(status))
+	}
+	if token := os.Getenv("SHORTENER_ADMIN_TOKEN"); token != "" {
+		opts = append(opts, WithAdminToken(token))
+	}
+	if n, err := idLengthSetting.Get(); err != nil {
+		problems = append(problems, err)
+	} else if n > 0 {
+		opts = append(opts, WithIDLength(n))
+	}
+	if origins := envconf.SplitList(os.Getenv("SHORTENER_CORS_ORIGINS")); len(origins) > 0 {
+		opts = append(opts, WithCORSOrigins(origins...))
+	}
+	if n, err := maxBodyBytesSetting.Get(); err != nil {
+		problems = append(problems, err)
+	} else if n > 0 {
+		opts = append(opts, WithMaxBodyBytes(int64(n)))
+	}
+	return maxEntries, opts, errors.Join(problems...)
+}
+
+// CheckEnv reports every problem with the shortener and Redis settings in the
+// environment, parsed just as FromEnv parses them, without connecting to
+// Redis or opening the log file
+func CheckEnv() error {
+	_, _, err := envOptions()
+	_, redisErr := redisConfigFromEnv()
+	return errors.Join(err, redisErr)
+}
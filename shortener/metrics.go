@@ -0,0 +1,19 @@
+package shortener
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Prometheus metrics exposed on the shortener's /metrics endpoint
+var (
+	shortURLsCreated = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "irc_agent_short_urls_created_total",
+		Help: "Number of short URLs created.",
+	})
+
+	redirectsServed = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "irc_agent_redirects_served_total",
+		Help: "Number of short URL redirects served.",
+	})
+)
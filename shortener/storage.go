@@ -0,0 +1,485 @@
+package shortener
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/r33drichards/irc-agent/internal/envconf"
+	"github.com/redis/go-redis/v9"
+)
+
+// ErrNotFound is returned by URLStorage.Get when a short ID has no mapping
+var ErrNotFound = errors.New("short ID not found")
+
+// URLStorage persists the mapping from short IDs to original URLs
+type URLStorage interface {
+	// Get returns the original URL for a short ID, or ErrNotFound
+	Get(ctx context.Context, id string) (string, error)
+	// GetMany returns the original URLs for several short IDs at once, keyed
+	// by ID. IDs without a mapping are left out rather than reported as errors.
+	GetMany(ctx context.Context, ids []string) (map[string]string, error)
+	// Set stores the original URL for a short ID
+	Set(ctx context.Context, id, url string) error
+	// SetIfAbsent stores the original URL for a short ID only when the ID has
+	// no mapping yet, atomically, and reports whether it did
+	SetIfAbsent(ctx context.Context, id, url string) (bool, error)
+	// List returns up to about limit stored mappings starting at cursor, ""
+	// for the first page, and the cursor of the next page, "" after the last
+	List(ctx context.Context, limit int, cursor string) ([]ShortURLEntry, string, error)
+	// Ping reports whether the backend is reachable
+	Ping(ctx context.Context) error
+}
+
+// ShortURLEntry is a stored mapping, as returned by List
+type ShortURLEntry struct {
+	ID  string `json:"short_id"`
+	URL string `json:"url"`
+}
+
+// defaultInMemoryMaxEntries caps the in-memory backend the bot uses by default
+const defaultInMemoryMaxEntries = 10000
+
+// InMemoryStorage keeps URL mappings in a map. Mappings are lost on restart.
+// With a maximum set, the least recently used mapping is evicted to make room.
+type InMemoryStorage struct {
+	mu         sync.Mutex
+	maxEntries int                      // 0 for no limit
+	urlMap     map[string]*list.Element // maps short ID to its entry in order
+	order      *list.List               // *memoryEntry, most recently used first
+}
+
+// memoryEntry is a mapping held by InMemoryStorage
+type memoryEntry struct {
+	id  string
+	url string
+}
+
+// InMemoryOption customizes an InMemoryStorage created by NewInMemoryStorage
+type InMemoryOption func(*InMemoryStorage)
+
+// WithMaxEntries caps how many mappings are kept, evicting the least recently
+// used past it. Zero or less means no limit.
+func WithMaxEntries(n int) InMemoryOption {
+	return func(s *InMemoryStorage) {
+		s.maxEntries = max(n, 0)
+	}
+}
+
+// NewInMemoryStorage creates an empty in-memory storage backend
+func NewInMemoryStorage(opts ...InMemoryOption) *InMemoryStorage {
+	s := &InMemoryStorage{
+		urlMap: make(map[string]*list.Element),
+		order:  list.New(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Get returns the original URL for a short ID, marking it recently used
+func (s *InMemoryStorage) Get(ctx context.Context, id string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	elem, exists := s.urlMap[id]
+	if !exists {
+		return "", ErrNotFound
+	}
+	s.order.MoveToFront(elem)
+	return elem.Value.(*memoryEntry).url, nil
+}
+
+// GetMany returns the original URLs for the short IDs that have one
+func (s *InMemoryStorage) GetMany(ctx context.Context, ids []string) (map[string]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	urls := make(map[string]string, len(ids))
+	for _, id := range ids {
+		if elem, exists := s.urlMap[id]; exists {
+			s.order.MoveToFront(elem)
+			urls[id] = elem.Value.(*memoryEntry).url
+		}
+	}
+	return urls, nil
+}
+
+// Set stores the original URL for a short ID, evicting the least recently
+// used mapping when full
+func (s *InMemoryStorage) Set(ctx context.Context, id, url string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, exists := s.urlMap[id]; exists {
+		elem.Value.(*memoryEntry).url = url
+		s.order.MoveToFront(elem)
+		return nil
+	}
+	s.insertLocked(id, url)
+	return nil
+}
+
+// SetIfAbsent stores url for id unless id is already mapped, checked under
+// the same lock as the write. Finding the existing mapping counts as use.
+func (s *InMemoryStorage) SetIfAbsent(ctx context.Context, id, url string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if elem, exists := s.urlMap[id]; exists {
+		s.order.MoveToFront(elem)
+		return false, nil
+	}
+	s.insertLocked(id, url)
+	return true, nil
+}
+
+// insertLocked adds a new mapping as the most recently used, evicting the
+// least recently used one when full. The caller holds s.mu.
+func (s *InMemoryStorage) insertLocked(id, url string) {
+	s.urlMap[id] = s.order.PushFront(&memoryEntry{id: id, url: url})
+	if s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.urlMap, oldest.Value.(*memoryEntry).id)
+	}
+}
+
+// List returns mappings in ID order. The cursor is the last ID of the
+// previous page. Listing doesn't count as use for eviction.
+func (s *InMemoryStorage) List(ctx context.Context, limit int, cursor string) ([]ShortURLEntry, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	ids := make([]string, 0, len(s.urlMap))
+	for id := range s.urlMap {
+		if id > cursor {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	next := ""
+	if limit > 0 && len(ids) > limit {
+		ids = ids[:limit]
+		next = ids[limit-1]
+	}
+	entries := make([]ShortURLEntry, len(ids))
+	for i, id := range ids {
+		entries[i] = ShortURLEntry{ID: id, URL: s.urlMap[id].Value.(*memoryEntry).url}
+	}
+	return entries, next, nil
+}
+
+// Len returns how many mappings are held
+func (s *InMemoryStorage) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// Ping always succeeds since the map lives in-process
+func (s *InMemoryStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+// RedisStorageConfig holds the connection settings for RedisStorage. Zero
+// pool and timeout settings use the defaults below.
+//
+// A single node at Addr is used unless ClusterAddrs is set, for Redis
+// Cluster, or SentinelMaster and SentinelAddrs are, for a Sentinel-managed
+// primary. Cluster mode has no databases, so DB is ignored there.
+type RedisStorageConfig struct {
+	Addr     string
+	Password string
+	DB       int
+
+	ClusterAddrs   []string // seed nodes of a Redis Cluster
+	SentinelMaster string   // name of the primary the sentinels monitor
+	SentinelAddrs  []string // sentinel nodes
+
+	PoolSize     int           // maximum connections in the pool
+	DialTimeout  time.Duration // for establishing new connections
+	ReadTimeout  time.Duration // for socket reads
+	WriteTimeout time.Duration // for socket writes
+}
+
+// Redis connection defaults. Lookups sit on the redirect path, so timeouts
+// are kept short rather than letting a slow server stall requests.
+const (
+	defaultRedisPoolSize     = 20
+	defaultRedisDialTimeout  = 5 * time.Second
+	defaultRedisReadTimeout  = 2 * time.Second
+	defaultRedisWriteTimeout = 2 * time.Second
+)
+
+// RedisStorage keeps URL mappings in Redis so short links survive restarts
+type RedisStorage struct {
+	client redis.UniversalClient
+}
+
+// redisKeyPrefix namespaces short URL keys in a shared Redis instance
+const redisKeyPrefix = "shorturl:"
+
+// NewRedisStorage creates a Redis-backed storage backend for a single node,
+// a cluster or a Sentinel-managed primary, depending on cfg
+func NewRedisStorage(cfg RedisStorageConfig) *RedisStorage {
+	opts := redisOptions(cfg)
+	switch {
+	case len(cfg.ClusterAddrs) > 0:
+		return &RedisStorage{client: redis.NewClusterClient(&redis.ClusterOptions{
+			Addrs:        cfg.ClusterAddrs,
+			Password:     opts.Password,
+			PoolSize:     opts.PoolSize,
+			DialTimeout:  opts.DialTimeout,
+			ReadTimeout:  opts.ReadTimeout,
+			WriteTimeout: opts.WriteTimeout,
+		})}
+	case cfg.SentinelMaster != "":
+		return &RedisStorage{client: redis.NewFailoverClient(&redis.FailoverOptions{
+			MasterName:    cfg.SentinelMaster,
+			SentinelAddrs: cfg.SentinelAddrs,
+			Password:      opts.Password,
+			DB:            opts.DB,
+			PoolSize:      opts.PoolSize,
+			DialTimeout:   opts.DialTimeout,
+			ReadTimeout:   opts.ReadTimeout,
+			WriteTimeout:  opts.WriteTimeout,
+		})}
+	}
+	return &RedisStorage{client: redis.NewClient(opts)}
+}
+
+// redisMode describes which kind of deployment cfg connects to, for logging
+func redisMode(cfg RedisStorageConfig) string {
+	switch {
+	case len(cfg.ClusterAddrs) > 0:
+		return fmt.Sprintf("cluster %s", strings.Join(cfg.ClusterAddrs, ","))
+	case cfg.SentinelMaster != "":
+		return fmt.Sprintf("sentinel master %s via %s", cfg.SentinelMaster, strings.Join(cfg.SentinelAddrs, ","))
+	}
+	return cfg.Addr
+}
+
+// redisConfigFromEnv reads the Redis settings from REDIS_ADDR, REDIS_PASSWORD,
+// REDIS_DB, REDIS_CLUSTER_ADDRS, REDIS_SENTINEL_MASTER, REDIS_SENTINEL_ADDRS,
+// REDIS_POOL_SIZE and REDIS_{DIAL,READ,WRITE}_TIMEOUT
+func redisConfigFromEnv() (RedisStorageConfig, error) {
+	cfg := RedisStorageConfig{
+		Addr:           os.Getenv("REDIS_ADDR"),
+		Password:       os.Getenv("REDIS_PASSWORD"),
+		ClusterAddrs:   envconf.SplitList(os.Getenv("REDIS_CLUSTER_ADDRS")),
+		SentinelMaster: os.Getenv("REDIS_SENTINEL_MASTER"),
+		SentinelAddrs:  envconf.SplitList(os.Getenv("REDIS_SENTINEL_ADDRS")),
+	}
+	var problems []error
+	if cfg.SentinelMaster != "" && len(cfg.SentinelAddrs) == 0 {
+		problems = append(problems, fmt.Errorf("REDIS_SENTINEL_ADDRS is required with REDIS_SENTINEL_MASTER"))
+	}
+	// Zero leaves the client's default
+	ints := []struct {
+		setting envconf.Int
+		dst     *int
+	}{
+		{envconf.Int{Name: "REDIS_DB"}, &cfg.DB},
+		{envconf.Int{Name: "REDIS_POOL_SIZE"}, &cfg.PoolSize},
+	}
+	for _, v := range ints {
+		n, err := v.setting.Get()
+		problems = append(problems, err)
+		*v.dst = n
+	}
+	durations := []struct {
+		setting envconf.Duration
+		dst     *time.Duration
+	}{
+		{envconf.Duration{Name: "REDIS_DIAL_TIMEOUT"}, &cfg.DialTimeout},
+		{envconf.Duration{Name: "REDIS_READ_TIMEOUT"}, &cfg.ReadTimeout},
+		{envconf.Duration{Name: "REDIS_WRITE_TIMEOUT"}, &cfg.WriteTimeout},
+	}
+	for _, v := range durations {
+		d, err := v.setting.Get()
+		problems = append(problems, err)
+		*v.dst = d
+	}
+	return cfg, errors.Join(problems...)
+}
+
+// redisConfigured reports whether any Redis deployment is configured
+func redisConfigured() bool {
+	return os.Getenv("REDIS_ADDR") != "" || os.Getenv("REDIS_CLUSTER_ADDRS") != "" || os.Getenv("REDIS_SENTINEL_MASTER") != ""
+}
+
+// redisOptions returns the client options for cfg, filling in defaults
+func redisOptions(cfg RedisStorageConfig) *redis.Options {
+	opts := &redis.Options{
+		Addr:         cfg.Addr,
+		Password:     cfg.Password,
+		DB:           cfg.DB,
+		PoolSize:     cfg.PoolSize,
+		DialTimeout:  cfg.DialTimeout,
+		ReadTimeout:  cfg.ReadTimeout,
+		WriteTimeout: cfg.WriteTimeout,
+	}
+	if opts.PoolSize <= 0 {
+		opts.PoolSize = defaultRedisPoolSize
+	}
+	if opts.DialTimeout <= 0 {
+		opts.DialTimeout = defaultRedisDialTimeout
+	}
+	if opts.ReadTimeout <= 0 {
+		opts.ReadTimeout = defaultRedisReadTimeout
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = defaultRedisWriteTimeout
+	}
+	return opts
+}
+
+// Get returns the original URL for a short ID
+func (s *RedisStorage) Get(ctx context.Context, id string) (string, error) {
+	url, err := s.client.Get(ctx, redisKeyPrefix+id).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to get short ID from redis: %w", err)
+	}
+	return url, nil
+}
+
+// GetMany returns the original URLs for the short IDs that have one, in one
+// MGET. Keys in a cluster can live on different nodes, which MGET doesn't
+// allow, so there the GETs are pipelined instead.
+func (s *RedisStorage) GetMany(ctx context.Context, ids []string) (map[string]string, error) {
+	urls := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return urls, nil
+	}
+	keys := make([]string, len(ids))
+	for i, id := range ids {
+		keys[i] = redisKeyPrefix + id
+	}
+
+	if _, ok := s.client.(*redis.ClusterClient); ok {
+		cmds := make([]*redis.StringCmd, len(keys))
+		// Missing keys fail their GET with redis.Nil, so errors are checked per command
+		s.client.Pipelined(ctx, func(pipe redis.Pipeliner) error {
+			for i, key := range keys {
+				cmds[i] = pipe.Get(ctx, key)
+			}
+			return nil
+		})
+		for i, cmd := range cmds {
+			url, err := cmd.Result()
+			if errors.Is(err, redis.Nil) {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("failed to get short IDs from redis: %w", err)
+			}
+			urls[ids[i]] = url
+		}
+		return urls, nil
+	}
+
+	values, err := s.client.MGet(ctx, keys...).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get short IDs from redis: %w", err)
+	}
+	for i, value := range values {
+		if url, ok := value.(string); ok {
+			urls[ids[i]] = url
+		}
+	}
+	return urls, nil
+}
+
+// Set stores the original URL for a short ID
+func (s *RedisStorage) Set(ctx context.Context, id, url string) error {
+	if err := s.client.Set(ctx, redisKeyPrefix+id, url, 0).Err(); err != nil {
+		return fmt.Errorf("failed to store short ID in redis: %w", err)
+	}
+	return nil
+}
+
+// SetIfAbsent stores the mapping with SET NX, so of two shorteners racing to
+// claim an ID only one wins
+func (s *RedisStorage) SetIfAbsent(ctx context.Context, id, url string) (bool, error) {
+	stored, err := s.client.SetNX(ctx, redisKeyPrefix+id, url, 0).Result()
+	if err != nil {
+		return false, fmt.Errorf("failed to store short ID in redis: %w", err)
+	}
+	return stored, nil
+}
+
+// List walks the short URL keys with SCAN, so it doesn't block the server on
+// large key spaces. The cursor is Redis's own; a page can hold a few more than
+// limit, and mappings changed during a walk may be missed or seen twice. A
+// cluster would have to be walked node by node, which isn't supported.
+func (s *RedisStorage) List(ctx context.Context, limit int, cursor string) ([]ShortURLEntry, string, error) {
+	if _, ok := s.client.(*redis.ClusterClient); ok {
+		return nil, "", fmt.Errorf("listing short URLs is not supported with Redis Cluster")
+	}
+	var pos uint64
+	if cursor != "" {
+		n, err := strconv.ParseUint(cursor, 10, 64)
+		if err != nil {
+			return nil, "", fmt.Errorf("invalid cursor %q", cursor)
+		}
+		pos = n
+	}
+	if limit <= 0 {
+		limit = 100
+	}
+
+	var ids []string
+	for {
+		keys, next, err := s.client.Scan(ctx, pos, redisKeyPrefix+"*", int64(limit-len(ids))).Result()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to scan short IDs in redis: %w", err)
+		}
+		for _, key := range keys {
+			ids = append(ids, strings.TrimPrefix(key, redisKeyPrefix))
+		}
+		pos = next
+		if pos == 0 || len(ids) >= limit {
+			break
+		}
+	}
+
+	urls, err := s.GetMany(ctx, ids)
+	if err != nil {
+		return nil, "", err
+	}
+	entries := make([]ShortURLEntry, 0, len(ids))
+	for _, id := range ids {
+		// Keys deleted since the scan are skipped
+		if url, ok := urls[id]; ok {
+			entries = append(entries, ShortURLEntry{ID: id, URL: url})
+		}
+	}
+	next := ""
+	if pos != 0 {
+		next = strconv.FormatUint(pos, 10)
+	}
+	return entries, next, nil
+}
+
+// Ping checks that the Redis server is reachable
+func (s *RedisStorage) Ping(ctx context.Context) error {
+	return s.client.Ping(ctx).Err()
+}
+
+// Close releases the Redis connection pool
+func (s *RedisStorage) Close() error {
+	return s.client.Close()
+}
@@ -0,0 +1,339 @@
+package shortener
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r33drichards/irc-agent/internal/envconf"
+	"github.com/redis/go-redis/v9"
+)
+
+func TestInMemoryStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryStorage()
+
+	if err := storage.Ping(ctx); err != nil {
+		t.Errorf("Expected in-memory Ping to succeed, got %v", err)
+	}
+
+	if _, err := storage.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing ID, got %v", err)
+	}
+
+	if err := storage.Set(ctx, "abc12345", "https://example.com"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	url, err := storage.Get(ctx, "abc12345")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if url != "https://example.com" {
+		t.Errorf("Expected https://example.com, got %s", url)
+	}
+}
+
+// testSetIfAbsent checks that SetIfAbsent claims an ID once, using storage
+// holding nothing under id
+func testSetIfAbsent(t *testing.T, storage URLStorage, id string) {
+	t.Helper()
+	ctx := context.Background()
+
+	stored, err := storage.SetIfAbsent(ctx, id, "https://example.com/first")
+	if err != nil || !stored {
+		t.Fatalf("Expected the first SetIfAbsent to store, got %v (err %v)", stored, err)
+	}
+	stored, err = storage.SetIfAbsent(ctx, id, "https://example.com/second")
+	if err != nil || stored {
+		t.Errorf("Expected the second SetIfAbsent to leave the mapping, got %v (err %v)", stored, err)
+	}
+	if url, err := storage.Get(ctx, id); err != nil || url != "https://example.com/first" {
+		t.Errorf("Expected the first URL to be kept, got %q (err %v)", url, err)
+	}
+}
+
+func TestInMemoryStorageSetIfAbsent(t *testing.T) {
+	testSetIfAbsent(t, NewInMemoryStorage(), "abc12345")
+}
+
+// testGetMany checks GetMany against storage holding nothing under the test-many- prefix
+func testGetMany(t *testing.T, storage URLStorage) {
+	t.Helper()
+	ctx := context.Background()
+
+	for _, id := range []string{"test-many-a", "test-many-b"} {
+		if err := storage.Set(ctx, id, "https://example.com/"+id); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	urls, err := storage.GetMany(ctx, []string{"test-many-a", "test-many-missing", "test-many-b"})
+	if err != nil {
+		t.Fatalf("GetMany failed: %v", err)
+	}
+	if len(urls) != 2 || urls["test-many-a"] != "https://example.com/test-many-a" || urls["test-many-b"] != "https://example.com/test-many-b" {
+		t.Errorf("Expected the two stored URLs, got %v", urls)
+	}
+
+	if urls, err := storage.GetMany(ctx, nil); err != nil || len(urls) != 0 {
+		t.Errorf("Expected nothing for no IDs, got %v (err %v)", urls, err)
+	}
+}
+
+// testList pages through storage two entries at a time and checks that every
+// test-list- mapping is seen exactly once
+func testList(t *testing.T, storage URLStorage) {
+	t.Helper()
+	ctx := context.Background()
+
+	want := map[string]string{}
+	for _, id := range []string{"test-list-a", "test-list-b", "test-list-c", "test-list-d", "test-list-e"} {
+		want[id] = "https://example.com/" + id
+		if err := storage.Set(ctx, id, want[id]); err != nil {
+			t.Fatalf("Set failed: %v", err)
+		}
+	}
+
+	seen := map[string]string{}
+	cursor := ""
+	for pages := 0; ; pages++ {
+		if pages > 100 {
+			t.Fatal("Expected listing to finish")
+		}
+		entries, next, err := storage.List(ctx, 2, cursor)
+		if err != nil {
+			t.Fatalf("List failed: %v", err)
+		}
+		for _, e := range entries {
+			if _, dup := seen[e.ID]; dup && strings.HasPrefix(e.ID, "test-list-") {
+				t.Errorf("Expected %s to be listed once", e.ID)
+			}
+			seen[e.ID] = e.URL
+		}
+		if next == "" {
+			break
+		}
+		cursor = next
+	}
+	for id, url := range want {
+		if seen[id] != url {
+			t.Errorf("Expected %s -> %s in the listing, got %q", id, url, seen[id])
+		}
+	}
+}
+
+func TestInMemoryStorageList(t *testing.T) {
+	storage := NewInMemoryStorage()
+	testList(t, storage)
+
+	// Pages follow ID order
+	entries, next, _ := storage.List(context.Background(), 2, "")
+	if len(entries) != 2 || entries[0].ID != "test-list-a" || entries[1].ID != "test-list-b" || next != "test-list-b" {
+		t.Errorf("Expected the first two IDs and a cursor, got %v %q", entries, next)
+	}
+}
+
+func TestInMemoryStorageGetMany(t *testing.T) {
+	testGetMany(t, NewInMemoryStorage())
+}
+
+func TestInMemoryStorageEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	storage := NewInMemoryStorage(WithMaxEntries(2))
+
+	storage.Set(ctx, "a", "https://example.com/a")
+	storage.Set(ctx, "b", "https://example.com/b")
+	// Reading a makes b the least recently used
+	if _, err := storage.Get(ctx, "a"); err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	storage.Set(ctx, "c", "https://example.com/c")
+
+	if storage.Len() != 2 {
+		t.Errorf("Expected 2 entries, got %d", storage.Len())
+	}
+	if _, err := storage.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected b to be evicted, got %v", err)
+	}
+	for _, id := range []string{"a", "c"} {
+		if _, err := storage.Get(ctx, id); err != nil {
+			t.Errorf("Expected %s to be kept, got %v", id, err)
+		}
+	}
+
+	// Overwriting an ID doesn't take another slot
+	storage.Set(ctx, "c", "https://example.com/c2")
+	if url, _ := storage.Get(ctx, "c"); url != "https://example.com/c2" || storage.Len() != 2 {
+		t.Errorf("Expected c updated in place, got %q with %d entries", url, storage.Len())
+	}
+}
+
+func TestRedisOptionsDefaults(t *testing.T) {
+	opts := redisOptions(RedisStorageConfig{Addr: "localhost:6379"})
+
+	if opts.PoolSize != defaultRedisPoolSize || opts.DialTimeout != defaultRedisDialTimeout ||
+		opts.ReadTimeout != defaultRedisReadTimeout || opts.WriteTimeout != defaultRedisWriteTimeout {
+		t.Errorf("Expected default pool and timeouts, got %+v", opts)
+	}
+}
+
+func TestRedisStorageAppliesOptions(t *testing.T) {
+	t.Setenv("REDIS_ADDR", "redis.test:6380")
+	t.Setenv("REDIS_DB", "3")
+	t.Setenv("REDIS_POOL_SIZE", "7")
+	t.Setenv("REDIS_DIAL_TIMEOUT", "1s")
+	t.Setenv("REDIS_READ_TIMEOUT", "250ms")
+	t.Setenv("REDIS_WRITE_TIMEOUT", "500ms")
+
+	cfg, err := redisConfigFromEnv()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	storage := NewRedisStorage(cfg)
+	defer storage.Close()
+
+	opts := storage.client.(*redis.Client).Options()
+	if opts.Addr != "redis.test:6380" || opts.DB != 3 || opts.PoolSize != 7 {
+		t.Errorf("Expected address, DB and pool size from the environment, got %+v", opts)
+	}
+	if opts.DialTimeout != time.Second || opts.ReadTimeout != 250*time.Millisecond || opts.WriteTimeout != 500*time.Millisecond {
+		t.Errorf("Expected timeouts from the environment, got %v, %v, %v", opts.DialTimeout, opts.ReadTimeout, opts.WriteTimeout)
+	}
+
+	t.Setenv("REDIS_READ_TIMEOUT", "soon")
+	if _, err := redisConfigFromEnv(); err == nil {
+		t.Error("Expected an error for an invalid timeout")
+	}
+}
+
+func TestNewRedisStorageModes(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  RedisStorageConfig
+		want any
+	}{
+		{"single node", RedisStorageConfig{Addr: "localhost:6379"}, &redis.Client{}},
+		{"cluster", RedisStorageConfig{ClusterAddrs: []string{"a:6379", "b:6379"}}, &redis.ClusterClient{}},
+		{"sentinel", RedisStorageConfig{SentinelMaster: "mymaster", SentinelAddrs: []string{"s:26379"}}, &redis.Client{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			storage := NewRedisStorage(tt.cfg)
+			defer storage.Close()
+			if got, want := fmt.Sprintf("%T", storage.client), fmt.Sprintf("%T", tt.want); got != want {
+				t.Errorf("Expected a %s, got %s", want, got)
+			}
+		})
+	}
+
+	t.Setenv("REDIS_SENTINEL_MASTER", "mymaster")
+	if _, err := redisConfigFromEnv(); err == nil {
+		t.Error("Expected an error for a sentinel master without sentinels")
+	}
+}
+
+// newTestRedisStorage connects to the Redis server in TEST_REDIS_ADDR, skipping
+// the test when none is configured or reachable
+func newTestRedisStorage(t *testing.T) *RedisStorage {
+	t.Helper()
+
+	addr := os.Getenv("TEST_REDIS_ADDR")
+	if addr == "" {
+		t.Skip("TEST_REDIS_ADDR not set, skipping Redis test")
+	}
+
+	storage := NewRedisStorage(RedisStorageConfig{Addr: addr})
+	t.Cleanup(func() { storage.Close() })
+
+	if err := storage.Ping(context.Background()); err != nil {
+		t.Skipf("Redis at %s not reachable: %v", addr, err)
+	}
+	return storage
+}
+
+func TestRedisStorage(t *testing.T) {
+	ctx := context.Background()
+	storage := newTestRedisStorage(t)
+
+	if _, err := storage.Get(ctx, "test-missing"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Expected ErrNotFound for missing ID, got %v", err)
+	}
+
+	if err := storage.Set(ctx, "test-abc", "https://example.com"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+
+	url, err := storage.Get(ctx, "test-abc")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	if url != "https://example.com" {
+		t.Errorf("Expected https://example.com, got %s", url)
+	}
+}
+
+func TestRedisStorageSetIfAbsent(t *testing.T) {
+	storage := newTestRedisStorage(t)
+	id := fmt.Sprintf("test-nx-%d", time.Now().UnixNano())
+	testSetIfAbsent(t, storage, id)
+}
+
+func TestRedisStorageList(t *testing.T) {
+	testList(t, newTestRedisStorage(t))
+}
+
+func TestRedisStorageGetMany(t *testing.T) {
+	testGetMany(t, newTestRedisStorage(t))
+}
+
+func TestRedisStorageCluster(t *testing.T) {
+	addrs := envconf.SplitList(os.Getenv("TEST_REDIS_CLUSTER_ADDRS"))
+	if len(addrs) == 0 {
+		t.Skip("TEST_REDIS_CLUSTER_ADDRS not set, skipping Redis Cluster test")
+	}
+	testRedisDeployment(t, NewRedisStorage(RedisStorageConfig{ClusterAddrs: addrs}))
+}
+
+func TestRedisStorageSentinel(t *testing.T) {
+	master := os.Getenv("TEST_REDIS_SENTINEL_MASTER")
+	addrs := envconf.SplitList(os.Getenv("TEST_REDIS_SENTINEL_ADDRS"))
+	if master == "" || len(addrs) == 0 {
+		t.Skip("TEST_REDIS_SENTINEL_MASTER and TEST_REDIS_SENTINEL_ADDRS not set, skipping Sentinel test")
+	}
+	testRedisDeployment(t, NewRedisStorage(RedisStorageConfig{SentinelMaster: master, SentinelAddrs: addrs}))
+}
+
+// testRedisDeployment round-trips a mapping through storage, skipping the
+// test when the deployment isn't reachable
+func testRedisDeployment(t *testing.T, storage *RedisStorage) {
+	t.Helper()
+	defer storage.Close()
+	ctx := context.Background()
+
+	if err := storage.Ping(ctx); err != nil {
+		t.Skipf("Redis not reachable: %v", err)
+	}
+	if err := storage.Set(ctx, "test-ha", "https://example.com/ha"); err != nil {
+		t.Fatalf("Set failed: %v", err)
+	}
+	if url, err := storage.Get(ctx, "test-ha"); err != nil || url != "https://example.com/ha" {
+		t.Errorf("Expected https://example.com/ha, got %q (err %v)", url, err)
+	}
+	testGetMany(t, storage)
+}
+
+func TestRedisStoragePingUnreachable(t *testing.T) {
+	// Nothing listens on port 1, so Ping must fail fast
+	storage := NewRedisStorage(RedisStorageConfig{Addr: "127.0.0.1:1"})
+	defer storage.Close()
+
+	if err := storage.Ping(context.Background()); err == nil {
+		t.Errorf("Expected Ping to fail for an unreachable Redis server")
+	}
+}
@@ -0,0 +1,587 @@
+package shortener
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// URLShortener provides URL shortening functionality with HTTP serving
+type URLShortener struct {
+	storage        URLStorage // persists short ID to original URL mappings
+	idLength       int        // length of the short ID
+	host           string     // the base URL for short links (e.g., "http://example.com:3000")
+	redirectStatus int        // HTTP status used for redirects
+	artifactDir    string     // directory served under /artifacts/, empty to disable
+	adminToken     string     // bearer token for admin endpoints, empty to disable them
+	maxBodyBytes   int64      // largest POST body accepted when creating a short URL
+	corsOrigins    []string   // origins allowed to call the API from a browser, "*" for any
+	logger         *slog.Logger
+
+	mu       sync.Mutex
+	server   *http.Server // set while serving, used by Shutdown
+	shutdown bool         // Shutdown was called, so serve must not start
+}
+
+// Bounds and default for the hex short ID length. Below the minimum,
+// unrelated URLs start colliding; the maximum is a full SHA-256 digest.
+const (
+	defaultIDLength = 8
+	MinIDLength     = 6
+	maxIDLength     = sha256.Size * 2
+)
+
+// DefaultMaxBodyBytes caps POST bodies creating short URLs. URLs are short;
+// anything bigger is a mistake or an attempt to exhaust memory.
+const DefaultMaxBodyBytes = 8 << 10
+
+// ErrConflict is returned by Shorten when the short ID for a URL already maps
+// to a different URL
+var ErrConflict = errors.New("short ID already maps to a different URL")
+
+// ShortenerOption customizes a URLShortener created by NewURLShortener
+type ShortenerOption func(*URLShortener)
+
+// WithRedirectStatus sets the HTTP status used when redirecting short links
+func WithRedirectStatus(status int) ShortenerOption {
+	return func(us *URLShortener) {
+		us.redirectStatus = status
+	}
+}
+
+// WithIDLength sets the length of short IDs, clamped to between 6 and 64
+func WithIDLength(n int) ShortenerOption {
+	return func(us *URLShortener) {
+		us.idLength = max(MinIDLength, min(n, maxIDLength))
+	}
+}
+
+// WithMaxBodyBytes sets the largest POST body accepted when creating a short
+// URL; larger bodies are rejected with 413
+func WithMaxBodyBytes(n int64) ShortenerOption {
+	return func(us *URLShortener) {
+		us.maxBodyBytes = n
+	}
+}
+
+// WithCORSOrigins lets browser pages from origins call the API. "*" allows
+// any origin. Without it no CORS headers are sent.
+func WithCORSOrigins(origins ...string) ShortenerOption {
+	return func(us *URLShortener) {
+		us.corsOrigins = origins
+	}
+}
+
+// WithAdminToken enables the admin endpoints, such as /list, for requests
+// carrying token as a bearer token
+func WithAdminToken(token string) ShortenerOption {
+	return func(us *URLShortener) {
+		us.adminToken = token
+	}
+}
+
+// WithLogger sends the shortener's logs, including its access log, to logger
+// instead of the default logger
+func WithLogger(logger *slog.Logger) ShortenerOption {
+	return func(us *URLShortener) {
+		us.logger = logger
+	}
+}
+
+// WithArtifactDir serves files written by a FileArtifactStore under /artifacts/
+func WithArtifactDir(dir string) ShortenerOption {
+	return func(us *URLShortener) {
+		us.artifactDir = dir
+	}
+}
+
+// NewURLShortener creates a new URL shortener instance backed by storage
+func NewURLShortener(host string, storage URLStorage, opts ...ShortenerOption) *URLShortener {
+	us := &URLShortener{
+		storage:  storage,
+		idLength: defaultIDLength,
+		host:     host,
+		// 302 rather than 301: links point at expiring presigned URLs, and
+		// browsers cache permanent redirects aggressively
+		redirectStatus: http.StatusFound,
+		maxBodyBytes:   DefaultMaxBodyBytes,
+		logger:         slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(us)
+	}
+	return us
+}
+
+// ParseRedirectStatus validates a redirect status code such as the value of
+// SHORTENER_REDIRECT_STATUS
+func ParseRedirectStatus(raw string) (int, error) {
+	status, err := strconv.Atoi(raw)
+	if err != nil {
+		return 0, fmt.Errorf("redirect status must be a number, got %q", raw)
+	}
+
+	switch status {
+	case http.StatusMovedPermanently, http.StatusFound, http.StatusSeeOther,
+		http.StatusTemporaryRedirect, http.StatusPermanentRedirect:
+		return status, nil
+	}
+	return 0, fmt.Errorf("redirect status must be one of 301, 302, 303, 307 or 308, got %d", status)
+}
+
+// idFor derives the short ID for url from its SHA-256 hash
+func (us *URLShortener) idFor(url string) string {
+	hash := sha256.Sum256([]byte(url))
+	return hex.EncodeToString(hash[:])[:us.idLength]
+}
+
+// Shorten takes a URL (including signed URLs) and returns its short ID.
+// created reports whether a new mapping was stored; it is false when the URL
+// was already shortened. A different URL already holding the ID is an
+// ErrConflict.
+func (us *URLShortener) Shorten(url string) (shortID string, created bool, err error) {
+	shortID = us.idFor(url)
+	ctx := context.Background()
+	existing, err := us.storage.Get(ctx, shortID)
+	switch {
+	case err == nil && existing == url:
+		return shortID, false, nil
+	case err == nil:
+		return "", false, fmt.Errorf("%w: %s", ErrConflict, shortID)
+	case !errors.Is(err, ErrNotFound):
+		return "", false, fmt.Errorf("failed to look up short ID %s: %w", shortID, err)
+	}
+
+	// Store the mapping, unless another request claimed the ID since the lookup
+	stored, err := us.storage.SetIfAbsent(ctx, shortID, url)
+	if err != nil {
+		return "", false, fmt.Errorf("failed to store short ID %s: %w", shortID, err)
+	}
+	if !stored {
+		existing, err := us.storage.Get(ctx, shortID)
+		switch {
+		case err != nil:
+			return "", false, fmt.Errorf("failed to look up short ID %s: %w", shortID, err)
+		case existing != url:
+			return "", false, fmt.Errorf("%w: %s", ErrConflict, shortID)
+		}
+		return shortID, false, nil
+	}
+	shortURLsCreated.Inc()
+
+	us.logger.Info("shortened URL", "id", shortID, "url", url)
+	return shortID, true, nil
+}
+
+// GetShortURL returns the full short URL for a given original URL
+func (us *URLShortener) GetShortURL(url string) (string, error) {
+	shortID, _, err := us.Shorten(url)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%s/%s", us.host, shortID), nil
+}
+
+// Resolve returns the original URL for a short ID or a full short URL such as
+// "https://host/abcd1234"
+func (us *URLShortener) Resolve(ctx context.Context, shortURL string) (string, error) {
+	id := strings.TrimSuffix(strings.TrimSpace(shortURL), "/")
+	if i := strings.LastIndex(id, "/"); i >= 0 {
+		id = id[i+1:]
+	}
+	if id == "" {
+		return "", ErrNotFound
+	}
+	return us.storage.Get(ctx, id)
+}
+
+// writeJSON writes v as a JSON response with the given status code
+func (us *URLShortener) writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		us.logger.Error("failed to write JSON response", "err", err)
+	}
+}
+
+// Handler returns the HTTP handler for the shortener's routes. It uses its own
+// ServeMux so that several shorteners can run in one process.
+func (us *URLShortener) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	// Liveness probe: the process is up and serving HTTP
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		us.writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+
+	// Prometheus metrics for the shortener, executor and model usage
+	mux.Handle("/metrics", promhttp.Handler())
+
+	// Readiness probe: the storage backend is reachable so links can be resolved
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+		defer cancel()
+
+		if err := us.storage.Ping(ctx); err != nil {
+			us.logger.Warn("readiness check failed", "err", err)
+			us.writeJSON(w, http.StatusServiceUnavailable, map[string]string{"status": "unavailable"})
+			return
+		}
+		us.writeJSON(w, http.StatusOK, map[string]string{"status": "ready"})
+	})
+
+	// Show where a short link points without redirecting
+	mux.HandleFunc("/info/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		id := strings.TrimPrefix(r.URL.Path, "/info/")
+		originalURL, err := us.storage.Get(r.Context(), id)
+		if errors.Is(err, ErrNotFound) {
+			us.writeJSON(w, http.StatusNotFound, map[string]string{"error": "short ID not found"})
+			return
+		}
+		if err != nil {
+			us.logger.Error("failed to look up short ID", "id", id, "err", err)
+			us.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to look up short URL"})
+			return
+		}
+		us.writeJSON(w, http.StatusOK, map[string]string{"id": id, "url": originalURL})
+	})
+
+	// Admin: page through every stored short URL
+	if us.adminToken != "" {
+		mux.HandleFunc("/list", us.requireAdmin(us.serveList))
+	}
+
+	// Artifacts written by the filesystem artifact store
+	if us.artifactDir != "" {
+		mux.HandleFunc("/artifacts/", us.serveArtifact)
+	}
+
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		// Extract the ID from the path
+		id := strings.TrimPrefix(r.URL.Path, "/")
+
+		// Handle POST requests for creating short URLs
+		if r.Method == http.MethodPost {
+			if id != "" {
+				http.Error(w, "POST only allowed at root path", http.StatusBadRequest)
+				return
+			}
+
+			// Read the URL from request body
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, us.maxBodyBytes))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					http.Error(w, fmt.Sprintf("Request body exceeds %d bytes", tooLarge.Limit), http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "Failed to read request body", http.StatusBadRequest)
+				return
+			}
+			defer r.Body.Close()
+
+			url := strings.TrimSpace(string(body))
+			if url == "" {
+				http.Error(w, "URL cannot be empty", http.StatusBadRequest)
+				return
+			}
+			// One URL per request; a trailing newline from curl or echo is trimmed above
+			if strings.ContainsAny(url, " \t\r\n") {
+				http.Error(w, "URL cannot contain whitespace", http.StatusBadRequest)
+				return
+			}
+
+			// Create short URL
+			shortURL, err := us.GetShortURL(url)
+			if errors.Is(err, ErrConflict) {
+				http.Error(w, "Short ID is already used by a different URL", http.StatusConflict)
+				return
+			}
+			if err != nil {
+				us.logger.Error("failed to create short URL via POST", "err", err)
+				http.Error(w, "Failed to create short URL", http.StatusInternalServerError)
+				return
+			}
+
+			// Return the short URL
+			w.Header().Set("Content-Type", "text/plain")
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprint(w, shortURL)
+			us.logger.Info("created short URL via POST", "short_url", shortURL)
+			return
+		}
+
+		// Handle GET requests for redirects
+		if r.Method != http.MethodGet {
+			http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		// Handle root path
+		if id == "" {
+			w.WriteHeader(http.StatusOK)
+			fmt.Fprintf(w, "URL Shortener Service\n")
+			fmt.Fprintf(w, "Usage:\n")
+			fmt.Fprintf(w, "  GET  /<short-id> - Redirect to original URL\n")
+			fmt.Fprintf(w, "  POST /           - Create short URL (send URL in body)\n")
+			fmt.Fprintf(w, "  GET  /info/<short-id> - Show the original URL without redirecting\n")
+			fmt.Fprintf(w, "  GET  /healthz    - Liveness check\n")
+			fmt.Fprintf(w, "  GET  /readyz     - Readiness check\n")
+			fmt.Fprintf(w, "  GET  /metrics    - Prometheus metrics\n")
+			if us.artifactDir != "" {
+				fmt.Fprintf(w, "  GET  /artifacts/<name> - Stored code and results\n")
+			}
+			if us.adminToken != "" {
+				fmt.Fprintf(w, "  GET  /list       - List short URLs (admin token required)\n")
+			}
+			return
+		}
+
+		// Look up the original URL
+		originalURL, err := us.storage.Get(r.Context(), id)
+		if errors.Is(err, ErrNotFound) {
+			http.NotFound(w, r)
+			us.logger.Info("short ID not found", "id", id)
+			return
+		}
+		if err != nil {
+			http.Error(w, "Failed to look up short URL", http.StatusInternalServerError)
+			us.logger.Error("failed to look up short ID", "id", id, "err", err)
+			return
+		}
+
+		// Redirect to the original URL. Targets may expire, so never let
+		// clients or proxies cache the redirect.
+		us.logger.Info("redirecting", "id", id, "url", originalURL)
+		w.Header().Set("Cache-Control", "no-store")
+		http.Redirect(w, r, originalURL, us.redirectStatus)
+		redirectsServed.Inc()
+	})
+
+	return us.logRequests(us.cors(mux))
+}
+
+// Page sizes for /list
+const (
+	defaultListLimit = 100
+	maxListLimit     = 1000
+)
+
+// requireAdmin rejects requests that don't carry the admin bearer token
+func (us *URLShortener) requireAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(us.adminToken)) != 1 {
+			w.Header().Set("WWW-Authenticate", "Bearer")
+			us.writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "admin token required"})
+			return
+		}
+		next(w, r)
+	}
+}
+
+// serveList returns a page of stored short URLs as JSON. Pass the returned
+// next_cursor as ?cursor= to get the following page; it is empty on the last.
+func (us *URLShortener) serveList(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	limit := defaultListLimit
+	if raw := r.URL.Query().Get("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n <= 0 {
+			us.writeJSON(w, http.StatusBadRequest, map[string]string{"error": "limit must be a positive integer"})
+			return
+		}
+		limit = min(n, maxListLimit)
+	}
+
+	entries, next, err := us.storage.List(r.Context(), limit, r.URL.Query().Get("cursor"))
+	if err != nil {
+		us.logger.Error("failed to list short URLs", "err", err)
+		us.writeJSON(w, http.StatusInternalServerError, map[string]string{"error": "failed to list short URLs"})
+		return
+	}
+	us.writeJSON(w, http.StatusOK, struct {
+		Entries    []ShortURLEntry `json:"entries"`
+		NextCursor string          `json:"next_cursor"`
+	}{entries, next})
+}
+
+// serveArtifact serves a single file from the artifact directory. Only plain
+// file names are accepted so requests cannot escape the directory.
+func (us *URLShortener) serveArtifact(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	name := strings.TrimPrefix(r.URL.Path, "/artifacts/")
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := filepath.Join(us.artifactDir, name)
+	if info, err := os.Stat(path); err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+	http.ServeFile(w, r, path)
+}
+
+// allowedOrigin returns the Access-Control-Allow-Origin value for origin, or
+// "" when it may not call the API
+func (us *URLShortener) allowedOrigin(origin string) string {
+	if origin == "" {
+		return ""
+	}
+	for _, allowed := range us.corsOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if strings.EqualFold(allowed, origin) {
+			return origin
+		}
+	}
+	return ""
+}
+
+// cors wraps a handler with CORS headers for allowed origins and answers
+// their preflight requests
+func (us *URLShortener) cors(next http.Handler) http.Handler {
+	if len(us.corsOrigins) == 0 {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Origin")
+		allowed := us.allowedOrigin(r.Header.Get("Origin"))
+		if allowed == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		w.Header().Set("Access-Control-Allow-Origin", allowed)
+
+		if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+			w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+			w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type")
+			w.Header().Set("Access-Control-Max-Age", "600")
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// statusRecorder captures the status code written by a handler
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+// WriteHeader records the status before passing it through
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+// logRequests wraps a handler with an access log of method, path, status and latency
+func (us *URLShortener) logRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+
+		next.ServeHTTP(rec, r)
+
+		us.logger.Info("shortener request",
+			"method", r.Method,
+			"path", r.URL.Path,
+			"status", rec.status,
+			"latency", time.Since(start),
+		)
+	})
+}
+
+// Serve starts the HTTP server on the specified port. It blocks until the
+// server fails or Shutdown is called, in which case it returns nil.
+func (us *URLShortener) Serve(port string) error {
+	addr := ":" + port
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("failed to listen on %s: %w", addr, err)
+	}
+	return us.serve(ln)
+}
+
+// serve runs the HTTP server on an existing listener until it fails
+func (us *URLShortener) serve(ln net.Listener) error {
+	server := &http.Server{
+		Handler:           us.Handler(),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Shutdown may have been called before we got here
+	us.mu.Lock()
+	if us.shutdown {
+		us.mu.Unlock()
+		ln.Close()
+		return nil
+	}
+	us.server = server
+	us.mu.Unlock()
+
+	us.logger.Info("URL Shortener serving", "addr", ln.Addr().String())
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
+
+// Shutdown stops accepting new connections and waits for in-flight requests
+// to finish or for ctx to expire. A server that hasn't started yet never will.
+func (us *URLShortener) Shutdown(ctx context.Context) error {
+	us.mu.Lock()
+	server := us.server
+	us.server = nil
+	us.shutdown = true
+	us.mu.Unlock()
+
+	if server == nil {
+		return nil
+	}
+
+	us.logger.Info("shutting down URL Shortener")
+	return server.Shutdown(ctx)
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// AgentStats holds lightweight counters reported by the ,stats command
+type AgentStats struct {
+	started    time.Time
+	messages   atomic.Int64 // messages sent to the agent
+	executions atomic.Int64 // execute_typescript calls completed
+	errors     atomic.Int64 // agent runs that failed
+	sessions   atomic.Int64 // sessions with a run in progress
+}
+
+// NewAgentStats creates stats with the uptime clock starting at started
+func NewAgentStats(started time.Time) *AgentStats {
+	return &AgentStats{started: started}
+}
+
+// Summary formats the stats as a single IRC line
+func (s *AgentStats) Summary(now time.Time) string {
+	uptime := now.Sub(s.started).Round(time.Second)
+	return fmt.Sprintf("uptime %s, %d messages, %d executions, %d errors, %d active sessions",
+		uptime, s.messages.Load(), s.executions.Load(), s.errors.Load(), s.sessions.Load())
+}
@@ -0,0 +1,22 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestAgentStatsSummary(t *testing.T) {
+	started := time.Date(2025, 1, 1, 12, 0, 0, 0, time.UTC)
+	stats := NewAgentStats(started)
+
+	stats.messages.Add(3)
+	stats.executions.Add(2)
+	stats.errors.Add(1)
+	stats.sessions.Add(1)
+
+	summary := stats.Summary(started.Add(90*time.Minute + 400*time.Millisecond))
+	expected := "uptime 1h30m0s, 3 messages, 2 executions, 1 errors, 1 active sessions"
+	if summary != expected {
+		t.Errorf("Expected %q, got %q", expected, summary)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+)
+
+// Placeholders for the IRC settings stdin mode never uses
+const (
+	stdinServer  = "stdin.invalid"
+	stdinChannel = "#stdin"
+)
+
+// stdinMode reports whether the bot should read messages from stdin instead of
+// connecting to IRC, with --stdin or RUN_MODE=stdin
+func stdinMode(args []string) bool {
+	if len(args) > 1 && args[1] == "--stdin" {
+		return true
+	}
+	return strings.EqualFold(os.Getenv("RUN_MODE"), "stdin")
+}
+
+// stdinDefaults fills in SERVER and CHANNEL when they aren't set, so stdin
+// mode runs with nothing but a model API key
+func stdinDefaults() {
+	if os.Getenv("NETWORKS") != "" || os.Getenv("NETWORKS_FILE") != "" {
+		return
+	}
+	if os.Getenv("SERVER") == "" {
+		os.Setenv("SERVER", stdinServer)
+	}
+	if os.Getenv("CHANNEL") == "" {
+		os.Setenv("CHANNEL", stdinChannel)
+	}
+}
+
+// RunStdin answers messages read from in, one per line, as if sender had said
+// them in the agent's first channel. Replies go to sink instead of IRC, and
+// each message is answered before the next is read. It returns when in is
+// exhausted or ctx is cancelled.
+func (ia *IRCAgent) RunStdin(ctx context.Context, in io.Reader, sink MessageSink, sender string) error {
+	channel := ia.channel
+	ia.outbound = NewOutboundBuffer(outboundBufferSize, sink)
+	ia.outbound.Connected()
+	ia.outbound.Joined(channel)
+
+	log.Printf("Reading messages for %s from stdin as %s", channel, sender)
+	scanner := bufio.NewScanner(in)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		message := strings.TrimSpace(scanner.Text())
+		if message == "" {
+			continue
+		}
+
+		recent := ia.history.Recent(channel)
+		ia.history.Add(channel, sender, message)
+		ia.processMessage(ctx, sender, message, channel, recent, time.Now())
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read stdin: %w", err)
+	}
+	return nil
+}
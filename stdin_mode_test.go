@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"google.golang.org/adk/session"
+)
+
+func TestRunStdinAnswersEachLine(t *testing.T) {
+	ia, _ := newTestAgent(func(yield func(*session.Event, error) bool) {
+		yield(textEvent("reply"), nil)
+	})
+	ia.channel = "#stdin"
+	ia.history = NewChannelHistory(10)
+
+	var out bytes.Buffer
+	in := strings.NewReader("hello\n\n  \nagain\n")
+	if err := ia.RunStdin(context.Background(), in, NewWriterSink(&out), "alice"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	expected := "[#stdin] reply\n[#stdin] reply\n"
+	if out.String() != expected {
+		t.Errorf("Expected %q, got %q", expected, out.String())
+	}
+	if recent := ia.history.Recent("#stdin"); len(recent) != 2 || recent[1].Message != "again" {
+		t.Errorf("Expected both lines in the channel history, got %+v", recent)
+	}
+}
+
+func TestStdinMode(t *testing.T) {
+	t.Setenv("RUN_MODE", "")
+	if stdinMode([]string{"agent"}) {
+		t.Error("Expected IRC mode by default")
+	}
+	if !stdinMode([]string{"agent", "--stdin"}) {
+		t.Error("Expected --stdin to enable stdin mode")
+	}
+	t.Setenv("RUN_MODE", "stdin")
+	if !stdinMode([]string{"agent"}) {
+		t.Error("Expected RUN_MODE=stdin to enable stdin mode")
+	}
+}
+
+func TestStdinDefaults(t *testing.T) {
+	t.Setenv("NETWORKS", "")
+	t.Setenv("NETWORKS_FILE", "")
+	t.Setenv("SERVER", "")
+	t.Setenv("CHANNEL", "#mine")
+
+	stdinDefaults()
+	if got := os.Getenv("SERVER"); got != stdinServer {
+		t.Errorf("Expected SERVER to default to %s, got %q", stdinServer, got)
+	}
+	if got := os.Getenv("CHANNEL"); got != "#mine" {
+		t.Errorf("Expected CHANNEL to be kept, got %q", got)
+	}
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultThinkingMessage is sent when a reply is slow to arrive
+const defaultThinkingMessage = "thinking..."
+
+// thinkingNotice sends a message once a reply has taken longer than a delay,
+// so a slow model call or execution doesn't leave the channel silent
+type thinkingNotice struct {
+	mu      sync.Mutex
+	stopped bool
+	timer   *time.Timer
+}
+
+// startThinkingNotice calls send after delay unless stopped first. A zero
+// delay disables the notice.
+func startThinkingNotice(delay time.Duration, send func()) *thinkingNotice {
+	n := &thinkingNotice{}
+	if delay <= 0 {
+		n.stopped = true
+		return n
+	}
+	n.timer = time.AfterFunc(delay, func() {
+		n.mu.Lock()
+		defer n.mu.Unlock()
+		if !n.stopped {
+			n.stopped = true
+			send()
+		}
+	})
+	return n
+}
+
+// Stop cancels the notice. Once Stop returns the notice is never sent.
+func (n *thinkingNotice) Stop() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.stopped = true
+	if n.timer != nil {
+		n.timer.Stop()
+	}
+}
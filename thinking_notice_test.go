@@ -0,0 +1,41 @@
+package main
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestThinkingNoticeSentWhenSlow(t *testing.T) {
+	sent := make(chan struct{}, 1)
+	n := startThinkingNotice(10*time.Millisecond, func() { sent <- struct{}{} })
+	defer n.Stop()
+
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the notice to be sent after the delay")
+	}
+}
+
+func TestThinkingNoticeSuppressedWhenFast(t *testing.T) {
+	var sent atomic.Int32
+	n := startThinkingNotice(50*time.Millisecond, func() { sent.Add(1) })
+	n.Stop()
+
+	time.Sleep(100 * time.Millisecond)
+	if sent.Load() != 0 {
+		t.Error("Expected no notice when the reply arrived first")
+	}
+}
+
+func TestThinkingNoticeDisabled(t *testing.T) {
+	var sent atomic.Int32
+	n := startThinkingNotice(0, func() { sent.Add(1) })
+
+	time.Sleep(20 * time.Millisecond)
+	n.Stop()
+	if sent.Load() != 0 {
+		t.Error("Expected a zero delay to disable the notice")
+	}
+}
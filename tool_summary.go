@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// toolResultSummary builds the one-line notice posted to IRC when a tool
+// returns, reading the fields of the tool's result where it knows them
+func toolResultSummary(toolName string, response map[string]any) string {
+	if toolName != "execute_typescript" || response == nil {
+		return fmt.Sprintf("[Tool %s completed]", toolName)
+	}
+
+	var details []string
+	if status, _ := response["status"].(string); status == "success" {
+		details = append(details, "code ran")
+	} else if msg, _ := response["error_message"].(string); msg != "" {
+		details = append(details, "failed: "+msg)
+	} else {
+		details = append(details, "failed")
+	}
+
+	if exitCode, ok := numberField(response, "exit_code"); ok && exitCode >= 0 {
+		details = append(details, fmt.Sprintf("exit %d", exitCode))
+	}
+	if url, _ := response["short_url"].(string); url != "" {
+		details = append(details, "output: "+url)
+	}
+	if url, _ := response["code_short_url"].(string); url != "" {
+		details = append(details, "code: "+url)
+	}
+
+	return "[" + strings.Join(details, ", ") + "]"
+}
+
+// numberField reads an integer field that may have been decoded from JSON
+func numberField(m map[string]any, key string) (int, bool) {
+	switch v := m[key].(type) {
+	case int:
+		return v, true
+	case int64:
+		return int(v), true
+	case float64:
+		return int(v), true
+	}
+	return 0, false
+}
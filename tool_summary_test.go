@@ -0,0 +1,52 @@
+package main
+
+import "testing"
+
+func TestToolResultSummary(t *testing.T) {
+	tests := []struct {
+		name     string
+		tool     string
+		response map[string]any
+		expected string
+	}{
+		{
+			name: "success with urls",
+			tool: "execute_typescript",
+			response: map[string]any{
+				"status":         "success",
+				"exit_code":      float64(0),
+				"short_url":      "http://s/abc",
+				"code_short_url": "http://s/def",
+			},
+			expected: "[code ran, exit 0, output: http://s/abc, code: http://s/def]",
+		},
+		{
+			name: "failure",
+			tool: "execute_typescript",
+			response: map[string]any{
+				"status":        "error",
+				"error_message": "Execution failed with exit code 1",
+				"exit_code":     1,
+			},
+			expected: "[failed: Execution failed with exit code 1, exit 1]",
+		},
+		{
+			name:     "setup error without an exit code",
+			tool:     "execute_typescript",
+			response: map[string]any{"status": "error", "error_message": "Code execution is not available on this server", "exit_code": -1},
+			expected: "[failed: Code execution is not available on this server]",
+		},
+		{
+			name:     "other tool",
+			tool:     "web_search",
+			response: map[string]any{"status": "success"},
+			expected: "[Tool web_search completed]",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := toolResultSummary(tt.tool, tt.response); got != tt.expected {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.expected, got)
+		}
+	}
+}
@@ -2,27 +2,59 @@ package main
 
 import (
 	"bytes"
-	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 	"sync"
 	"time"
+	"unicode/utf8"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/r33drichards/irc-agent/shortener"
 	"google.golang.org/adk/tool"
 )
 
 // ExecuteTypeScriptParams defines the input parameters for executing TypeScript/JavaScript code
 type ExecuteTypeScriptParams struct {
-	Code string `json:"code" jsonschema:"The TypeScript or JavaScript code to execute"`
+	Code string   `json:"code" jsonschema:"The TypeScript or JavaScript code to execute"`
+	Args []string `json:"args,omitempty" jsonschema:"Optional command-line arguments for the script, available in Deno.args"`
+	// ParseJSON asks for the last non-empty stdout line to be parsed as a JSON object
+	ParseJSON bool `json:"parse_json,omitempty" jsonschema:"If true, the last non-empty line printed to stdout is parsed as a JSON object and returned in the json field"`
+}
+
+// maxScriptArgs caps the number of arguments passed to a script
+const maxScriptArgs = 32
+
+// binaryOutputNote replaces binary output in results, since raw bytes would be
+// garbled in the model's context and in IRC
+func binaryOutputNote(size int, contentType string, uploaded bool) string {
+	if uploaded {
+		return fmt.Sprintf("(binary output, %d bytes of %s, download it from short_url)", size, contentType)
+	}
+	return fmt.Sprintf("(binary output, %d bytes of %s, not shown)", size, contentType)
+}
+
+// validateScriptArgs rejects arguments that can't be passed to a process.
+// Arguments are passed directly, not through a shell, so no quoting is needed.
+func validateScriptArgs(args []string) error {
+	if len(args) > maxScriptArgs {
+		return fmt.Errorf("too many arguments: %d (max %d)", len(args), maxScriptArgs)
+	}
+	for i, arg := range args {
+		if strings.ContainsRune(arg, 0) {
+			return fmt.Errorf("argument %d contains a NUL byte", i)
+		}
+	}
+	return nil
 }
 
 // ExecuteTypeScriptResults defines the output of TypeScript/JavaScript execution
@@ -34,79 +66,277 @@ type ExecuteTypeScriptResults struct {
 	SignedURL    string `json:"signed_url,omitempty"`
 	ShortURL     string `json:"short_url,omitempty"`
 	CodeShortURL string `json:"code_short_url,omitempty"`
+	// Set when parse_json was requested
+	JSON            map[string]any `json:"json,omitempty"`
+	JSONParseFailed bool           `json:"json_parse_failed,omitempty"`
 }
 
 // TypeScriptExecutor handles TypeScript/JavaScript code execution using Deno
 type TypeScriptExecutor struct {
 	mu           sync.Mutex
-	URLShortener *URLShortener
+	URLShortener *shortener.URLShortener
+	Store        ArtifactStore // where code and full output are uploaded; nil disables uploads
+	// UploadResults uploads code and full output to Store. When false the
+	// output is only returned inline and the URL fields are left empty.
+	UploadResults bool
+	DenoPath      string // Deno binary to run, defaults to "deno" looked up on PATH
+	// WorkspaceDir, when set, keeps a persistent working directory per
+	// channel under it instead of a fresh temp dir per execution. Files a
+	// script writes stay readable by later scripts from the same channel, so
+	// anything sensitive a script produces outlives the call; leave it empty
+	// unless that trade-off is acceptable.
+	WorkspaceDir string
+	MaxCodeBytes int           // largest accepted script, defaults to defaultMaxCodeBytes
+	DeniedCode   *CodeDenylist // code matching this is refused before running; nil allows all
+	Runner       CommandRunner // starts Deno, defaults to os/exec
+	// Notices posts progress notices to IRC while code runs, on the
+	// connection the call came from
+	Notices bool
+	// Channel is where notices go. Defaults to the channel or nick the call
+	// came from.
+	Channel string
+	// AllowNet lists the hosts scripts may connect to, such as the result
+	// bucket's endpoints, besides the local shortener
+	AllowNet []string
+
+	denoMissing bool // deno was not found at construction, so executions are refused
 }
 
-// uploadToS3AndGetSignedURL uploads content to S3 and returns a presigned URL
-func uploadToS3AndGetSignedURL(ctx context.Context, content string) (string, error) {
-	const bucketName = "robust-cicada"
-	const region = "us-west-2"
+// TypeScriptExecutorConfig holds the settings for NewTypeScriptExecutor
+type TypeScriptExecutorConfig struct {
+	URLShortener *shortener.URLShortener
+	Store        ArtifactStore
+	// UploadResults turns uploading to Store on or off. Defaults to true.
+	UploadResults *bool
+	// DenoPath is the Deno binary, either a path or a name looked up on PATH.
+	// Defaults to "deno".
+	DenoPath string
+	// WorkspaceDir enables persistent per-channel workspaces under this directory
+	WorkspaceDir string
+	// MaxCodeBytes caps the size of submitted code. Defaults to defaultMaxCodeBytes.
+	MaxCodeBytes int
+	// DeniedCode rejects code that mentions forbidden APIs. Optional.
+	DeniedCode *CodeDenylist
+	// Runner starts Deno. Defaults to os/exec; when set, DenoPath isn't
+	// looked up, since the runner decides what the command means.
+	Runner CommandRunner
+	// Notices and Channel enable progress notices. Optional.
+	Notices bool
+	Channel string
+	// AllowNet lists hosts scripts may reach. Optional.
+	AllowNet []string
+}
 
-	// Load AWS configuration
-	cfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(region))
-	if err != nil {
-		return "", fmt.Errorf("failed to load AWS config: %w", err)
+// defaultDenoPath is used when no Deno binary is configured
+const defaultDenoPath = "deno"
+
+// defaultMaxCodeBytes is the largest script accepted when no limit is configured
+const defaultMaxCodeBytes = 256 << 10
+
+// denoWaitDelay bounds how long a cancelled Deno process may hold its output
+// pipes open after being killed
+const denoWaitDelay = 2 * time.Second
+
+// denoUnavailableMessage is returned to the model when Deno isn't installed
+const denoUnavailableMessage = "Code execution is not available on this server"
+
+// NewTypeScriptExecutor creates an executor, checking up front that Deno is
+// installed so a missing binary shows up in the startup logs. A missing
+// default "deno" only disables execution; an explicitly configured DenoPath
+// that can't be run is an error.
+func NewTypeScriptExecutor(cfg TypeScriptExecutorConfig) (*TypeScriptExecutor, error) {
+	e := &TypeScriptExecutor{
+		URLShortener:  cfg.URLShortener,
+		Store:         cfg.Store,
+		UploadResults: cfg.UploadResults == nil || *cfg.UploadResults,
+		DenoPath:      cfg.DenoPath,
+		WorkspaceDir:  cfg.WorkspaceDir,
+		MaxCodeBytes:  cfg.MaxCodeBytes,
+		DeniedCode:    cfg.DeniedCode,
+		Runner:        cfg.Runner,
+		Notices:       cfg.Notices,
+		Channel:       cfg.Channel,
+		AllowNet:      cfg.AllowNet,
+	}
+	if e.DenoPath == "" {
+		e.DenoPath = defaultDenoPath
+	}
+	if e.MaxCodeBytes <= 0 {
+		e.MaxCodeBytes = defaultMaxCodeBytes
+	}
+	if e.Runner != nil {
+		return e, nil
+	}
+	e.Runner = execRunner{waitDelay: denoWaitDelay}
+
+	path, err := exec.LookPath(e.DenoPath)
+	switch {
+	case err == nil:
+		e.DenoPath = path
+	case cfg.DenoPath != "" && cfg.DenoPath != defaultDenoPath:
+		return nil, fmt.Errorf("deno binary %q is not usable: %w", cfg.DenoPath, err)
+	case errors.Is(err, exec.ErrNotFound):
+		log.Printf("Warning: deno not found on PATH, code execution is disabled")
+		e.denoMissing = true
+	default:
+		log.Printf("Warning: failed to look up deno: %v", err)
 	}
 
-	// Create S3 client
-	s3Client := s3.NewFromConfig(cfg)
+	return e, nil
+}
 
-	// Generate a unique key based on timestamp and content hash
-	hash := sha256.Sum256([]byte(content))
-	hashStr := hex.EncodeToString(hash[:])[:16]
-	timestamp := time.Now().Unix()
-	key := fmt.Sprintf("code-results/%d-%s.txt", timestamp, hashStr)
+// envOrDefault returns the value of the named env var, or def when it is unset
+func envOrDefault(name, def string) string {
+	if v := os.Getenv(name); v != "" {
+		return v
+	}
+	return def
+}
 
-	// Upload content to S3
-	_, err = s3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(bucketName),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader([]byte(content)),
-		ContentType: aws.String("text/plain"),
-	})
-	if err != nil {
-		return "", fmt.Errorf("failed to upload to S3: %w", err)
+// notify sends a progress notice for a call from ctx, back over the
+// connection its message arrived on. It does nothing unless Notices is set.
+func (e *TypeScriptExecutor) notify(ctx tool.Context, message string) {
+	if !e.Notices || ctx == nil {
+		return
+	}
+	route, ok := replyRouteFromContext(ctx)
+	if !ok {
+		return
+	}
+	target := route.target
+	if e.Channel != "" {
+		target = e.Channel
+	}
+	route.sink.Send(target, message)
+}
+
+// executionNotice describes the outcome of a run for IRC, with the short URL
+// of the uploaded results when there is one
+func executionNotice(results ExecuteTypeScriptResults) string {
+	switch {
+	case results.Status == "success" && results.ShortURL != "":
+		return "Code executed successfully: " + results.ShortURL
+	case results.Status == "success":
+		return "Code executed successfully"
+	case results.ExitCode > 0:
+		return fmt.Sprintf("Code execution failed with exit code %d", results.ExitCode)
+	default:
+		return "Code execution failed"
 	}
+}
 
-	// Create S3 presign client
-	presignClient := s3.NewPresignClient(s3Client)
+// allowedHosts returns the hosts scripts may connect to: AllowNet and the
+// local URL shortener
+func (e *TypeScriptExecutor) allowedHosts() []string {
+	return append(slices.Clone(e.AllowNet), "localhost:"+shortener.Port())
+}
 
-	// Generate presigned URL (valid for 24 hours)
-	presignResult, err := presignClient.PresignGetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(bucketName),
-		Key:    aws.String(key),
-	}, s3.WithPresignExpires(24*time.Hour))
+// workDir returns the directory a script runs in and a cleanup function. By
+// default that's a fresh temp dir removed afterwards; with WorkspaceDir set it
+// is the calling channel's persistent workspace, which is kept.
+func (e *TypeScriptExecutor) workDir(ctx tool.Context) (string, func(), error) {
+	if e.WorkspaceDir == "" {
+		dir, err := os.MkdirTemp("", "deno-exec-"+workspaceName(ctx)+"-")
+		if err != nil {
+			return "", nil, err
+		}
+		return dir, func() { os.RemoveAll(dir) }, nil
+	}
 
-	if err != nil {
-		return "", fmt.Errorf("failed to generate presigned URL: %w", err)
+	dir := filepath.Join(e.WorkspaceDir, workspaceName(ctx))
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", nil, err
+	}
+	return dir, func() {}, nil
+}
+
+// workspaceName returns a directory name unique to the channel a tool call
+// came from, so channels never share files. The runner's user ID names the
+// channel and, on named networks, the network.
+func workspaceName(ctx tool.Context) string {
+	channel := ""
+	if ctx != nil {
+		channel = strings.ToLower(ctx.UserID())
+	}
+	if channel == "" {
+		return "default"
 	}
 
-	return presignResult.URL, nil
+	// Keep only characters that are safe in a single path element, plus a
+	// hash of the channel since different names can sanitize the same way
+	safe := strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '-', r == '_':
+			return r
+		}
+		return '_'
+	}, channel)
+	hash := sha256.Sum256([]byte(channel))
+	return safe + "-" + hex.EncodeToString(hash[:])[:8]
 }
 
 // Execute runs TypeScript/JavaScript code using Deno
-func (e *TypeScriptExecutor) Execute(ctx tool.Context, params ExecuteTypeScriptParams) ExecuteTypeScriptResults {
+func (e *TypeScriptExecutor) Execute(ctx tool.Context, params ExecuteTypeScriptParams) (results ExecuteTypeScriptResults) {
 	e.mu.Lock()
 	defer e.mu.Unlock()
 
-	// Create a temporary directory for script isolation
-	tempDir, err := os.MkdirTemp("", "deno-exec-")
+	// The run may have been cancelled, e.g. by shutdown, while waiting for the lock
+	runCtx := toolContext(ctx)
+	if err := runCtx.Err(); err != nil {
+		return ExecuteTypeScriptResults{
+			Status:       "error",
+			ErrorMessage: fmt.Sprintf("Execution cancelled: %v", err),
+			ExitCode:     -1,
+		}
+	}
+
+	if e.denoMissing {
+		return ExecuteTypeScriptResults{
+			Status:       "error",
+			ErrorMessage: denoUnavailableMessage,
+			ExitCode:     -1,
+		}
+	}
+
+	// Reject oversized code before writing or uploading anything
+	if len(params.Code) > e.MaxCodeBytes {
+		return ExecuteTypeScriptResults{
+			Status:       "error",
+			ErrorMessage: fmt.Sprintf("Code is too large: %d bytes (max %d)", len(params.Code), e.MaxCodeBytes),
+			ExitCode:     -1,
+		}
+	}
+
+	if match, denied := e.DeniedCode.Check(params.Code); denied {
+		log.Printf("Refusing code that uses denied API %q", match)
+		return ExecuteTypeScriptResults{
+			Status:       "error",
+			ErrorMessage: fmt.Sprintf("Code uses %q, which is not allowed on this server", match),
+			ExitCode:     -1,
+		}
+	}
+
+	if err := validateScriptArgs(params.Args); err != nil {
+		return ExecuteTypeScriptResults{
+			Status:       "error",
+			ErrorMessage: fmt.Sprintf("Invalid args: %v", err),
+			ExitCode:     -1,
+		}
+	}
+
+	// Create the directory the script runs in
+	workDir, cleanup, err := e.workDir(ctx)
 	if err != nil {
 		return ExecuteTypeScriptResults{
 			Status:       "error",
-			ErrorMessage: fmt.Sprintf("Failed to create temp directory: %v", err),
+			ErrorMessage: fmt.Sprintf("Failed to create working directory: %v", err),
 			ExitCode:     -1,
 		}
 	}
-	defer os.RemoveAll(tempDir) // Clean up
+	defer cleanup()
 
-	// Write the code to a temporary file
-	scriptPath := filepath.Join(tempDir, "script.ts")
+	// Write the code to a file in the working directory
+	scriptPath := filepath.Join(workDir, "script.ts")
 	err = os.WriteFile(scriptPath, []byte(params.Code), 0600)
 	if err != nil {
 		return ExecuteTypeScriptResults{
@@ -116,55 +346,111 @@ func (e *TypeScriptExecutor) Execute(ctx tool.Context, params ExecuteTypeScriptP
 		}
 	}
 
-	// Upload code to S3 and get signed URL
-	codeSignedURL, err := uploadToS3AndGetSignedURL(context.Background(), params.Code)
+	// Upload code to the artifact store and get its URL
 	var codeShortURL string
-	if err != nil {
-		log.Printf("Warning: Failed to upload code to S3: %v", err)
-	} else if e.URLShortener != nil {
-		codeShortURL = e.URLShortener.GetShortURL(codeSignedURL)
+	uploading := e.UploadResults && e.Store != nil
+	if uploading {
+		codeSignedURL, err := e.Store.Upload(runCtx, params.Code, "text/plain; charset=utf-8")
+		if err != nil {
+			log.Printf("Warning: Failed to upload code: %v", err)
+		} else if e.URLShortener != nil {
+			if codeShortURL, err = e.URLShortener.GetShortURL(codeSignedURL); err != nil {
+				log.Printf("Warning: Failed to shorten code URL: %v", err)
+			}
+		}
 	}
 
 	// Execute the script using Deno
-	cmd := exec.Command(
-		"deno",
+	denoPath := e.DenoPath
+	if denoPath == "" {
+		denoPath = defaultDenoPath
+	}
+	args := []string{
 		"run",
 		"--no-check",
 		"--allow-env=AWS_*,HOME,USERPROFILE,HOMEPATH,HOMEDRIVE,_X_AMZN_TRACE_ID",
-		"--allow-net=s3.us-west-2.amazonaws.com,robust-cicada.s3.us-west-2.amazonaws.com,localhost:3000",
+		"--allow-net=" + strings.Join(e.allowedHosts(), ","),
 		"--allow-sys=osRelease",
 		"--allow-read=.,/root/.cache/deno",
 		"--allow-write=.",
 		scriptPath,
-	)
-	cmd.Dir = tempDir
+	}
+	// Script arguments go after the script path so Deno hands them to Deno.args
+	args = append(args, params.Args...)
+	runner := e.Runner
+	if runner == nil {
+		runner = execRunner{waitDelay: denoWaitDelay}
+	}
+
+	// Capture stdout and stderr together, keeping stdout alone for parse_json
+	var combined lockedBuffer
+	var stdout bytes.Buffer
 
-	// Capture stdout and stderr
-	output, execErr := cmd.CombinedOutput()
+	// Once Deno has been started, report how the run went
+	e.notify(ctx, "Executing code...")
+	defer func() { e.notify(ctx, executionNotice(results)) }()
+
+	// Deno is killed if the run is cancelled
+	start := time.Now()
+	execErr := runner.Run(runCtx, workDir, denoPath, args, io.MultiWriter(&combined, &stdout), &combined)
+	output := combined.Bytes()
+	executionDuration.Observe(time.Since(start).Seconds())
+	executionsRun.Inc()
 	if execErr != nil {
+		executionFailures.Inc()
 		// command can exit with non-zero code and that would be
 		// an error technically, but not an error logically
 		log.Printf("Deno execution error: %v", execErr)
 	}
 	outputText := string(output)
 
-	// Upload full result to S3 and get signed URL
-	signedURL, uploadErr := uploadToS3AndGetSignedURL(context.Background(), outputText)
-	if uploadErr != nil {
-		log.Printf("Warning: Failed to upload result to S3: %v", uploadErr)
-		// Continue without signed URL - don't fail the execution
-		signedURL = ""
+	if err := runCtx.Err(); err != nil {
+		return ExecuteTypeScriptResults{
+			Status:       "error",
+			Output:       outputText,
+			ErrorMessage: fmt.Sprintf("Execution cancelled: %v", err),
+			ExitCode:     -1,
+			CodeShortURL: codeShortURL,
+		}
+	}
+
+	// Binary output (images, archives) is uploaded as is but never shown inline
+	binary := !utf8.Valid(output)
+	contentType := ""
+	if binary {
+		contentType = http.DetectContentType(output)
+	}
+
+	// Upload full result to the artifact store and get its URL
+	var signedURL string
+	if uploading {
+		url, uploadErr := e.Store.Upload(runCtx, outputText, contentType)
+		if uploadErr != nil {
+			// Continue without signed URL - don't fail the execution
+			log.Printf("Warning: Failed to upload result: %v", uploadErr)
+		} else {
+			signedURL = url
+		}
 	}
 
 	// Create shortened URL if we have a signed URL
-	var shortURL string
+	var shortURL, linkError string
 	if signedURL != "" && e.URLShortener != nil {
-		shortURL = e.URLShortener.GetShortURL(signedURL)
+		var err error
+		if shortURL, err = e.URLShortener.GetShortURL(signedURL); err != nil {
+			log.Printf("Warning: Failed to shorten result URL: %v", err)
+			linkError = "The output was uploaded but a short link couldn't be created; share signed_url instead"
+		}
+	}
+
+	if binary {
+		outputText = binaryOutputNote(len(output), contentType, signedURL != "")
 	}
 
 	if execErr != nil {
 		// Check if it's an exit error
-		if exitErr, ok := execErr.(*exec.ExitError); ok {
+		var exitErr interface{ ExitCode() int }
+		if errors.As(execErr, &exitErr) {
 			exitCode := exitErr.ExitCode()
 
 			// Check for permission errors
@@ -191,7 +477,16 @@ func (e *TypeScriptExecutor) Execute(ctx tool.Context, params ExecuteTypeScriptP
 			}
 		}
 
-		// Other execution errors (e.g., Deno not found)
+		// Deno was removed after startup
+		if errors.Is(execErr, exec.ErrNotFound) {
+			return ExecuteTypeScriptResults{
+				Status:       "error",
+				ErrorMessage: denoUnavailableMessage,
+				ExitCode:     -1,
+			}
+		}
+
+		// Other execution errors
 		return ExecuteTypeScriptResults{
 			Status:       "error",
 			Output:       outputText,
@@ -214,15 +509,64 @@ func (e *TypeScriptExecutor) Execute(ctx tool.Context, params ExecuteTypeScriptP
 	const maxOutputLen = 500
 	truncatedOutput := fullResult
 	if len(fullResult) > maxOutputLen {
-		truncatedOutput = fullResult[:maxOutputLen] + fmt.Sprintf("\n... (output truncated, %d more bytes available via signed_url)", len(fullResult)-maxOutputLen)
+		inline := truncateUTF8(fullResult, maxOutputLen)
+		if signedURL != "" {
+			truncatedOutput = inline + fmt.Sprintf("\n... (output truncated, %d more bytes available via signed_url)", len(fullResult)-len(inline))
+		} else {
+			truncatedOutput = inline + fmt.Sprintf("\n... (output truncated, %d more bytes not shown)", len(fullResult)-len(inline))
+		}
 	}
 
-	return ExecuteTypeScriptResults{
+	results = ExecuteTypeScriptResults{
 		Status:       "success",
 		Output:       truncatedOutput,
+		ErrorMessage: linkError,
 		ExitCode:     0,
 		SignedURL:    signedURL,
 		ShortURL:     shortURL,
 		CodeShortURL: codeShortURL,
 	}
+	if params.ParseJSON {
+		if parsed, ok := parseLastJSONLine(stdout.String()); ok {
+			results.JSON = parsed
+		} else {
+			results.JSONParseFailed = true
+		}
+	}
+	return results
+}
+
+// parseLastJSONLine parses the last non-empty line of output as a JSON object
+func parseLastJSONLine(output string) (map[string]any, bool) {
+	lines := strings.Split(strings.TrimRight(output, "\r\n\t "), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	if last == "" {
+		return nil, false
+	}
+
+	var parsed map[string]any
+	if err := json.Unmarshal([]byte(last), &parsed); err != nil {
+		return nil, false
+	}
+	return parsed, true
+}
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes exec makes
+// when stdout and stderr go to different writers
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+// Bytes returns the buffered output
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
 }
@@ -0,0 +1,575 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r33drichards/irc-agent/shortener"
+	"google.golang.org/adk/tool"
+)
+
+// fakeToolContext is a tool.Context for a call from the given channel, bound
+// to ctx (or a background context). Other tool.Context methods are not implemented.
+type fakeToolContext struct {
+	tool.Context
+	channel string
+	ctx     context.Context
+}
+
+func (c fakeToolContext) UserID() string { return c.channel }
+
+func (c fakeToolContext) base() context.Context {
+	if c.ctx == nil {
+		return context.Background()
+	}
+	return c.ctx
+}
+
+func (c fakeToolContext) Deadline() (time.Time, bool) { return c.base().Deadline() }
+func (c fakeToolContext) Done() <-chan struct{}       { return c.base().Done() }
+func (c fakeToolContext) Err() error                  { return c.base().Err() }
+func (c fakeToolContext) Value(key any) any           { return c.base().Value(key) }
+
+// shellDeno is a fake deno that runs the script file (its last argument) with
+// sh, so tests can exercise the executor without Deno installed
+const shellDeno = `for arg; do script=$arg; done; exec sh "$script"`
+
+func TestExecuteWithoutDeno(t *testing.T) {
+	// An empty PATH guarantees deno can't be found
+	t.Setenv("PATH", t.TempDir())
+
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{})
+	if err != nil {
+		t.Fatalf("Expected a missing default deno to only disable execution, got %v", err)
+	}
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "console.log(1)"})
+
+	if result.Status != "error" {
+		t.Errorf("Expected status error, got %s", result.Status)
+	}
+	if result.ErrorMessage != denoUnavailableMessage {
+		t.Errorf("Expected %q, got %q", denoUnavailableMessage, result.ErrorMessage)
+	}
+	if result.ExitCode != -1 {
+		t.Errorf("Expected exit code -1, got %d", result.ExitCode)
+	}
+}
+
+// writeFakeDeno creates an executable script standing in for deno
+func writeFakeDeno(t *testing.T, script string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "fake-deno")
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake deno: %v", err)
+	}
+	return path
+}
+
+func TestExecuteWithDenoPath(t *testing.T) {
+	denoPath := writeFakeDeno(t, `echo "fake deno $1"`)
+
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{DenoPath: denoPath})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "console.log(1)"})
+
+	if result.Status != "success" {
+		t.Fatalf("Expected status success, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+	if !strings.Contains(result.Output, "fake deno run") {
+		t.Errorf("Expected output from the fake binary, got %q", result.Output)
+	}
+}
+
+func TestNewTypeScriptExecutorInvalidDenoPath(t *testing.T) {
+	_, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{DenoPath: filepath.Join(t.TempDir(), "missing")})
+	if err == nil {
+		t.Errorf("Expected error for a configured deno path that doesn't exist")
+	}
+}
+
+func TestExecuteWorkspacePersistsFiles(t *testing.T) {
+	workspace := t.TempDir()
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{
+		DenoPath:     writeFakeDeno(t, shellDeno),
+		WorkspaceDir: workspace,
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	ctx := fakeToolContext{channel: "#agent"}
+
+	if result := executor.Execute(ctx, ExecuteTypeScriptParams{Code: "echo remembered > state.txt"}); result.Status != "success" {
+		t.Fatalf("First execution failed: %s %s", result.ErrorMessage, result.Output)
+	}
+
+	result := executor.Execute(ctx, ExecuteTypeScriptParams{Code: "cat state.txt"})
+	if result.Status != "success" {
+		t.Fatalf("Second execution failed: %s %s", result.ErrorMessage, result.Output)
+	}
+	if strings.TrimSpace(result.Output) != "remembered" {
+		t.Errorf("Expected file from the previous call, got %q", result.Output)
+	}
+
+	if _, err := os.Stat(filepath.Join(workspace, workspaceName(ctx), "state.txt")); err != nil {
+		t.Errorf("Expected the file in the channel's workspace: %v", err)
+	}
+}
+
+func TestExecuteWorkspacesIsolatedPerChannel(t *testing.T) {
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{
+		DenoPath:     writeFakeDeno(t, shellDeno),
+		WorkspaceDir: t.TempDir(),
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	// These sanitize to the same name, so only the hash keeps them apart
+	first := fakeToolContext{channel: "#team.a"}
+	second := fakeToolContext{channel: "#team_a"}
+
+	executor.Execute(first, ExecuteTypeScriptParams{Code: "echo first > result.txt"})
+	executor.Execute(second, ExecuteTypeScriptParams{Code: "echo second > result.txt"})
+
+	for ctx, expected := range map[fakeToolContext]string{first: "first", second: "second"} {
+		result := executor.Execute(ctx, ExecuteTypeScriptParams{Code: "cat result.txt"})
+		if strings.TrimSpace(result.Output) != expected {
+			t.Errorf("Expected %s to read %q, got %q", ctx.channel, expected, result.Output)
+		}
+	}
+}
+
+func TestExecutePassesArgs(t *testing.T) {
+	// Print only the arguments after the script path
+	denoPath := writeFakeDeno(t, `for arg; do if [ -n "$seen" ]; then echo "$arg"; fi; case $arg in *script.ts) seen=1;; esac; done`)
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{DenoPath: denoPath})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{
+		Code: "console.log(Deno.args)",
+		Args: []string{"one", "two words", "$HOME;rm -rf /"},
+	})
+	if result.Status != "success" {
+		t.Fatalf("Expected status success, got %s (%s)", result.Status, result.ErrorMessage)
+	}
+
+	expected := "one\ntwo words\n$HOME;rm -rf /\n"
+	if result.Output != expected {
+		t.Errorf("Expected args passed verbatim %q, got %q", expected, result.Output)
+	}
+}
+
+func TestValidateScriptArgs(t *testing.T) {
+	if err := validateScriptArgs([]string{"a", "b"}); err != nil {
+		t.Errorf("Expected valid args, got %v", err)
+	}
+	if err := validateScriptArgs([]string{"bad\x00arg"}); err == nil {
+		t.Errorf("Expected error for NUL byte")
+	}
+	if err := validateScriptArgs(make([]string, maxScriptArgs+1)); err == nil {
+		t.Errorf("Expected error for too many args")
+	}
+}
+
+func TestExecuteParseJSON(t *testing.T) {
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{DenoPath: writeFakeDeno(t, shellDeno)})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{
+		Code:      `echo computing; echo warning >&2; echo '{"sum": 55}'; echo`,
+		ParseJSON: true,
+	})
+	if result.JSONParseFailed {
+		t.Fatalf("Expected JSON to parse, output was %q", result.Output)
+	}
+	if result.JSON["sum"] != float64(55) {
+		t.Errorf("Expected sum 55, got %v", result.JSON["sum"])
+	}
+	if !strings.Contains(result.Output, "computing") {
+		t.Errorf("Expected raw output alongside JSON, got %q", result.Output)
+	}
+
+	result = executor.Execute(nil, ExecuteTypeScriptParams{Code: "echo not json", ParseJSON: true})
+	if !result.JSONParseFailed || result.JSON != nil {
+		t.Errorf("Expected parse failure flag for non-JSON output, got %+v", result)
+	}
+}
+
+func TestExecuteCancellationStopsDeno(t *testing.T) {
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{DenoPath: writeFakeDeno(t, shellDeno)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	runCtx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(100*time.Millisecond, cancel)
+
+	start := time.Now()
+	result := executor.Execute(fakeToolContext{channel: "#agent", ctx: runCtx}, ExecuteTypeScriptParams{Code: "echo started; exec sleep 30"})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected cancellation to stop the execution, took %s", elapsed)
+	}
+	if result.Status != "error" || !strings.Contains(result.ErrorMessage, "cancelled") {
+		t.Errorf("Expected a cancelled error, got %+v", result)
+	}
+	if !strings.Contains(result.Output, "started") {
+		t.Errorf("Expected output before cancellation to be kept, got %q", result.Output)
+	}
+
+	// Once cancelled, queued executions don't start at all
+	result = executor.Execute(fakeToolContext{channel: "#agent", ctx: runCtx}, ExecuteTypeScriptParams{Code: "echo never"})
+	if result.Status != "error" || result.Output != "" {
+		t.Errorf("Expected execution to be skipped after cancellation, got %+v", result)
+	}
+}
+
+func TestExecuteRejectsOversizedCode(t *testing.T) {
+	// A fake deno that leaves a marker shows whether the script ever ran
+	marker := filepath.Join(t.TempDir(), "ran")
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{
+		DenoPath:     writeFakeDeno(t, "touch "+marker),
+		MaxCodeBytes: 64,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: strings.Repeat("x", 65)})
+	if result.Status != "error" || !strings.Contains(result.ErrorMessage, "too large") {
+		t.Errorf("Expected a too large error, got %+v", result)
+	}
+	if _, err := os.Stat(marker); err == nil {
+		t.Error("Expected oversized code not to run")
+	}
+
+	if result := executor.Execute(nil, ExecuteTypeScriptParams{Code: strings.Repeat("x", 64)}); result.Status != "success" {
+		t.Errorf("Expected code at the limit to run, got %+v", result)
+	}
+}
+
+func TestExecutorDefaultMaxCodeBytes(t *testing.T) {
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{DenoPath: writeFakeDeno(t, "true")})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if executor.MaxCodeBytes != defaultMaxCodeBytes {
+		t.Errorf("Expected default limit %d, got %d", defaultMaxCodeBytes, executor.MaxCodeBytes)
+	}
+}
+
+func TestExecuteBinaryOutput(t *testing.T) {
+	dir := t.TempDir()
+	store := &FileArtifactStore{Dir: dir, BaseURL: "http://short.test"}
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{
+		DenoPath: writeFakeDeno(t, shellDeno),
+		Store:    store,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// A PNG signature followed by bytes that aren't valid UTF-8
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: `printf '\211PNG\r\n\032\n\377\376\000\001'`})
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+	if !strings.Contains(result.Output, "binary output, 12 bytes of image/png") {
+		t.Errorf("Expected a binary output note instead of raw bytes, got %q", result.Output)
+	}
+	if !strings.HasSuffix(result.SignedURL, ".png") {
+		t.Fatalf("Expected the output to be uploaded as a .png, got %q", result.SignedURL)
+	}
+
+	uploaded, err := os.ReadFile(filepath.Join(dir, filepath.Base(result.SignedURL)))
+	if err != nil {
+		t.Fatalf("Failed to read uploaded output: %v", err)
+	}
+	if string(uploaded) != "\x89PNG\r\n\x1a\n\xff\xfe\x00\x01" {
+		t.Errorf("Expected the raw bytes to be uploaded unchanged, got %q", uploaded)
+	}
+}
+
+func TestExecuteBinaryOutputWithoutUpload(t *testing.T) {
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{DenoPath: writeFakeDeno(t, shellDeno)})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: `printf '\377\376\375'`})
+	if !strings.Contains(result.Output, "binary output, 3 bytes") || !strings.Contains(result.Output, "not shown") {
+		t.Errorf("Expected a binary output note, got %q", result.Output)
+	}
+}
+
+// fakeExitError is a non-zero exit from a fakeCommandRunner
+type fakeExitError struct{ code int }
+
+func (e fakeExitError) Error() string { return fmt.Sprintf("exit status %d", e.code) }
+func (e fakeExitError) ExitCode() int { return e.code }
+
+// fakeCommandRunner stands in for Deno: it writes canned output and exits
+// with exitCode, or with err. When block is set it waits for the run to be
+// cancelled instead.
+type fakeCommandRunner struct {
+	stdout   string
+	stderr   string
+	exitCode int
+	err      error
+	block    bool
+	args     []string // arguments of the last run
+}
+
+func (r *fakeCommandRunner) Run(ctx context.Context, dir, name string, args []string, stdout, stderr io.Writer) error {
+	r.args = args
+	if r.block {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	io.WriteString(stdout, r.stdout)
+	io.WriteString(stderr, r.stderr)
+	if r.exitCode != 0 {
+		return fakeExitError{code: r.exitCode}
+	}
+	return r.err
+}
+
+// failingArtifactStore rejects every upload
+type failingArtifactStore struct{}
+
+func (failingArtifactStore) Upload(ctx context.Context, content, contentType string) (string, error) {
+	return "", errors.New("bucket unavailable")
+}
+
+// newFakeExecutor returns an executor running scripts with runner and
+// uploading to store, if any
+func newFakeExecutor(t *testing.T, runner CommandRunner, store ArtifactStore) *TypeScriptExecutor {
+	t.Helper()
+	// An empty PATH proves Deno is never looked up or run
+	t.Setenv("PATH", t.TempDir())
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{
+		Runner:       runner,
+		Store:        store,
+		URLShortener: shortener.NewURLShortener("http://short.test", shortener.NewInMemoryStorage()),
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return executor
+}
+
+func TestExecuteWithFakeRunnerSuccess(t *testing.T) {
+	runner := &fakeCommandRunner{stdout: "hello\n"}
+	store := &fakeArtifactStore{}
+	executor := newFakeExecutor(t, runner, store)
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "console.log('hello')", Args: []string{"a"}})
+
+	if result.Status != "success" || result.Output != "hello\n" || result.ExitCode != 0 {
+		t.Fatalf("Expected the canned output, got %+v", result)
+	}
+	if len(store.uploads) != 2 || store.uploads[0] != "console.log('hello')" || store.uploads[1] != "hello\n" {
+		t.Errorf("Expected the code and output to be uploaded, got %q", store.uploads)
+	}
+	if result.SignedURL == "" || !strings.HasPrefix(result.ShortURL, "http://short.test/") || result.CodeShortURL == "" {
+		t.Errorf("Expected upload URLs, got %+v", result)
+	}
+	if last := runner.args[len(runner.args)-1]; last != "a" {
+		t.Errorf("Expected script args after the script path, got %q", runner.args)
+	}
+}
+
+func TestExecuteAllowsConfiguredHosts(t *testing.T) {
+	runner := &fakeCommandRunner{}
+	executor := newFakeExecutor(t, runner, nil)
+	executor.AllowNet = []string{"minio.local:9000"}
+
+	executor.Execute(nil, ExecuteTypeScriptParams{Code: "console.log(1)"})
+	expected := "--allow-net=minio.local:9000,localhost:" + shortener.Port()
+	if !slices.Contains(runner.args, expected) {
+		t.Errorf("Expected %s, got %q", expected, runner.args)
+	}
+}
+
+func TestExecuteWithFakeRunnerNonZeroExit(t *testing.T) {
+	executor := newFakeExecutor(t, &fakeCommandRunner{stderr: "error: Uncaught Error: boom", exitCode: 1}, nil)
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "throw new Error('boom')"})
+
+	if result.Status != "error" || result.ExitCode != 1 {
+		t.Fatalf("Expected exit code 1, got %+v", result)
+	}
+	if result.ErrorMessage != "Execution failed with exit code 1" || !strings.Contains(result.Output, "boom") {
+		t.Errorf("Unexpected result %+v", result)
+	}
+}
+
+func TestExecuteWithFakeRunnerPermissionDenied(t *testing.T) {
+	runner := &fakeCommandRunner{stderr: `error: Uncaught PermissionDenied: Requires net access to "example.com"`, exitCode: 1}
+	executor := newFakeExecutor(t, runner, nil)
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "await fetch('https://example.com')"})
+
+	if result.Status != "error" || !strings.HasPrefix(result.ErrorMessage, "Permission denied") {
+		t.Errorf("Expected a permission error, got %+v", result)
+	}
+}
+
+func TestExecuteWithFakeRunnerTimeout(t *testing.T) {
+	executor := newFakeExecutor(t, &fakeCommandRunner{block: true}, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	result := executor.Execute(fakeToolContext{channel: "#agent", ctx: ctx}, ExecuteTypeScriptParams{Code: "while (true) {}"})
+
+	if result.Status != "error" || result.ExitCode != -1 {
+		t.Fatalf("Expected the run to be stopped, got %+v", result)
+	}
+	if !strings.Contains(result.ErrorMessage, "deadline exceeded") {
+		t.Errorf("Expected a deadline error, got %q", result.ErrorMessage)
+	}
+}
+
+func TestExecuteWithFakeRunnerTruncation(t *testing.T) {
+	long := strings.Repeat("x", 800)
+
+	uploaded := newFakeExecutor(t, &fakeCommandRunner{stdout: long}, &fakeArtifactStore{})
+	result := uploaded.Execute(nil, ExecuteTypeScriptParams{Code: "print()"})
+	if !strings.HasPrefix(result.Output, strings.Repeat("x", 500)) || !strings.Contains(result.Output, "300 more bytes available via signed_url") {
+		t.Errorf("Expected output truncated with a pointer to the upload, got %q", result.Output)
+	}
+
+	inline := newFakeExecutor(t, &fakeCommandRunner{stdout: long}, nil)
+	result = inline.Execute(nil, ExecuteTypeScriptParams{Code: "print()"})
+	if !strings.Contains(result.Output, "300 more bytes not shown") {
+		t.Errorf("Expected output truncated without an upload, got %q", result.Output)
+	}
+}
+
+func TestExecuteWithFailingUploader(t *testing.T) {
+	executor := newFakeExecutor(t, &fakeCommandRunner{stdout: "ok"}, failingArtifactStore{})
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "print()"})
+
+	if result.Status != "success" || result.Output != "ok" {
+		t.Fatalf("Expected upload failures not to fail the run, got %+v", result)
+	}
+	if result.SignedURL != "" || result.ShortURL != "" || result.CodeShortURL != "" {
+		t.Errorf("Expected no URLs when uploads fail, got %+v", result)
+	}
+}
+
+func TestExecuteSendsNotices(t *testing.T) {
+	var notices []sentMessage
+	executor := newFakeExecutor(t, &fakeCommandRunner{stdout: "ok"}, &fakeArtifactStore{})
+	executor.Notices = true
+	ctx := withReplyRoute(context.Background(), MessageSinkFunc(func(target, message string) {
+		notices = append(notices, sentMessage{target: target, message: message})
+	}), "#agent")
+
+	result := executor.Execute(fakeToolContext{channel: "#agent", ctx: ctx}, ExecuteTypeScriptParams{Code: "print()"})
+
+	if len(notices) != 2 {
+		t.Fatalf("Expected 2 notices, got %v", notices)
+	}
+	if notices[0] != (sentMessage{target: "#agent", message: "Executing code..."}) {
+		t.Errorf("Expected a progress notice to #agent, got %+v", notices[0])
+	}
+	want := "Code executed successfully: " + result.ShortURL
+	if result.ShortURL == "" || notices[1] != (sentMessage{target: "#agent", message: want}) {
+		t.Errorf("Expected %q to #agent, got %+v", want, notices[1])
+	}
+}
+
+func TestExecuteNoticeTargetsAndFailures(t *testing.T) {
+	var notices []sentMessage
+	executor := newFakeExecutor(t, &fakeCommandRunner{exitCode: 2}, nil)
+	executor.Notices = true
+	executor.Channel = "#status"
+	ctx := withReplyRoute(context.Background(), MessageSinkFunc(func(target, message string) {
+		notices = append(notices, sentMessage{target: target, message: message})
+	}), "#agent")
+
+	executor.Execute(fakeToolContext{channel: "#agent", ctx: ctx}, ExecuteTypeScriptParams{Code: "Deno.exit(2)"})
+
+	if len(notices) != 2 || notices[1] != (sentMessage{target: "#status", message: "Code execution failed with exit code 2"}) {
+		t.Errorf("Expected the failure reported to #status, got %v", notices)
+	}
+
+	// Code that is refused never runs, so gets no notices
+	notices = nil
+	executor.MaxCodeBytes = 4
+	executor.Execute(fakeToolContext{channel: "#agent", ctx: ctx}, ExecuteTypeScriptParams{Code: "console.log()"})
+	if len(notices) != 0 {
+		t.Errorf("Expected no notices for code that didn't run, got %v", notices)
+	}
+}
+
+func TestExecuteWithRealDeno(t *testing.T) {
+	if _, err := exec.LookPath("deno"); err != nil {
+		t.Skip("deno is not installed")
+	}
+	executor, err := NewTypeScriptExecutor(TypeScriptExecutorConfig{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		code     string
+		status   string
+		output   string
+		exitCode int
+	}{
+		{"console.log", `console.log("Hello from Deno!");`, "success", "Hello from Deno!", 0},
+		{"calculation", `console.log("Sum of 1 to 10:", Array.from({length: 10}, (_, i) => i + 1).reduce((a, b) => a + b, 0));`, "success", "Sum of 1 to 10: 55", 0},
+		{"thrown error", `throw new Error("This is a test error");`, "error", "This is a test error", 1},
+		{
+			"typescript",
+			"interface Person { name: string; age: number }\nconst person: Person = { name: \"Alice\", age: 30 };\nconsole.log(`${person.name} is ${person.age} years old`);",
+			"success", "Alice is 30 years old", 0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := executor.Execute(nil, ExecuteTypeScriptParams{Code: tt.code})
+			if result.Status != tt.status || result.ExitCode != tt.exitCode {
+				t.Errorf("Expected %s with exit code %d, got %+v", tt.status, tt.exitCode, result)
+			}
+			if !strings.Contains(result.Output, tt.output) {
+				t.Errorf("Expected output containing %q, got %q", tt.output, result.Output)
+			}
+		})
+	}
+}
+
+func TestExecuteWithoutUploadResults(t *testing.T) {
+	store := &fakeArtifactStore{}
+	executor := newFakeExecutor(t, &fakeCommandRunner{stdout: "ok"}, store)
+	if !executor.UploadResults {
+		t.Fatal("Expected results to be uploaded by default")
+	}
+	executor.UploadResults = false
+
+	result := executor.Execute(nil, ExecuteTypeScriptParams{Code: "print()"})
+	if result.Status != "success" || result.Output != "ok" {
+		t.Fatalf("Expected the output inline, got %+v", result)
+	}
+	if len(store.uploads) != 0 || result.SignedURL != "" {
+		t.Errorf("Expected nothing to be uploaded, got %d uploads and %+v", len(store.uploads), result)
+	}
+}
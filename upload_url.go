@@ -0,0 +1,101 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"path"
+	"regexp"
+	"time"
+
+	"github.com/r33drichards/irc-agent/shortener"
+	"google.golang.org/adk/tool"
+)
+
+// uploadNameUnsafe matches characters replaced in upload file names
+var uploadNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// PresignUploadParams defines the input parameters for the presign_upload tool
+type PresignUploadParams struct {
+	Filename string `json:"filename" jsonschema:"Name for the file being uploaded, e.g. chart.png"`
+}
+
+// PresignUploadResults defines the output of the presign_upload tool
+type PresignUploadResults struct {
+	Status       string `json:"status"`
+	UploadURL    string `json:"upload_url,omitempty"`   // accepts one PUT of the file
+	DownloadURL  string `json:"download_url,omitempty"` // link to share once uploaded
+	ExpiresIn    string `json:"expires_in,omitempty"`
+	ErrorMessage string `json:"error_message,omitempty"`
+}
+
+// UploadURLIssuer hands out presigned S3 upload URLs so executed code can
+// write files with a plain fetch PUT instead of AWS credentials
+type UploadURLIssuer struct {
+	store     *S3ArtifactStore
+	shortener *shortener.URLShortener
+}
+
+// NewUploadURLIssuer creates an issuer for store, shortening download links with shortener
+func NewUploadURLIssuer(store *S3ArtifactStore, shortener *shortener.URLShortener) *UploadURLIssuer {
+	return &UploadURLIssuer{store: store, shortener: shortener}
+}
+
+// uploadKey builds the key for an upload named filename under prefix, keeping
+// only the base name and characters safe in a URL. The random token keeps two
+// uploads of the same name in the same second from overwriting each other.
+func uploadKey(prefix, filename string, now time.Time, token string) string {
+	name := uploadNameUnsafe.ReplaceAllString(path.Base(filename), "-")
+	if name == "" || name == "." || name == ".." || name == "-" {
+		name = "upload"
+	}
+	return fmt.Sprintf("%suploads/%d-%s-%s", prefix, now.Unix(), token, name)
+}
+
+// newUploadToken returns a random hex token for uploadKey
+func newUploadToken() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate upload key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// PresignUpload returns an upload URL for a new object and the link it will be
+// downloadable at
+func (u *UploadURLIssuer) PresignUpload(ctx tool.Context, params PresignUploadParams) PresignUploadResults {
+	prefix := u.store.KeyPrefix
+	if prefix == "" {
+		prefix = defaultKeyPrefix
+	}
+	token, err := newUploadToken()
+	if err != nil {
+		return PresignUploadResults{Status: "error", ErrorMessage: err.Error()}
+	}
+	key := uploadKey(prefix, params.Filename, time.Now(), token)
+
+	uploadURL, err := u.store.presignPutURL(toolContext(ctx), key)
+	if err != nil {
+		return PresignUploadResults{Status: "error", ErrorMessage: err.Error()}
+	}
+	downloadURL, err := u.store.presignGetURL(toolContext(ctx), key)
+	if err != nil {
+		return PresignUploadResults{Status: "error", ErrorMessage: err.Error()}
+	}
+	if u.shortener != nil {
+		// The presigned URL still works if it can't be shortened
+		if short, err := u.shortener.GetShortURL(downloadURL); err != nil {
+			log.Printf("Warning: Failed to shorten upload download URL: %v", err)
+		} else {
+			downloadURL = short
+		}
+	}
+
+	return PresignUploadResults{
+		Status:      "success",
+		UploadURL:   uploadURL,
+		DownloadURL: downloadURL,
+		ExpiresIn:   presignedPutTTL.String(),
+	}
+}
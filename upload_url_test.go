@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+func TestUploadKey(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		filename string
+		expected string
+	}{
+		{"chart.png", "p/uploads/1700000000-ab12-chart.png"},
+		{"../../etc/passwd", "p/uploads/1700000000-ab12-passwd"},
+		{"my report (final).csv", "p/uploads/1700000000-ab12-my-report-final-.csv"},
+		{"", "p/uploads/1700000000-ab12-upload"},
+	}
+	for _, tt := range tests {
+		if got := uploadKey("p/", tt.filename, now, "ab12"); got != tt.expected {
+			t.Errorf("uploadKey(%q): Expected %s, got %s", tt.filename, tt.expected, got)
+		}
+	}
+}
+
+func TestPresignUploadKeysAreUnique(t *testing.T) {
+	useStaticAWSCredentials(t)
+	issuer := NewUploadURLIssuer(&S3ArtifactStore{Bucket: "results"}, nil)
+
+	first := issuer.PresignUpload(nil, PresignUploadParams{Filename: "chart.png"})
+	second := issuer.PresignUpload(nil, PresignUploadParams{Filename: "chart.png"})
+	if first.Status != "success" || second.Status != "success" {
+		t.Fatalf("Expected success, got %+v and %+v", first, second)
+	}
+	if strings.Split(first.UploadURL, "?")[0] == strings.Split(second.UploadURL, "?")[0] {
+		t.Errorf("Expected two uploads of chart.png to get different keys, got %s", first.UploadURL)
+	}
+}
+
+func TestPresignUpload(t *testing.T) {
+	useStaticAWSCredentials(t)
+
+	us := shortener.NewURLShortener("http://short.test", shortener.NewInMemoryStorage())
+	issuer := NewUploadURLIssuer(&S3ArtifactStore{Bucket: "results"}, us)
+
+	result := issuer.PresignUpload(nil, PresignUploadParams{Filename: "chart.png"})
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+	if !strings.Contains(result.UploadURL, "/"+defaultKeyPrefix+"uploads/") || !strings.Contains(result.UploadURL, "chart.png") {
+		t.Errorf("Expected an upload URL under the uploads prefix, got %s", result.UploadURL)
+	}
+	if !strings.HasPrefix(result.DownloadURL, "http://short.test/") {
+		t.Errorf("Expected a short download URL, got %s", result.DownloadURL)
+	}
+	if result.ExpiresIn != "15m0s" {
+		t.Errorf("Expected a 15m0s expiry, got %s", result.ExpiresIn)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/r33drichards/irc-agent/shortener"
+	"google.golang.org/adk/tool"
+)
+
+// defaultSearchAPIURL is the Brave Search web endpoint
+const defaultSearchAPIURL = "https://api.search.brave.com/res/v1/web/search"
+
+// Limits for web_search
+const (
+	webSearchTimeout       = 10 * time.Second
+	webSearchDefaultCount  = 5
+	webSearchMaxCount      = 10
+	webSearchMaxSnippetLen = 300
+)
+
+// WebSearchParams defines the input parameters for the web_search tool
+type WebSearchParams struct {
+	Query string `json:"query" jsonschema:"The search query"`
+	Count int    `json:"count,omitempty" jsonschema:"Number of results to return, 1-10 (default 5)"`
+}
+
+// WebSearchResult is a single search hit
+type WebSearchResult struct {
+	Title   string `json:"title"`
+	URL     string `json:"url"`
+	Snippet string `json:"snippet,omitempty"`
+}
+
+// WebSearchResults defines the output of the web_search tool
+type WebSearchResults struct {
+	Status       string            `json:"status"`
+	Results      []WebSearchResult `json:"results,omitempty"`
+	ErrorMessage string            `json:"error_message,omitempty"`
+}
+
+// WebSearcher queries a Brave-compatible search API for the model
+type WebSearcher struct {
+	client    *http.Client
+	apiURL    string
+	apiKey    string
+	shortener *shortener.URLShortener // shortens result URLs when set
+}
+
+// NewWebSearcher creates a searcher for apiURL, or the Brave API when apiURL is empty
+func NewWebSearcher(apiURL, apiKey string, shortener *shortener.URLShortener) *WebSearcher {
+	if apiURL == "" {
+		apiURL = defaultSearchAPIURL
+	}
+	return &WebSearcher{
+		client:    &http.Client{Timeout: webSearchTimeout},
+		apiURL:    apiURL,
+		apiKey:    apiKey,
+		shortener: shortener,
+	}
+}
+
+// braveSearchResponse is the part of the Brave response we use
+type braveSearchResponse struct {
+	Web struct {
+		Results []struct {
+			Title       string `json:"title"`
+			URL         string `json:"url"`
+			Description string `json:"description"`
+		} `json:"results"`
+	} `json:"web"`
+}
+
+// Search runs the query and returns the top results
+func (s *WebSearcher) Search(ctx tool.Context, params WebSearchParams) WebSearchResults {
+	if params.Query == "" {
+		return WebSearchResults{Status: "error", ErrorMessage: "Query is required"}
+	}
+	count := params.Count
+	if count <= 0 {
+		count = webSearchDefaultCount
+	}
+	count = min(count, webSearchMaxCount)
+
+	query := url.Values{}
+	query.Set("q", params.Query)
+	query.Set("count", strconv.Itoa(count))
+
+	req, err := http.NewRequestWithContext(toolContext(ctx), http.MethodGet, s.apiURL+"?"+query.Encode(), nil)
+	if err != nil {
+		return WebSearchResults{Status: "error", ErrorMessage: fmt.Sprintf("Failed to build request: %v", err)}
+	}
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("X-Subscription-Token", s.apiKey)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return WebSearchResults{Status: "error", ErrorMessage: fmt.Sprintf("Search request failed: %v", err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 200))
+		return WebSearchResults{Status: "error", ErrorMessage: fmt.Sprintf("Search API returned %d: %s", resp.StatusCode, body)}
+	}
+
+	var decoded braveSearchResponse
+	if err := json.NewDecoder(resp.Body).Decode(&decoded); err != nil {
+		return WebSearchResults{Status: "error", ErrorMessage: fmt.Sprintf("Failed to decode search response: %v", err)}
+	}
+
+	results := WebSearchResults{Status: "success"}
+	for _, hit := range decoded.Web.Results {
+		if len(results.Results) == count {
+			break
+		}
+		link := hit.URL
+		if s.shortener != nil {
+			// The original link still works if it can't be shortened
+			if short, err := s.shortener.GetShortURL(link); err != nil {
+				log.Printf("Warning: Failed to shorten search result %s: %v", link, err)
+			} else {
+				link = short
+			}
+		}
+		snippet := hit.Description
+		if len(snippet) > webSearchMaxSnippetLen {
+			snippet = truncateUTF8(snippet, webSearchMaxSnippetLen) + "..."
+		}
+		results.Results = append(results.Results, WebSearchResult{Title: hit.Title, URL: link, Snippet: snippet})
+	}
+	return results
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/r33drichards/irc-agent/shortener"
+)
+
+// roundTripFunc lets a function stand in for an HTTP transport
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+// mockSearcher returns a WebSearcher whose requests are answered by handle
+func mockSearcher(shortener *shortener.URLShortener, handle func(req *http.Request) (int, string)) *WebSearcher {
+	s := NewWebSearcher("", "test-key", shortener)
+	s.client.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+		status, body := handle(req)
+		return &http.Response{
+			StatusCode: status,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+			Body:       io.NopCloser(strings.NewReader(body)),
+			Request:    req,
+		}, nil
+	})
+	return s
+}
+
+func TestWebSearch(t *testing.T) {
+	shortener := shortener.NewURLShortener("http://short.test", shortener.NewInMemoryStorage())
+	searcher := mockSearcher(shortener, func(req *http.Request) (int, string) {
+		if req.URL.Host != "api.search.brave.com" {
+			t.Errorf("Expected Brave API host, got %s", req.URL.Host)
+		}
+		if got := req.Header.Get("X-Subscription-Token"); got != "test-key" {
+			t.Errorf("Expected API key header, got %q", got)
+		}
+		if q := req.URL.Query(); q.Get("q") != "irc bots" || q.Get("count") != "2" {
+			t.Errorf("Unexpected query: %s", req.URL.RawQuery)
+		}
+		return http.StatusOK, `{"web":{"results":[
+			{"title":"First","url":"https://example.com/1","description":"one"},
+			{"title":"Second","url":"https://example.com/2","description":"two"},
+			{"title":"Third","url":"https://example.com/3","description":"three"}
+		]}}`
+	})
+
+	result := searcher.Search(nil, WebSearchParams{Query: "irc bots", Count: 2})
+	if result.Status != "success" {
+		t.Fatalf("Expected success, got %+v", result)
+	}
+	if len(result.Results) != 2 {
+		t.Fatalf("Expected 2 results, got %d", len(result.Results))
+	}
+	if result.Results[0].Title != "First" || result.Results[0].Snippet != "one" {
+		t.Errorf("Unexpected first result: %+v", result.Results[0])
+	}
+
+	// Result URLs are shortened and resolve back to the original
+	if !strings.HasPrefix(result.Results[0].URL, "http://short.test/") {
+		t.Errorf("Expected shortened URL, got %q", result.Results[0].URL)
+	}
+	original, err := shortener.Resolve(t.Context(), result.Results[1].URL)
+	if err != nil || original != "https://example.com/2" {
+		t.Errorf("Expected short URL to resolve to https://example.com/2, got %q (%v)", original, err)
+	}
+}
+
+func TestWebSearchAPIError(t *testing.T) {
+	searcher := mockSearcher(nil, func(req *http.Request) (int, string) {
+		return http.StatusUnauthorized, `{"error":"bad key"}`
+	})
+
+	result := searcher.Search(nil, WebSearchParams{Query: "anything"})
+	if result.Status != "error" || !strings.Contains(result.ErrorMessage, "401") {
+		t.Errorf("Expected 401 error, got %+v", result)
+	}
+}
+
+func TestWebSearchRequiresQuery(t *testing.T) {
+	searcher := mockSearcher(nil, func(req *http.Request) (int, string) {
+		t.Error("Expected no request for an empty query")
+		return http.StatusOK, "{}"
+	})
+
+	if result := searcher.Search(nil, WebSearchParams{}); result.Status != "error" {
+		t.Errorf("Expected error for empty query, got %+v", result)
+	}
+}
+
+func TestWebSearchTruncatesSnippetsOnCharacters(t *testing.T) {
+	// The cut falls inside the three-byte 語
+	long := strings.Repeat("a", webSearchMaxSnippetLen-1) + "語"
+	searcher := mockSearcher(nil, func(req *http.Request) (int, string) {
+		return http.StatusOK, `{"web":{"results":[{"title":"Long","url":"https://example.com/","description":"` + long + `"}]}}`
+	})
+
+	result := searcher.Search(nil, WebSearchParams{Query: "long"})
+	if len(result.Results) != 1 {
+		t.Fatalf("Expected one result, got %+v", result)
+	}
+	snippet := result.Results[0].Snippet
+	if !utf8.ValidString(snippet) || snippet != strings.Repeat("a", webSearchMaxSnippetLen-1)+"..." {
+		t.Errorf("Expected the snippet cut before the partial character, got %q", snippet)
+	}
+}